@@ -22,7 +22,6 @@ import (
 	"os"
 	"slices"
 
-	obopo "github.com/rhobs/obo-prometheus-operator/pkg/operator"
 	"go.uber.org/zap/zapcore"
 	k8sflag "k8s.io/component-base/cli/flag"
 	"k8s.io/utils/ptr"
@@ -33,12 +32,15 @@ import (
 )
 
 // The default values we use. Prometheus and Alertmanager are handled by
-// prometheus-operator. For thanos we use the default version from
-// prometheus-operator.
+// prometheus-operator. Thanos is pinned to a version supporting the
+// --endpoint.sd-config flag of the querier (v0.42+).
 var defaultImages = map[string]string{
 	"prometheus":               "",
 	"alertmanager":             "",
-	"thanos":                   obopo.DefaultThanosImage,
+	"thanos":                   "quay.io/thanos/thanos:v0.42.4",
+	"blackbox-exporter":        "quay.io/prometheus/blackbox-exporter:v0.25.0",
+	"prom-label-proxy":         "quay.io/prometheuscommunity/prom-label-proxy:v0.11.0",
+	"kube-rbac-proxy":          "quay.io/brancz/kube-rbac-proxy:v0.18.1",
 	"ui-dashboards":            "quay.io/openshift-observability-ui/console-dashboards-plugin:v0.3.0",
 	"ui-troubleshooting-panel": "quay.io/openshift-observability-ui/troubleshooting-panel-console-plugin:v0.3.0",
 	"ui-distributed-tracing":   "quay.io/openshift-observability-ui/distributed-tracing-console-plugin:v0.3.0",
@@ -90,7 +92,7 @@ func main() {
 	flag.StringVar(&namespace, "namespace", "default", "The namespace in which the operator runs")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&healthProbeAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
-	flag.Var(images, "images", fmt.Sprintf("Full images refs to use for containers managed by the operator. E.g thanos=quay.io/thanos/thanos:v0.33.0. Images used are %v", imagesUsed()))
+	flag.Var(images, "images", fmt.Sprintf("Full images refs to use for containers managed by the operator. E.g thanos=quay.io/thanos/thanos:v0.42.4. Images used are %v", imagesUsed()))
 	flag.BoolVar(&openShiftEnabled, "openshift.enabled", false, "Enable OpenShift specific features such as Console Plugins.")
 
 	opts := zap.Options{
@@ -127,6 +129,9 @@ func main() {
 			operator.WithAlertmanagerImage(imgMap["alertmanager"]),
 			operator.WithThanosSidecarImage(imgMap["thanos"]),
 			operator.WithThanosQuerierImage(imgMap["thanos"]),
+			operator.WithBlackboxExporterImage(imgMap["blackbox-exporter"]),
+			operator.WithPromLabelProxyImage(imgMap["prom-label-proxy"]),
+			operator.WithKubeRBACProxyImage(imgMap["kube-rbac-proxy"]),
 			operator.WithUIPluginImages(imgMap),
 			operator.WithFeatureGates(operator.FeatureGates{
 				OpenShift: operator.OpenShiftFeatureGates{
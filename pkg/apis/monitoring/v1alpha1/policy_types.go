@@ -0,0 +1,110 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitoringStackPolicy provisions a MonitoringStack in every namespace
+// matching its namespace selector, and deletes it once the namespace no
+// longer matches.
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:annotations="observability.openshift.io/api-support=TechPreview"
+// +kubebuilder:printcolumn:name="Stack",type="string",JSONPath=".spec.stackName"
+// +kubebuilder:printcolumn:name="Template",type="string",JSONPath=".spec.template"
+// +kubebuilder:printcolumn:name="Provisioned",type="integer",JSONPath=".status.provisioned"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MonitoringStackPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MonitoringStackPolicySpec   `json:"spec,omitempty"`
+	Status MonitoringStackPolicyStatus `json:"status,omitempty"`
+}
+
+// MonitoringStackPolicyList contains a list of MonitoringStackPolicy
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MonitoringStackPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringStackPolicy `json:"items"`
+}
+
+// MonitoringStackPolicySpec defines which namespaces get a MonitoringStack
+// and how it is configured.
+type MonitoringStackPolicySpec struct {
+	// Selector of the namespaces in which a MonitoringStack is provisioned.
+	// +kubebuilder:validation:Required
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+
+	// Name of the provisioned MonitoringStacks.
+	// +optional
+	// +kubebuilder:default="monitoring-stack"
+	// +kubebuilder:validation:MaxLength=48
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	StackName string `json:"stackName,omitempty"`
+
+	// Name of the MonitoringStackTemplate referenced by the provisioned
+	// MonitoringStacks.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Labels added to the provisioned MonitoringStacks (e.g. to select them
+	// from a ThanosQuerier).
+	// +optional
+	StackLabels map[string]string `json:"stackLabels,omitempty"`
+
+	// Label selector for the resources monitored by the provisioned
+	// MonitoringStacks. Defaults to all resources of the namespace.
+	// +optional
+	ResourceSelector *metav1.LabelSelector `json:"resourceSelector,omitempty"`
+
+	// Maximum number of MonitoringStacks provisioned by the policy. When the
+	// limit is reached, the oldest namespaces are served first and the others
+	// are reported in the status. No limit applies when unset.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxStacks *int32 `json:"maxStacks,omitempty"`
+
+	// Label key allowing namespaces to opt out: a matching namespace with
+	// this label set to "true" doesn't get a MonitoringStack.
+	// +optional
+	// +kubebuilder:default="monitoring.rhobs/opt-out"
+	OptOutLabel string `json:"optOutLabel,omitempty"`
+}
+
+// SkippedNamespace is a namespace matching the policy in which no
+// MonitoringStack was provisioned.
+type SkippedNamespace struct {
+	// Name of the namespace.
+	Name string `json:"name"`
+	// Reason why no MonitoringStack was provisioned.
+	Reason string `json:"reason"`
+}
+
+// MonitoringStackPolicyStatus defines the observed state of MonitoringStackPolicy.
+type MonitoringStackPolicyStatus struct {
+	// Number of MonitoringStacks provisioned by the policy.
+	// +optional
+	Provisioned int32 `json:"provisioned,omitempty"`
+
+	// Namespaces in which a MonitoringStack is provisioned.
+	// +optional
+	// +listType=set
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Namespaces matching the policy in which no MonitoringStack is
+	// provisioned.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	SkippedNamespaces []SkippedNamespace `json:"skippedNamespaces,omitempty"`
+
+	// Conditions provide status information about the policy.
+	// +optional
+	// +listType=atomic
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -36,5 +36,13 @@ var (
 )
 
 func init() {
-	SchemeBuilder.Register(&MonitoringStack{}, &MonitoringStackList{}, &ThanosQuerier{}, &ThanosQuerierList{})
+	SchemeBuilder.Register(
+		&MonitoringStack{}, &MonitoringStackList{},
+		&MonitoringStackPolicy{}, &MonitoringStackPolicyList{},
+		&MonitoringStackSnapshot{}, &MonitoringStackSnapshotList{},
+		&MonitoringStackTemplate{}, &MonitoringStackTemplateList{},
+		&PrometheusRuleTest{}, &PrometheusRuleTestList{},
+		&SLO{}, &SLOList{},
+		&ThanosQuerier{}, &ThanosQuerierList{},
+	)
 }
@@ -0,0 +1,198 @@
+package v1alpha1
+
+import (
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PrometheusRuleTest is a unit test for the rules of a PrometheusRule. The
+// format follows the one of `promtool test rules`: the operator loads the
+// input series, evaluates the rules with the Prometheus rule engine and
+// compares the result with the expected alerts and samples.
+//
+// The tests are evaluated one at a time by the operator, which fails the
+// tests exceeding 10000 rule evaluations, 1000000 input samples, 1000000
+// samples loaded by a single query or 30 seconds of evaluation.
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:annotations="observability.openshift.io/api-support=TechPreview"
+// +kubebuilder:printcolumn:name="Rule",type="string",JSONPath=".spec.ruleRef.name"
+// +kubebuilder:printcolumn:name="Passed",type="integer",JSONPath=".status.passed"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type PrometheusRuleTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PrometheusRuleTestSpec   `json:"spec,omitempty"`
+	Status PrometheusRuleTestStatus `json:"status,omitempty"`
+}
+
+// PrometheusRuleTestList contains a list of PrometheusRuleTest
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type PrometheusRuleTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PrometheusRuleTest `json:"items"`
+}
+
+// PrometheusRuleTestSpec defines the input series and the expectations of
+// the test.
+type PrometheusRuleTestSpec struct {
+	// PrometheusRule, in the same namespace, whose rules are tested.
+	// +kubebuilder:validation:Required
+	RuleRef RuleReference `json:"ruleRef"`
+
+	// Interval at which the rules are evaluated. The evaluation interval
+	// of the rule groups is ignored.
+	// +optional
+	// +kubebuilder:default="1m"
+	EvaluationInterval monv1.Duration `json:"evaluationInterval,omitempty"`
+
+	// External labels accessible to the alert templates.
+	// +optional
+	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+
+	// Series loaded before evaluating the rules.
+	// +optional
+	// +listType=atomic
+	InputSeries []InputSeries `json:"inputSeries,omitempty"`
+
+	// Expected firing alerts at given evaluation times.
+	// +optional
+	// +listType=atomic
+	AlertRuleTests []AlertRuleTest `json:"alertRuleTests,omitempty"`
+
+	// Expected results of PromQL expressions at given evaluation times.
+	// The expressions can query the input series as well as the series
+	// produced by the recording rules.
+	// +optional
+	// +listType=atomic
+	PromQLExprTests []PromQLExprTest `json:"promqlExprTests,omitempty"`
+}
+
+// RuleReference references a PrometheusRule in the same namespace.
+type RuleReference struct {
+	// Name of the PrometheusRule.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+}
+
+// InputSeries is a series loaded before evaluating the rules.
+type InputSeries struct {
+	// Series in the PromQL notation, e.g. `up{job="api"}`.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Series string `json:"series"`
+
+	// Values of the series in the expanding notation of promtool, e.g.
+	// `1+1x10 _x5 stale`. The first value is at time 0 and the next ones
+	// are spaced by the evaluation interval.
+	// +kubebuilder:validation:Required
+	Values string `json:"values"`
+}
+
+// AlertRuleTest lists the alerts expected to be firing for an alert name at
+// a given time.
+type AlertRuleTest struct {
+	// Time elapsed since time 0 at which the alerts are checked.
+	// +kubebuilder:validation:Required
+	EvalTime monv1.Duration `json:"evalTime"`
+
+	// Name of the alert under test.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Alertname string `json:"alertname"`
+
+	// Alerts expected to be firing. An empty list means that no alert
+	// should be firing.
+	// +optional
+	// +listType=atomic
+	ExpAlerts []ExpectedAlert `json:"expAlerts,omitempty"`
+}
+
+// ExpectedAlert is an alert expected to be firing.
+type ExpectedAlert struct {
+	// Expected labels of the alert, excluding the `alertname` label.
+	// +optional
+	ExpLabels map[string]string `json:"expLabels,omitempty"`
+
+	// Expected annotations of the alert.
+	// +optional
+	ExpAnnotations map[string]string `json:"expAnnotations,omitempty"`
+}
+
+// PromQLExprTest lists the samples expected to be returned by a PromQL
+// expression at a given time.
+type PromQLExprTest struct {
+	// PromQL expression to evaluate.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Expr string `json:"expr"`
+
+	// Time elapsed since time 0 at which the expression is evaluated.
+	// +kubebuilder:validation:Required
+	EvalTime monv1.Duration `json:"evalTime"`
+
+	// Samples expected to be returned. An empty list means that the
+	// expression should return no result.
+	// +optional
+	// +listType=atomic
+	ExpSamples []ExpectedSample `json:"expSamples,omitempty"`
+}
+
+// ExpectedSample is a sample expected to be returned by a PromQL expression.
+type ExpectedSample struct {
+	// Labels of the sample in the PromQL notation, e.g.
+	// `job:up:sum{job="api"}`.
+	// +optional
+	Labels string `json:"labels,omitempty"`
+
+	// Value of the sample (e.g. `1`, `0.5`, `+Inf` or `NaN`).
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+const (
+	// TestsPassedCondition reports whether all the tests of a
+	// PrometheusRuleTest passed against the latest version of the
+	// referenced PrometheusRule.
+	TestsPassedCondition ConditionType = "TestsPassed"
+)
+
+// PrometheusRuleTestStatus defines the observed state of PrometheusRuleTest.
+type PrometheusRuleTestStatus struct {
+	// Generation of the PrometheusRuleTest which was last evaluated.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Resource version of the PrometheusRule which was last evaluated.
+	// +optional
+	RuleResourceVersion string `json:"ruleResourceVersion,omitempty"`
+
+	// Time of the last evaluation.
+	// +optional
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+
+	// Number of alert and PromQL expression tests which passed.
+	// +optional
+	Passed int32 `json:"passed,omitempty"`
+
+	// Number of alert and PromQL expression tests which failed.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// Details of the failed tests.
+	// +optional
+	// +listType=atomic
+	Failures []string `json:"failures,omitempty"`
+
+	// Conditions provide status information about the test.
+	// +optional
+	// +listType=atomic
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -0,0 +1,146 @@
+package v1alpha1
+
+import (
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SLO defines a service level objective evaluated by a MonitoringStack. The
+// operator generates a PrometheusRule with the recording rules of the
+// service level indicator and multi-window, multi-burn-rate alerts, labelled
+// to be selected by the MonitoringStack, and reports the remaining error
+// budget in the status.
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:annotations="observability.openshift.io/api-support=TechPreview"
+// +kubebuilder:printcolumn:name="Stack",type="string",JSONPath=".spec.monitoringStack"
+// +kubebuilder:printcolumn:name="Objective",type="string",JSONPath=".spec.objective"
+// +kubebuilder:printcolumn:name="Window",type="string",JSONPath=".spec.window"
+// +kubebuilder:printcolumn:name="Budget Remaining",type="string",JSONPath=".status.errorBudget.remaining"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type SLO struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SLOSpec   `json:"spec,omitempty"`
+	Status SLOStatus `json:"status,omitempty"`
+}
+
+// SLOList contains a list of SLO
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SLOList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SLO `json:"items"`
+}
+
+// SLOSpec defines the objective, the service level indicator and the
+// alerting policy of an SLO.
+type SLOSpec struct {
+	// Name of the MonitoringStack, in the same namespace, evaluating the SLO.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	MonitoringStack string `json:"monitoringStack"`
+
+	// Target percentage of good events over the window, e.g. `99.9`.
+	// +kubebuilder:validation:Pattern=`^[0-9]{1,2}(\.[0-9]+)?$`
+	// +kubebuilder:validation:Required
+	Objective string `json:"objective"`
+
+	// Rolling window over which the objective is evaluated.
+	// +optional
+	// +kubebuilder:default="28d"
+	Window monv1.Duration `json:"window,omitempty"`
+
+	// Service level indicator of the SLO.
+	// +kubebuilder:validation:Required
+	Indicator SLIRatio `json:"indicator"`
+
+	// Alerting policy of the SLO.
+	// +optional
+	Alerting *SLOAlerting `json:"alerting,omitempty"`
+}
+
+// SLIRatio defines the service level indicator as the ratio of errors over
+// the total number of events. The queries must contain the `{{.window}}`
+// placeholder for the range of the rate functions and should return a
+// single series, e.g.
+// `sum(rate(http_requests_total{job="api",code=~"5.."}[{{.window}}]))`.
+type SLIRatio struct {
+	// Query returning the rate of errors (bad events).
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Errors string `json:"errors"`
+
+	// Query returning the rate of all events.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	Total string `json:"total"`
+}
+
+// SLOAlerting defines the multi-window, multi-burn-rate alerts of an SLO.
+// Page alerts fire when the error budget burns 14.4 times (over 1h and 5m)
+// or 6 times (over 6h and 30m) faster than sustainable, ticket alerts when it
+// burns 3 times (over 1d and 2h) or once (over 3d and 6h) faster.
+type SLOAlerting struct {
+	// Disable the generation of the alerts.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Name of the alerts.
+	// +optional
+	// +kubebuilder:default="SLOErrorBudgetBurn"
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Name string `json:"name,omitempty"`
+
+	// Labels added to the page alerts. Defaults to `severity: critical`.
+	// +optional
+	PageLabels map[string]string `json:"pageLabels,omitempty"`
+
+	// Labels added to the ticket alerts. Defaults to `severity: warning`.
+	// +optional
+	TicketLabels map[string]string `json:"ticketLabels,omitempty"`
+
+	// Annotations added to all the alerts.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SLOErrorBudget is the state of the error budget read back from Prometheus.
+type SLOErrorBudget struct {
+	// Time at which the error budget was last read.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Error encountered when reading the error budget.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Percentage of good events over the window.
+	// +optional
+	SLI string `json:"sli,omitempty"`
+
+	// Percentage of the error budget remaining over the window. A negative
+	// value means that the objective isn't met.
+	// +optional
+	Remaining string `json:"remaining,omitempty"`
+}
+
+// SLOStatus defines the observed state of SLO.
+type SLOStatus struct {
+	// Name of the generated PrometheusRule.
+	// +optional
+	PrometheusRule string `json:"prometheusRule,omitempty"`
+
+	// State of the error budget.
+	// +optional
+	ErrorBudget *SLOErrorBudget `json:"errorBudget,omitempty"`
+
+	// Conditions provide status information about the SLO.
+	// +optional
+	// +listType=atomic
+	Conditions []Condition `json:"conditions,omitempty"`
+}
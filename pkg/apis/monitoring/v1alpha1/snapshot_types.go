@@ -0,0 +1,150 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitoringStackSnapshot requests a point-in-time TSDB snapshot of the
+// Prometheus instance managed by a MonitoringStack.
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:annotations="observability.openshift.io/api-support=TechPreview"
+// +kubebuilder:printcolumn:name="Stack",type="string",JSONPath=".spec.monitoringStack"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Size",type="string",JSONPath=".status.size"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MonitoringStackSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MonitoringStackSnapshotSpec   `json:"spec,omitempty"`
+	Status MonitoringStackSnapshotStatus `json:"status,omitempty"`
+}
+
+// MonitoringStackSnapshotList contains a list of MonitoringStackSnapshot
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MonitoringStackSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringStackSnapshot `json:"items"`
+}
+
+// MonitoringStackSnapshotSpec defines which MonitoringStack to snapshot and
+// where the snapshot should be copied to.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type MonitoringStackSnapshotSpec struct {
+	// Name of the MonitoringStack, in the same namespace, to snapshot.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	MonitoringStack string `json:"monitoringStack"`
+
+	// Skip the data present in the head block (i.e. not yet compacted to disk).
+	// +optional
+	SkipHead bool `json:"skipHead,omitempty"`
+
+	// Destination where the snapshot is copied to once taken.
+	// +kubebuilder:validation:Required
+	Destination SnapshotDestination `json:"destination"`
+}
+
+// SnapshotDestination defines where a TSDB snapshot is stored.
+// Exactly one of the destinations must be set.
+// +kubebuilder:validation:XValidation:rule="has(self.persistentVolumeClaim) != has(self.objectStorage)",message="exactly one of persistentVolumeClaim or objectStorage must be set"
+type SnapshotDestination struct {
+	// Copy the snapshot to a PersistentVolumeClaim in the same namespace.
+	// +optional
+	PersistentVolumeClaim *SnapshotPersistentVolumeClaim `json:"persistentVolumeClaim,omitempty"`
+
+	// Upload the snapshot blocks to object storage.
+	// +optional
+	ObjectStorage *SnapshotObjectStorage `json:"objectStorage,omitempty"`
+}
+
+// SnapshotPersistentVolumeClaim references the PersistentVolumeClaim a
+// snapshot is copied to.
+type SnapshotPersistentVolumeClaim struct {
+	// Name of the PersistentVolumeClaim.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	ClaimName string `json:"claimName"`
+
+	// Directory inside the volume where the snapshot is copied to.
+	// Defaults to the name of the MonitoringStackSnapshot.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// SnapshotObjectStorage references the object storage configuration used to
+// upload a snapshot.
+type SnapshotObjectStorage struct {
+	// Secret key containing the Thanos object storage configuration.
+	// See https://thanos.io/tip/thanos/storage.md/ for the format.
+	// +kubebuilder:validation:Required
+	Config SecretKeySelector `json:"config"`
+}
+
+// SnapshotPhase is the lifecycle phase of a MonitoringStackSnapshot.
+// +kubebuilder:validation:Enum=Pending;Snapshotting;Copying;Completed;Failed
+type SnapshotPhase string
+
+const (
+	// SnapshotPending means the snapshot has not been started yet.
+	SnapshotPending SnapshotPhase = "Pending"
+
+	// SnapshotSnapshotting means the admin API has been enabled and the
+	// operator is waiting for Prometheus to take the snapshot.
+	SnapshotSnapshotting SnapshotPhase = "Snapshotting"
+
+	// SnapshotCopying means the snapshot has been taken and is being copied
+	// to its destination.
+	SnapshotCopying SnapshotPhase = "Copying"
+
+	// SnapshotCompleted means the snapshot is available at its destination.
+	SnapshotCompleted SnapshotPhase = "Completed"
+
+	// SnapshotFailed means the snapshot could not be taken or copied.
+	SnapshotFailed SnapshotPhase = "Failed"
+)
+
+// MonitoringStackSnapshotStatus defines the observed state of MonitoringStackSnapshot.
+type MonitoringStackSnapshotStatus struct {
+	// Current phase of the snapshot.
+	// +optional
+	Phase SnapshotPhase `json:"phase,omitempty"`
+
+	// Human readable message describing the current phase.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Prometheus pod the snapshot was taken from.
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// Name of the snapshot directory returned by the Prometheus admin API.
+	// +optional
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// Location of the copied snapshot.
+	// +optional
+	Location string `json:"location,omitempty"`
+
+	// Size of the copied snapshot.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Time at which the snapshot was started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Time at which the snapshot was taken by Prometheus.
+	// +optional
+	SnapshotTime *metav1.Time `json:"snapshotTime,omitempty"`
+
+	// Time at which the snapshot reached the Completed or Failed phase.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
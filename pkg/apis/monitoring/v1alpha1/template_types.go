@@ -0,0 +1,108 @@
+package v1alpha1
+
+import (
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitoringStackTemplate holds cluster-wide defaults for MonitoringStacks.
+// A MonitoringStack inherits the values of the template referenced by
+// `spec.template`; fields explicitly set in the MonitoringStack take
+// precedence over the template.
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:metadata:annotations="observability.openshift.io/api-support=TechPreview"
+// +kubebuilder:printcolumn:name="Preset",type="string",JSONPath=".spec.preset"
+// +kubebuilder:printcolumn:name="Retention",type="string",JSONPath=".spec.retention"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MonitoringStackTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MonitoringStackTemplateSpec `json:"spec,omitempty"`
+}
+
+// MonitoringStackTemplateList contains a list of MonitoringStackTemplate
+// +kubebuilder:resource
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MonitoringStackTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringStackTemplate `json:"items"`
+}
+
+// MonitoringStackPreset is a named sizing profile for MonitoringStacks.
+// +kubebuilder:validation:Enum=Small;Medium;Large
+type MonitoringStackPreset string
+
+const (
+	// SmallPreset matches the default resources of a MonitoringStack.
+	SmallPreset MonitoringStackPreset = "Small"
+
+	// MediumPreset is suited for a few hundred thousand active series.
+	MediumPreset MonitoringStackPreset = "Medium"
+
+	// LargePreset is suited for a few million active series.
+	LargePreset MonitoringStackPreset = "Large"
+)
+
+// TLSMode defines whether TLS is mandatory for the web servers of the
+// MonitoringStacks using a template.
+// +kubebuilder:validation:Enum=Optional;Required
+type TLSMode string
+
+const (
+	// TLSModeOptional leaves it to each MonitoringStack to configure TLS.
+	TLSModeOptional TLSMode = "Optional"
+
+	// TLSModeRequired rejects MonitoringStacks which don't configure TLS for
+	// the Prometheus (and Alertmanager, if deployed) web servers.
+	TLSModeRequired TLSMode = "Required"
+)
+
+// MonitoringStackTemplateSpec defines the defaults inherited by
+// MonitoringStacks.
+type MonitoringStackTemplateSpec struct {
+	// Sizing preset providing default resources for the Monitoring Stack Pods.
+	// Explicit `resources` take precedence over the preset.
+	// +optional
+	Preset MonitoringStackPreset `json:"preset,omitempty"`
+
+	// Default log level of the configured components.
+	// +optional
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+
+	// Default time duration to retain data for.
+	// +optional
+	Retention monv1.Duration `json:"retention,omitempty"`
+
+	// Default resources requests and limits for Monitoring Stack Pods.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Default tolerations for Monitoring Stack Pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Default node selector for Monitoring Stack Pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Remote write endpoints added to every MonitoringStack using the
+	// template. Endpoints defined by a MonitoringStack with the same URL
+	// replace the ones of the template.
+	// +optional
+	RemoteWrite []monv1.RemoteWriteSpec `json:"remoteWrite,omitempty"`
+
+	// External labels added to every MonitoringStack using the template.
+	// Labels defined by a MonitoringStack take precedence.
+	// +optional
+	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+
+	// Whether TLS is required on the web servers of the MonitoringStacks
+	// using the template. Defaults to `Optional`.
+	// +optional
+	TLSMode TLSMode `json:"tlsMode,omitempty"`
+}
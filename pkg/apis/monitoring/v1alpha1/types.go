@@ -7,7 +7,10 @@ package v1alpha1
 import (
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -34,6 +37,18 @@ type MonitoringStackList struct {
 	Items           []MonitoringStack `json:"items"`
 }
 
+// Suspended returns true when the stack is reported as suspended, either
+// explicitly or by its hibernation schedule. Prometheus isn't running while
+// the stack is suspended.
+func (ms *MonitoringStack) Suspended() bool {
+	for _, c := range ms.Status.Conditions {
+		if c.Type == SuspendedCondition {
+			return c.Status == ConditionTrue
+		}
+	}
+	return false
+}
+
 // Loglevel set log levels of configured components
 // +kubebuilder:validation:Enum=debug;info;warn;error
 type LogLevel string
@@ -54,8 +69,18 @@ const (
 
 // MonitoringStackSpec is the specification for desired Monitoring Stack
 type MonitoringStackSpec struct {
+	// Name of the MonitoringStackTemplate providing the defaults of the stack.
+	// Fields set in the MonitoringStack take precedence over the template.
+	// The MonitoringStacks created before the introduction of the templates
+	// store the former default values of `logLevel` (`info`), `retention`
+	// (`120h`) and `resources`: with a template, these values are considered
+	// unset and replaced by the ones of the template, if any.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Log level of the configured components. Defaults to the log level of
+	// the template or to 'info'.
 	// +optional
-	// +kubebuilder:default="info"
 	LogLevel LogLevel `json:"logLevel,omitempty"`
 
 	// Label selector for Monitoring Stack Resources.
@@ -71,14 +96,15 @@ type MonitoringStackSpec struct {
 	// +optional
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 
-	// Time duration to retain data for. Default is '120h',
-	// and must match the regular expression `[0-9]+(ms|s|m|h|d|w|y)` (milliseconds seconds minutes hours days weeks years).
-	// +kubebuilder:default="120h"
+	// Time duration to retain data for. Default is the retention of the
+	// template or '120h', and must match the regular expression `[0-9]+(ms|s|m|h|d|w|y)` (milliseconds seconds minutes hours days weeks years).
+	// +optional
 	Retention monv1.Duration `json:"retention,omitempty"`
 
 	// Define resources requests and limits for Monitoring Stack Pods.
+	// Defaults to the resources of the template (or its preset) or to
+	// `{requests:{cpu: "100m", memory: "256Mi"}, limits:{memory: "512Mi", cpu: "500m"}}`.
 	// +optional
-	// +kubebuilder:default={requests:{cpu: "100m", memory: "256Mi"}, limits:{memory: "512Mi", cpu: "500m"}}
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
 	// Define tolerations for Monitoring Stack Pods.
@@ -98,6 +124,210 @@ type MonitoringStackSpec struct {
 	// +optional
 	// +kubebuilder:default={disabled: false}
 	AlertmanagerConfig AlertmanagerConfig `json:"alertmanagerConfig,omitempty"`
+
+	// Suspend scales Prometheus, Alertmanager and the blackbox exporter down to
+	// zero replicas while keeping their persistent volumes. While suspended,
+	// changes to the MonitoringStack are not applied until it is resumed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Hibernation suspends the MonitoringStack on a recurring schedule.
+	// It has no effect when `suspend` is true.
+	// +optional
+	Hibernation *HibernationConfig `json:"hibernation,omitempty"`
+
+	// Insights configures the periodic collection of cardinality and target
+	// statistics from Prometheus into the status.
+	// +optional
+	Insights *InsightsConfig `json:"insights,omitempty"`
+
+	// Recommendations configures the computation of resource
+	// recommendations for Prometheus from the usage observed by the stack
+	// itself, and optionally their automatic application.
+	// +optional
+	Recommendations *RecommendationsConfig `json:"recommendations,omitempty"`
+
+	// Probing deploys a blackbox exporter executing the Probes selected by
+	// the stack. The Probes must set `prober.url` to
+	// `<name>-blackbox-exporter.<namespace>.svc:9115`.
+	// +optional
+	Probing *ProbingConfig `json:"probing,omitempty"`
+
+	// Federation configures the scraping of the `/federate` endpoint of the
+	// Prometheus instances of other MonitoringStacks.
+	// +optional
+	Federation *FederationConfig `json:"federation,omitempty"`
+}
+
+// FederationConfig selects the MonitoringStacks federated by a stack and the
+// series collected from them. When a federated stack serves its API over
+// TLS, its certificate authority is used to verify the connection.
+type FederationConfig struct {
+	// Label selector for the federated MonitoringStacks. The stack itself is
+	// never federated.
+	// +kubebuilder:validation:Required
+	StackSelector metav1.LabelSelector `json:"stackSelector"`
+
+	// Namespace selector for the federated MonitoringStacks.
+	// To select all namespaces, set to empty map selector. E.g. namespaceSelector: {}.
+	// To select MonitoringStacks in the namespace of the stack only, set to null. E.g. namespaceSelector:.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Series selectors of the federated series, passed as `match[]`
+	// parameters to the `/federate` endpoint.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:Required
+	Match []string `json:"match"`
+
+	// Interval between the federation scrapes. Defaults to the scrape
+	// interval of the stack.
+	// +optional
+	Interval *monv1.Duration `json:"interval,omitempty"`
+
+	// Keep the labels of the federated series when they conflict with the
+	// target labels. Defaults to the value of `true`.
+	// +optional
+	// +kubebuilder:default=true
+	HonorLabels *bool `json:"honorLabels,omitempty"`
+}
+
+// ProbingConfig defines the blackbox exporter deployed for the stack. The
+// Probes use it by setting their prober URL to the address of its Service
+// (`<name>-blackbox-exporter.<namespace>.svc:9115`).
+type ProbingConfig struct {
+	// Enables the deployment of the blackbox exporter.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Number of replicas of the blackbox exporter.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Define resources requests and limits for the blackbox exporter pods.
+	// Defaults to `{requests:{cpu: "10m", memory: "32Mi"}}`.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Modules of the blackbox exporter, indexed by name. They are added to
+	// the default `http_2xx`, `http_post_2xx` and `tcp_connect` modules and
+	// override them when they have the same name.
+	// +optional
+	Modules map[string]ProbeModule `json:"modules,omitempty"`
+}
+
+// ProbeModule defines a module of the blackbox exporter.
+// See https://github.com/prometheus/blackbox_exporter/blob/master/CONFIGURATION.md.
+type ProbeModule struct {
+	// Prober used by the module.
+	// +kubebuilder:validation:Enum=http;tcp;icmp;dns;grpc
+	// +kubebuilder:validation:Required
+	Prober string `json:"prober"`
+
+	// Timeout of the probes. Defaults to the scrape timeout.
+	// +optional
+	Timeout monv1.Duration `json:"timeout,omitempty"`
+
+	// Configuration of the prober, i.e. the content of the `http`, `tcp`,
+	// `icmp`, `dns` or `grpc` section of the module.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	Config *apiextensionsv1.JSON `json:"config,omitempty"`
+}
+
+// RecommendationsConfig defines how resource recommendations are computed
+// and applied.
+type RecommendationsConfig struct {
+	// Enables the computation of resource recommendations.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Time window over which the resource usage is observed.
+	// +optional
+	// +kubebuilder:default="24h"
+	Window monv1.Duration `json:"window,omitempty"`
+
+	// Autoscale applies the recommendations to the Prometheus pods.
+	// +optional
+	Autoscale *AutoscalePolicy `json:"autoscale,omitempty"`
+}
+
+// AutoscalePolicy defines how and within which bounds resource
+// recommendations are applied.
+type AutoscalePolicy struct {
+	// Enables the automatic application of the recommendations. The
+	// recommended resources replace the `resources` of the stack and the
+	// Prometheus persistent volume claims are expanded (never shrunk) when
+	// their storage class allows it.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Lower bounds for the applied cpu and memory requests.
+	// +optional
+	MinAllowed corev1.ResourceList `json:"minAllowed,omitempty"`
+
+	// Upper bounds for the applied cpu and memory requests and limits, and
+	// for the storage size.
+	// +optional
+	MaxAllowed corev1.ResourceList `json:"maxAllowed,omitempty"`
+
+	// Minimum difference, in percent, between the applied and the
+	// recommended requests for the recommendation to be applied.
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Tolerance int32 `json:"tolerance,omitempty"`
+
+	// Minimum duration between two updates of the Prometheus resources.
+	// Updates are only applied while the stack is available so that the
+	// Prometheus pods are rolled one at a time.
+	// +optional
+	// +kubebuilder:default="6h"
+	CooldownPeriod monv1.Duration `json:"cooldownPeriod,omitempty"`
+}
+
+// InsightsConfig defines how cardinality and target statistics are collected
+// from Prometheus.
+type InsightsConfig struct {
+	// Enables the collection of insights.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Interval between two collections.
+	// +optional
+	// +kubebuilder:default="5m"
+	Interval monv1.Duration `json:"interval,omitempty"`
+
+	// Number of entries reported for the top metric names and label pairs.
+	// +optional
+	// +kubebuilder:default=10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=50
+	Limit int32 `json:"limit,omitempty"`
+}
+
+// HibernationConfig defines when a MonitoringStack is automatically suspended
+// and resumed. Schedules use the standard 5-field cron format
+// (e.g. "0 19 * * 1-5").
+type HibernationConfig struct {
+	// Cron schedule at which the MonitoringStack is suspended.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	SleepSchedule string `json:"sleepSchedule"`
+
+	// Cron schedule at which the MonitoringStack is resumed.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Required
+	WakeSchedule string `json:"wakeSchedule"`
+
+	// Time zone name (e.g. "Europe/Paris") in which the schedules are
+	// evaluated. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 // MonitoringStackStatus defines the observed state of MonitoringStack.
@@ -106,6 +336,157 @@ type MonitoringStackStatus struct {
 	// Conditions provide status information about the MonitoringStack
 	// +listType=atomic
 	Conditions []Condition `json:"conditions"`
+
+	// EffectiveSpec is the specification of the MonitoringStack after merging
+	// it with its template and applying the default values. It's unset when
+	// the template can't be resolved.
+	// +optional
+	EffectiveSpec *MonitoringStackSpec `json:"effectiveSpec,omitempty"`
+
+	// Insights reports cardinality and target statistics collected from
+	// Prometheus when enabled.
+	// +optional
+	Insights *MonitoringStackInsights `json:"insights,omitempty"`
+
+	// Recommendations reports the resources recommended for Prometheus
+	// when enabled.
+	// +optional
+	Recommendations *ResourceRecommendations `json:"recommendations,omitempty"`
+
+	// Validation reports the PrometheusRules, ServiceMonitors, PodMonitors,
+	// Probes and ScrapeConfigs selected by the stack which fail validation.
+	// +optional
+	Validation *ResourceValidation `json:"validation,omitempty"`
+}
+
+// ResourceValidation holds the result of the validation of the resources
+// selected by a MonitoringStack.
+type ResourceValidation struct {
+	// Number of validated resources.
+	// +optional
+	Validated int32 `json:"validated,omitempty"`
+
+	// Resources rejected by the validation.
+	// +optional
+	// +listType=atomic
+	Rejected []RejectedResource `json:"rejected,omitempty"`
+}
+
+// RejectedResource is a resource selected by a MonitoringStack which fails
+// validation.
+type RejectedResource struct {
+	// Kind of the resource.
+	Kind string `json:"kind"`
+	// Namespace of the resource.
+	Namespace string `json:"namespace"`
+	// Name of the resource.
+	Name string `json:"name"`
+	// Reasons why the resource is rejected.
+	// +listType=atomic
+	Reasons []string `json:"reasons"`
+}
+
+// ResourceRecommendations holds the resources recommended for the Prometheus
+// instance of a MonitoringStack and the ones applied by the autoscaler.
+type ResourceRecommendations struct {
+	// Time of the last computation.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Error which occurred during the last computation, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Recommended resources for the Prometheus containers.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Recommended size of the Prometheus persistent volumes.
+	// +optional
+	Storage *resource.Quantity `json:"storage,omitempty"`
+
+	// Number of series in the head block when the recommendation was
+	// computed.
+	// +optional
+	HeadSeries int64 `json:"headSeries,omitempty"`
+
+	// Observed growth of the number of head series per day.
+	// +optional
+	HeadSeriesGrowthPerDay int64 `json:"headSeriesGrowthPerDay,omitempty"`
+
+	// Resources applied to the Prometheus containers by the autoscaler.
+	// +optional
+	AppliedResources *corev1.ResourceRequirements `json:"appliedResources,omitempty"`
+
+	// Storage size applied to the Prometheus persistent volume claims by the
+	// autoscaler.
+	// +optional
+	AppliedStorage *resource.Quantity `json:"appliedStorage,omitempty"`
+
+	// Time when the autoscaler last updated the applied resources.
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+// MonitoringStackInsights holds cardinality and target statistics of the
+// Prometheus instance of a MonitoringStack.
+type MonitoringStackInsights struct {
+	// Time of the last collection.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Error which occurred during the last collection, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Number of series in the head block.
+	// +optional
+	HeadSeries int64 `json:"headSeries,omitempty"`
+
+	// Number of chunks in the head block.
+	// +optional
+	HeadChunks int64 `json:"headChunks,omitempty"`
+
+	// Metric names with the highest number of series.
+	// +optional
+	// +listType=atomic
+	TopMetrics []SeriesCount `json:"topMetrics,omitempty"`
+
+	// Label name/value pairs with the highest number of series.
+	// +optional
+	// +listType=atomic
+	TopLabelPairs []SeriesCount `json:"topLabelPairs,omitempty"`
+
+	// Health of the scrape targets, per scrape pool.
+	// +optional
+	// +listType=atomic
+	Targets []ScrapePoolTargets `json:"targets,omitempty"`
+}
+
+// SeriesCount is the number of series for a metric name or a label pair.
+type SeriesCount struct {
+	Name   string `json:"name"`
+	Series int64  `json:"series"`
+}
+
+// ScrapePoolTargets summarizes the health of the targets of a scrape pool.
+// The scrape pool identifies the resource which generated the scrape
+// configuration (e.g. "serviceMonitor/<namespace>/<name>/<endpoint>").
+type ScrapePoolTargets struct {
+	ScrapePool string `json:"scrapePool"`
+	// Value of the "job" label of the targets.
+	// +optional
+	Job string `json:"job,omitempty"`
+	// Number of healthy targets.
+	Up int32 `json:"up"`
+	// Number of unhealthy targets.
+	Down int32 `json:"down"`
+	// Number of targets not scraped yet.
+	// +optional
+	Unknown int32 `json:"unknown,omitempty"`
+	// Last scrape error of one of the unhealthy targets.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 type ConditionStatus string
@@ -124,6 +505,10 @@ const (
 	ReconciledCondition        ConditionType = "Reconciled"
 	AvailableCondition         ConditionType = "Available"
 	ResourceDiscoveryCondition ConditionType = "ResourceDiscovery"
+	SuspendedCondition         ConditionType = "Suspended"
+	// ConfigurationLoadedCondition reports whether the Prometheus pods
+	// successfully loaded their latest configuration and rules.
+	ConfigurationLoadedCondition ConditionType = "ConfigurationLoaded"
 )
 
 type Condition struct {
@@ -201,9 +586,84 @@ type PrometheusConfig struct {
 	// Default interval between scrapes.
 	// +optional
 	ScrapeInterval *monv1.Duration `json:"scrapeInterval,omitempty"`
+	// Default timeout of the scrapes. It must not be greater than the scrape
+	// interval (30s when unset), otherwise the stack isn't reconciled.
+	// +optional
+	ScrapeTimeout *monv1.Duration `json:"scrapeTimeout,omitempty"`
+	// Protocols negotiated during the scrapes, in order of preference.
+	// +optional
+	// +listType=set
+	ScrapeProtocols []monv1.ScrapeProtocol `json:"scrapeProtocols,omitempty"`
+	// Interval between the evaluations of the rules.
+	// +optional
+	EvaluationInterval *monv1.Duration `json:"evaluationInterval,omitempty"`
+	// Enable the ingestion of native histograms (`native-histograms`
+	// feature). Defaults to the value of `false`.
+	// +optional
+	EnableNativeHistograms *bool `json:"enableNativeHistograms,omitempty"`
+	// Scrape the classic histograms of the targets exposing both native and
+	// classic histograms. It applies to the scrape jobs generated by the
+	// operator (e.g. self-monitoring).
+	// +optional
+	ScrapeClassicHistograms *bool `json:"scrapeClassicHistograms,omitempty"`
+	// Maximum number of buckets of the native histograms. Histograms with
+	// more buckets are reduced in resolution. It applies to the scrape jobs
+	// generated by the operator (e.g. self-monitoring).
+	// +optional
+	NativeHistogramBucketLimit *uint64 `json:"nativeHistogramBucketLimit,omitempty"`
+	// Minimum growth factor between the buckets of the native histograms.
+	// Histograms with a lower factor are reduced in resolution. It applies
+	// to the scrape jobs generated by the operator (e.g. self-monitoring).
+	// +optional
+	NativeHistogramMinBucketFactor *resource.Quantity `json:"nativeHistogramMinBucketFactor,omitempty"`
 	// Configure TLS options for the Prometheus web server.
 	// +optional
 	WebTLSConfig *WebTLSConfig `json:"webTLSConfig,omitempty"`
+	// Enable mutual TLS on the gRPC server of the Thanos sidecar queried by
+	// the ThanosQueriers. The sidecar only trusts the client certificates
+	// signed by the client certificate authority of the operator, which are
+	// issued to the ThanosQueriers of the namespaces allowed by a cluster
+	// administrator (see the `grpcTLS` field of the ThanosQueriers).
+	// +optional
+	ThanosSidecarGRPCTLS *GRPCTLSConfig `json:"thanosSidecarGRPCTLS,omitempty"`
+	// Configure the export of the traces of Prometheus (e.g. PromQL queries)
+	// to an OTLP endpoint.
+	// +optional
+	TracingConfig *monv1.PrometheusTracingConfig `json:"tracingConfig,omitempty"`
+	// Configure the storage of exemplars. Setting it enables the
+	// `exemplar-storage` feature of Prometheus.
+	// +optional
+	Exemplars *monv1.Exemplars `json:"exemplars,omitempty"`
+	// Configure the limits of the PromQL queries and the query log.
+	// +optional
+	Query *QueryConfig `json:"query,omitempty"`
+}
+
+// QueryConfig defines the limits applied to the PromQL queries evaluated by
+// Prometheus and the logging of the queries.
+type QueryConfig struct {
+	// Maximum number of queries executed concurrently.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrency *int32 `json:"maxConcurrency,omitempty"`
+	// Maximum duration of a query before it is aborted.
+	// +optional
+	Timeout *monv1.Duration `json:"timeout,omitempty"`
+	// Maximum number of samples a single query can load into memory. Queries
+	// loading more samples fail.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxSamples *int32 `json:"maxSamples,omitempty"`
+	// Maximum duration for which a sample is considered when evaluating an
+	// expression (defaults to 5m).
+	// +optional
+	LookbackDelta *monv1.Duration `json:"lookbackDelta,omitempty"`
+	// File to which the PromQL queries are logged. A file name without
+	// directory (e.g. `query.log`) is written in an emptyDir volume mounted
+	// at `/var/log/prometheus`. Use `/dev/stdout` to log the queries to the
+	// Prometheus logs.
+	// +optional
+	QueryLogFile string `json:"queryLogFile,omitempty"`
 }
 
 type AlertmanagerConfig struct {
@@ -216,15 +676,21 @@ type AlertmanagerConfig struct {
 	WebTLSConfig *WebTLSConfig `json:"webTLSConfig,omitempty"`
 }
 
-// NamespaceSelector is a selector for selecting either all namespaces or a
-// list of namespaces.
+// NamespaceSelector is a selector for selecting either all namespaces or the
+// namespaces matching a list of names and a label selector. When neither
+// names nor labels are specified, only the namespace of the ThanosQuerier is
+// selected.
 // +k8s:openapi-gen=true
+// +kubebuilder:validation:XValidation:rule="!has(self.any) || !self.any || ((!has(self.matchLabels) || size(self.matchLabels) == 0) && (!has(self.matchExpressions) || size(self.matchExpressions) == 0))",message="any can't be combined with matchLabels or matchExpressions"
 type NamespaceSelector struct {
 	// Boolean describing whether all namespaces are selected in contrast to a
-	// list restricting them.
+	// list restricting them. It can't be combined with a label selector.
 	Any bool `json:"any,omitempty"`
-	// List of namespace names.
+	// List of namespace names. When combined with a label selector, the
+	// namespaces must match both.
 	MatchNames []string `json:"matchNames,omitempty"`
+	// Label selector of the namespaces.
+	metav1.LabelSelector `json:",inline"`
 }
 
 // ThanosQuerier outlines the Thanos querier components, managed by this stack
@@ -241,23 +707,55 @@ type ThanosQuerier struct {
 	Status ThanosQuerierStatus `json:"status,omitempty"`
 }
 
-func (t ThanosQuerier) MatchesNamespace(namespace string) bool {
+// NamespaceLabelSelector converts the namespace selector of the querier to a
+// label selector on the namespaces. The names of `matchNames` are converted
+// to a requirement on the `kubernetes.io/metadata.name` label. When no
+// namespace is selected, the namespace of the querier is selected.
+func (t ThanosQuerier) NamespaceLabelSelector() (labels.Selector, error) {
 	namespaceSelector := t.Spec.NamespaceSelector
 	if namespaceSelector.Any {
-		return true
+		return labels.Everything(), nil
 	}
 
-	if len(namespaceSelector.MatchNames) == 0 {
-		return t.Namespace == namespace
+	selector := namespaceSelector.LabelSelector.DeepCopy()
+	names := namespaceSelector.MatchNames
+	if len(names) == 0 && len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		names = []string{t.Namespace}
+	}
+	if len(names) > 0 {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      corev1.LabelMetadataName,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   names,
+		})
 	}
 
-	for _, ns := range namespaceSelector.MatchNames {
-		if ns == namespace {
-			return true
-		}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// MatchesNamespace returns true if the namespace with the given name is
+// selected by the namespace selector of the querier. The labels of the
+// namespace are unknown: a querier selecting namespaces by labels never
+// matches, use MatchesNamespaceObject instead.
+func (t ThanosQuerier) MatchesNamespace(namespace string) bool {
+	return t.MatchesNamespaceObject(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+}
+
+// MatchesNamespaceObject returns true if the namespace is selected by the
+// namespace selector of the querier.
+func (t ThanosQuerier) MatchesNamespaceObject(ns *corev1.Namespace) bool {
+	selector, err := t.NamespaceLabelSelector()
+	if err != nil {
+		return false
 	}
 
-	return false
+	// The name label is set by the API server but it may be missing from
+	// namespaces built locally.
+	lbls := labels.Set{corev1.LabelMetadataName: ns.Name}
+	for k, v := range ns.Labels {
+		lbls[k] = v
+	}
+	return selector.Matches(lbls)
 }
 
 // ThanosQuerierList contains a list of ThanosQuerier
@@ -273,17 +771,374 @@ type ThanosQuerierList struct {
 // label selector by which Monitoring Stack instances to query are selected, and
 // an optional namespace selector and a list of replica labels by which to
 // deduplicate.
+// +kubebuilder:validation:XValidation:rule="!has(self.tenancy) || !self.tenancy.enabled || !has(self.queryFrontend) || !self.queryFrontend.enabled",message="the query frontend can't be deployed in tenancy mode"
+// +kubebuilder:validation:XValidation:rule="!has(self.includeOpenShiftPlatform) || !self.includeOpenShiftPlatform || (has(self.openShiftPlatformTLSSecretName) && size(self.openShiftPlatformTLSSecretName) > 0)",message="openShiftPlatformTLSSecretName is required to query the OpenShift platform"
 type ThanosQuerierSpec struct {
 	// Selector to select Monitoring stacks to unify
 	Selector metav1.LabelSelector `json:"selector"`
 	// Selector to select which namespaces the Monitoring Stack objects are discovered from.
 	NamespaceSelector NamespaceSelector `json:"namespaceSelector,omitempty"`
 	ReplicaLabels     []string          `json:"replicaLabels,omitempty"`
+
+	// Deduplicate the series of the replicas of the queried Prometheus
+	// instances. When disabled, the replica labels aren't configured and
+	// `replicaLabels` must be empty. Defaults to true.
+	// +optional
+	Deduplication *bool `json:"deduplication,omitempty"`
+
+	// Replica label configured in addition to `replicaLabels`. Defaults to
+	// 'prometheus_replica', the replica label of the Prometheus instances
+	// deployed by the MonitoringStacks.
+	// +optional
+	DefaultReplicaLabel string `json:"defaultReplicaLabel,omitempty"`
+
+	// Settings of the query engine of the Thanos querier.
+	// +optional
+	Query *ThanosQuerierQueryConfig `json:"query,omitempty"`
+
+	// Number of replicas of the Thanos querier. A PodDisruptionBudget and a
+	// pod anti-affinity are configured when more than one replica is
+	// deployed.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources requests and limits of the Thanos querier container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Node selector of the Thanos querier pods. Defaults to
+	// `kubernetes.io/os: linux`.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the Thanos querier pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity of the Thanos querier pods. When more than one replica is
+	// deployed, defaults to spreading the replicas across nodes and zones.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Topology spread constraints of the Thanos querier pods.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// Priority class of the Thanos querier pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Log level of the Thanos querier. Defaults to 'info'.
+	// +optional
+	LogLevel LogLevel `json:"logLevel,omitempty"`
+
+	// Enable mutual TLS on the gRPC connections to the Thanos sidecars. All
+	// the selected MonitoringStacks must enable
+	// `prometheusConfig.thanosSidecarGRPCTLS`. The client certificates are
+	// trusted by the sidecars of every MonitoringStack: the operator only
+	// issues them in the namespaces labelled with
+	// `monitoring.rhobs/grpc-client-certificates=true`, which is usually
+	// restricted to the cluster administrators. In the other namespaces,
+	// `secretName` must reference a certificate signed by the certificate
+	// authority of the `observability-operator-grpc-client-ca` secret in the
+	// operator namespace.
+	// +optional
+	GRPCTLS *ThanosQuerierGRPCTLSConfig `json:"grpcTLS,omitempty"`
+
+	// Additional StoreAPI endpoints queried by the Thanos querier besides the
+	// Thanos sidecars of the selected MonitoringStacks, e.g. the Thanos
+	// sidecars of Prometheus instances which aren't managed by a
+	// MonitoringStack.
+	// +optional
+	// +listType=atomic
+	AdditionalEndpoints []ThanosEndpoint `json:"additionalEndpoints,omitempty"`
+
+	// Query the Prometheus instances of the OpenShift platform monitoring
+	// stack in the `openshift-monitoring` namespace, like the platform
+	// thanos-querier does. Requires `openShiftPlatformTLSSecretName`.
+	// +optional
+	IncludeOpenShiftPlatform bool `json:"includeOpenShiftPlatform,omitempty"`
+
+	// Name of the secret, in the namespace of the ThanosQuerier, holding the
+	// gRPC client certificate (`tls.crt`), private key (`tls.key`) and
+	// certificate authority (`ca.crt`) used to query the OpenShift platform.
+	// The operator never copies the client certificate of the platform
+	// thanos-querier: the secret must be provided by a cluster administrator.
+	// +optional
+	OpenShiftPlatformTLSSecretName string `json:"openShiftPlatformTLSSecretName,omitempty"`
+
+	// Tenancy mode restricting the queries of the callers to the namespaces
+	// they have access to.
+	// +optional
+	Tenancy *ThanosQuerierTenancyConfig `json:"tenancy,omitempty"`
+
+	// Query frontend deployed in front of the Thanos querier. When enabled,
+	// the Service and the ServiceMonitor of the ThanosQuerier target the query
+	// frontend.
+	// +optional
+	QueryFrontend *ThanosQueryFrontendConfig `json:"queryFrontend,omitempty"`
+}
+
+// ThanosPartialResponseStrategy defines how the Thanos querier handles the
+// failure of some of the StoreAPI endpoints.
+// +kubebuilder:validation:Enum=Warn;Abort
+type ThanosPartialResponseStrategy string
+
+const (
+	// The results of the available endpoints are returned with a warning.
+	PartialResponseWarn ThanosPartialResponseStrategy = "Warn"
+	// The query fails.
+	PartialResponseAbort ThanosPartialResponseStrategy = "Abort"
+)
+
+// ThanosQuerierQueryConfig defines the settings of the query engine of the
+// Thanos querier. The unset fields default to the Thanos defaults.
+type ThanosQuerierQueryConfig struct {
+	// Behaviour of the queries when some of the StoreAPI endpoints fail.
+	// Defaults to 'Warn'.
+	// +optional
+	PartialResponseStrategy ThanosPartialResponseStrategy `json:"partialResponseStrategy,omitempty"`
+
+	// Maximum duration of a query before it is aborted.
+	// +optional
+	Timeout *monv1.Duration `json:"timeout,omitempty"`
+
+	// Maximum number of queries executed concurrently.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrent *int32 `json:"maxConcurrent,omitempty"`
+
+	// Maximum duration for which a sample is considered when evaluating an
+	// expression.
+	// +optional
+	LookbackDelta *monv1.Duration `json:"lookbackDelta,omitempty"`
+
+	// Evaluation interval of the subqueries which don't specify their step.
+	// +optional
+	DefaultEvaluationInterval *monv1.Duration `json:"defaultEvaluationInterval,omitempty"`
+
+	// Maximum duration to wait for the response of a StoreAPI endpoint
+	// before ignoring it. It must be lower than `timeout`. Defaults to no
+	// timeout.
+	// +optional
+	StoreResponseTimeout *monv1.Duration `json:"storeResponseTimeout,omitempty"`
+}
+
+// ThanosQueryFrontendConfig defines the Thanos query frontend which splits
+// the range queries, retries them on failure and caches their results. The
+// query frontend pods share the node selector, the tolerations and the
+// priority class of the Thanos querier.
+type ThanosQueryFrontendConfig struct {
+	// Deploy the query frontend.
+	Enabled bool `json:"enabled"`
+
+	// Number of replicas of the query frontend.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources requests and limits of the query frontend container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Interval by which the range queries are split. Defaults to '24h'.
+	// +optional
+	SplitInterval *monv1.Duration `json:"splitInterval,omitempty"`
+
+	// Maximum number of retries of a single request. Defaults to 5.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// Cache of the query results. Defaults to an in-memory cache.
+	// +optional
+	Cache *ThanosQueryFrontendCache `json:"cache,omitempty"`
+}
+
+// ThanosCacheType is the backend of the results cache of the query frontend.
+// +kubebuilder:validation:Enum=InMemory;Memcached;Redis
+type ThanosCacheType string
+
+const (
+	InMemoryCache  ThanosCacheType = "InMemory"
+	MemcachedCache ThanosCacheType = "Memcached"
+	RedisCache     ThanosCacheType = "Redis"
+)
+
+// ThanosQueryFrontendCache defines the results cache of the query frontend.
+// +kubebuilder:validation:XValidation:rule="self.type == 'InMemory' || (has(self.addresses) && size(self.addresses) > 0)",message="addresses are required for the Memcached and Redis caches"
+// +kubebuilder:validation:XValidation:rule="self.type != 'Redis' || !has(self.addresses) || size(self.addresses) == 1",message="the Redis cache requires exactly one address"
+// +kubebuilder:validation:XValidation:rule="self.type == 'InMemory' || !has(self.maxSize)",message="maxSize is only supported by the InMemory cache"
+type ThanosQueryFrontendCache struct {
+	// Backend of the cache.
+	// +optional
+	// +kubebuilder:default=InMemory
+	Type ThanosCacheType `json:"type,omitempty"`
+
+	// Maximum size of the in-memory cache (e.g. '256MB'). Defaults to the
+	// Thanos default.
+	// +optional
+	MaxSize string `json:"maxSize,omitempty"`
+
+	// Addresses (`host:port`) of the Memcached servers or of the Redis
+	// server.
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Validity of the cached results. Defaults to the Thanos default.
+	// +optional
+	Validity *monv1.Duration `json:"validity,omitempty"`
+}
+
+// ThanosQuerierTenancyConfig defines the tenancy mode of the Thanos querier.
+// When enabled, kube-rbac-proxy and prom-label-proxy are deployed in front of
+// the querier and served over HTTPS on the `tenancy` port (9092) of the
+// `thanos-querier-<name>-tenancy` Service. The callers pass the namespace in
+// the `namespace` query parameter: the request is allowed if the caller can
+// get `pods.metrics.k8s.io` in the namespace and the label matcher of the
+// namespace is enforced in the queries. Only the query, series and label APIs
+// are served.
+//
+// The HTTP and gRPC APIs of the querier are then only reachable from its pod:
+// the `thanos-querier-<name>` Service isn't available and the query frontend
+// can't be deployed. The metrics and the stores API of the querier are served
+// over HTTPS on the `metrics` port (9093) of the tenancy Service to the
+// callers allowed to get the `/metrics` and `/api/v1/stores` non-resource URLs
+// and to the `thanos-querier-<name>-metrics-reader` service account, whose
+// token is used by the ServiceMonitor and by the operator.
+type ThanosQuerierTenancyConfig struct {
+	// Deploy the tenancy proxies.
+	Enabled bool `json:"enabled"`
+
+	// Label enforced in the queries, which is also the name of the query
+	// parameter holding its value.
+	// +optional
+	// +kubebuilder:default=namespace
+	Label string `json:"label,omitempty"`
+
+	// Name of the secret holding the serving certificate (`tls.crt`), the
+	// private key (`tls.key`) and the certificate authority (`ca.crt`) of the
+	// tenancy endpoint. When empty, the operator generates the secret
+	// `<name>-tenancy-tls` with a certificate signed by its own certificate
+	// authority.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// ThanosEndpoint is a StoreAPI endpoint queried by the Thanos querier.
+// +kubebuilder:validation:XValidation:rule="[has(self.address), has(self.dnsSRV), has(self.serviceSelector)].filter(x, x).size() == 1",message="exactly one of address, dnsSRV and serviceSelector must be set"
+type ThanosEndpoint struct {
+	// Static address of the endpoint (`<host>:<port>`).
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// DNS SRV record resolved to the addresses of the endpoint (e.g.
+	// `_grpc._tcp.prometheus-operated.monitoring.svc.cluster.local`).
+	// +optional
+	DNSSRV string `json:"dnsSRV,omitempty"`
+
+	// Services exposing the endpoint.
+	// +optional
+	ServiceSelector *ThanosServiceSelector `json:"serviceSelector,omitempty"`
+
+	// TLS configuration of the gRPC connections to the endpoint. When not
+	// set, the connections are configured like the connections to the
+	// Thanos sidecars of the MonitoringStacks (see `grpcTLS`). The endpoints
+	// resolved through DNS (`dnsSRV` and `serviceSelector`) with a TLS
+	// configuration are queried as a group: each query is sent to only one
+	// of their addresses, which must serve the same data (e.g. the replicas
+	// of a Prometheus).
+	// +optional
+	TLS *ThanosEndpointTLSConfig `json:"tls,omitempty"`
+}
+
+// ThanosServiceSelector selects the Services exposing a StoreAPI endpoint.
+type ThanosServiceSelector struct {
+	// Label selector of the Services.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Namespace of the Services. Defaults to the namespace of the
+	// ThanosQuerier.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the gRPC port of the Services.
+	// +optional
+	// +kubebuilder:default=grpc
+	Port string `json:"port,omitempty"`
+}
+
+// ThanosEndpointTLSConfig configures TLS on the gRPC connections to a StoreAPI
+// endpoint. The secrets must be in the namespace of the ThanosQuerier.
+// +kubebuilder:validation:XValidation:rule="has(self.cert) == has(self.key)",message="cert and key must be set together"
+type ThanosEndpointTLSConfig struct {
+	// Certificate authority verifying the certificate of the endpoint.
+	// Defaults to the system certificate authorities.
+	// +optional
+	CA *SecretKeySelector `json:"ca,omitempty"`
+
+	// Client certificate presented to the endpoint.
+	// +optional
+	Cert *SecretKeySelector `json:"cert,omitempty"`
+
+	// Private key of the client certificate.
+	// +optional
+	Key *SecretKeySelector `json:"key,omitempty"`
+
+	// Name verified against the certificate of the endpoint.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
 }
 
 // ThanosQuerierStatus defines the observed state of ThanosQuerier.
 // It should always be reconstructable from the state of the cluster and/or outside world.
-type ThanosQuerierStatus struct{}
+type ThanosQuerierStatus struct {
+	// Conditions provide status information about the ThanosQuerier.
+	// +optional
+	// +listType=atomic
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// MonitoringStacks queried by the Thanos querier, as `<namespace>/<name>`.
+	// +optional
+	MonitoringStacks []string `json:"monitoringStacks,omitempty"`
+
+	// MonitoringStacks matching the selector which aren't queried because
+	// their namespace isn't selected, as `<namespace>/<name>`.
+	// +optional
+	ExcludedMonitoringStacks []string `json:"excludedMonitoringStacks,omitempty"`
+
+	// Endpoints configured in the Thanos querier.
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Health of the stores known by the Thanos querier.
+	// +optional
+	Stores []ThanosStoreStatus `json:"stores,omitempty"`
+}
+
+// ThanosStoreStatus is the health of a store known by the Thanos querier.
+type ThanosStoreStatus struct {
+	// Address of the store.
+	Name string `json:"name"`
+
+	// Type of the store (e.g. `sidecar`).
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Whether the last health check of the store succeeded.
+	Healthy bool `json:"healthy"`
+
+	// Time of the last health check of the store.
+	// +optional
+	LastCheck *metav1.Time `json:"lastCheck,omitempty"`
+
+	// Error of the last health check of the store.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
 
 // SecretKeySelector selects a key of a secret.
 type SecretKeySelector struct {
@@ -297,6 +1152,33 @@ type SecretKeySelector struct {
 	Key string `json:"key"`
 }
 
+// GRPCTLSConfig configures mutual TLS on gRPC connections.
+type GRPCTLSConfig struct {
+	// Name of the secret holding the certificate (`tls.crt`), the private
+	// key (`tls.key`) and the certificate authority verifying the peers
+	// (`ca.crt`). When empty, the operator generates the secret
+	// `<name>-grpc-tls` with a certificate signed by its own certificate
+	// authorities (`observability-operator-grpc-ca` for the sidecars and
+	// `observability-operator-grpc-client-ca` for the queriers) and renews
+	// it before it expires. The certificate of a sidecar must be valid for
+	// the name of its Service (`<name>-thanos-sidecar.<namespace>.svc`).
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ThanosQuerierGRPCTLSConfig configures mutual TLS on the gRPC connections
+// of the Thanos querier.
+type ThanosQuerierGRPCTLSConfig struct {
+	GRPCTLSConfig `json:",inline"`
+
+	// Name verified against the certificates of the Thanos sidecars.
+	// Defaults to the name of the sidecar Service of each MonitoringStack
+	// (`<name>-thanos-sidecar.<namespace>.svc`) for which the operator
+	// issues the sidecar certificates.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+}
+
 // WebTLSConfig contains configuration to enable TLS on web endpoints.
 type WebTLSConfig struct {
 	// Reference to the TLS private key for the web server.
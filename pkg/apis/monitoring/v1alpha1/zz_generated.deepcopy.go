@@ -23,10 +23,33 @@ package v1alpha1
 import (
 	monitoringv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertRuleTest) DeepCopyInto(out *AlertRuleTest) {
+	*out = *in
+	if in.ExpAlerts != nil {
+		in, out := &in.ExpAlerts, &out.ExpAlerts
+		*out = make([]ExpectedAlert, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertRuleTest.
+func (in *AlertRuleTest) DeepCopy() *AlertRuleTest {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertRuleTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertmanagerConfig) DeepCopyInto(out *AlertmanagerConfig) {
 	*out = *in
@@ -48,258 +71,211 @@ func (in *AlertmanagerConfig) DeepCopy() *AlertmanagerConfig {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Condition) DeepCopyInto(out *Condition) {
+func (in *AutoscalePolicy) DeepCopyInto(out *AutoscalePolicy) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.MinAllowed != nil {
+		in, out := &in.MinAllowed, &out.MinAllowed
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.MaxAllowed != nil {
+		in, out := &in.MaxAllowed, &out.MaxAllowed
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
-func (in *Condition) DeepCopy() *Condition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalePolicy.
+func (in *AutoscalePolicy) DeepCopy() *AutoscalePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(Condition)
+	out := new(AutoscalePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MonitoringStack) DeepCopyInto(out *MonitoringStack) {
+func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStack.
-func (in *MonitoringStack) DeepCopy() *MonitoringStack {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
 	if in == nil {
 		return nil
 	}
-	out := new(MonitoringStack)
+	out := new(Condition)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *MonitoringStack) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MonitoringStackList) DeepCopyInto(out *MonitoringStackList) {
+func (in *ExpectedAlert) DeepCopyInto(out *ExpectedAlert) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]MonitoringStack, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.ExpLabels != nil {
+		in, out := &in.ExpLabels, &out.ExpLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExpAnnotations != nil {
+		in, out := &in.ExpAnnotations, &out.ExpAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackList.
-func (in *MonitoringStackList) DeepCopy() *MonitoringStackList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpectedAlert.
+func (in *ExpectedAlert) DeepCopy() *ExpectedAlert {
 	if in == nil {
 		return nil
 	}
-	out := new(MonitoringStackList)
+	out := new(ExpectedAlert)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *MonitoringStackList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExpectedSample) DeepCopyInto(out *ExpectedSample) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpectedSample.
+func (in *ExpectedSample) DeepCopy() *ExpectedSample {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(ExpectedSample)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MonitoringStackSpec) DeepCopyInto(out *MonitoringStackSpec) {
+func (in *FederationConfig) DeepCopyInto(out *FederationConfig) {
 	*out = *in
-	if in.ResourceSelector != nil {
-		in, out := &in.ResourceSelector, &out.ResourceSelector
-		*out = new(v1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
+	in.StackSelector.DeepCopyInto(&out.StackSelector)
 	if in.NamespaceSelector != nil {
 		in, out := &in.NamespaceSelector, &out.NamespaceSelector
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
-	in.Resources.DeepCopyInto(&out.Resources)
-	if in.Tolerations != nil {
-		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]corev1.Toleration, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Match != nil {
+		in, out := &in.Match, &out.Match
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	if in.NodeSelector != nil {
-		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(monitoringv1.Duration)
+		**out = **in
 	}
-	if in.PrometheusConfig != nil {
-		in, out := &in.PrometheusConfig, &out.PrometheusConfig
-		*out = new(PrometheusConfig)
-		(*in).DeepCopyInto(*out)
+	if in.HonorLabels != nil {
+		in, out := &in.HonorLabels, &out.HonorLabels
+		*out = new(bool)
+		**out = **in
 	}
-	in.AlertmanagerConfig.DeepCopyInto(&out.AlertmanagerConfig)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSpec.
-func (in *MonitoringStackSpec) DeepCopy() *MonitoringStackSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationConfig.
+func (in *FederationConfig) DeepCopy() *FederationConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(MonitoringStackSpec)
+	out := new(FederationConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MonitoringStackStatus) DeepCopyInto(out *MonitoringStackStatus) {
+func (in *GRPCTLSConfig) DeepCopyInto(out *GRPCTLSConfig) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackStatus.
-func (in *MonitoringStackStatus) DeepCopy() *MonitoringStackStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCTLSConfig.
+func (in *GRPCTLSConfig) DeepCopy() *GRPCTLSConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(MonitoringStackStatus)
+	out := new(GRPCTLSConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NamespaceSelector) DeepCopyInto(out *NamespaceSelector) {
+func (in *HibernationConfig) DeepCopyInto(out *HibernationConfig) {
 	*out = *in
-	if in.MatchNames != nil {
-		in, out := &in.MatchNames, &out.MatchNames
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceSelector.
-func (in *NamespaceSelector) DeepCopy() *NamespaceSelector {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationConfig.
+func (in *HibernationConfig) DeepCopy() *HibernationConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(NamespaceSelector)
+	out := new(HibernationConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
+func (in *InputSeries) DeepCopyInto(out *InputSeries) {
 	*out = *in
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int32)
-		**out = **in
-	}
-	if in.RemoteWrite != nil {
-		in, out := &in.RemoteWrite, &out.RemoteWrite
-		*out = make([]monitoringv1.RemoteWriteSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.PersistentVolumeClaim != nil {
-		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
-		*out = new(corev1.PersistentVolumeClaimSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ExternalLabels != nil {
-		in, out := &in.ExternalLabels, &out.ExternalLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.EnableOtlpHttpReceiver != nil {
-		in, out := &in.EnableOtlpHttpReceiver, &out.EnableOtlpHttpReceiver
-		*out = new(bool)
-		**out = **in
-	}
-	if in.ScrapeInterval != nil {
-		in, out := &in.ScrapeInterval, &out.ScrapeInterval
-		*out = new(monitoringv1.Duration)
-		**out = **in
-	}
-	if in.WebTLSConfig != nil {
-		in, out := &in.WebTLSConfig, &out.WebTLSConfig
-		*out = new(WebTLSConfig)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusConfig.
-func (in *PrometheusConfig) DeepCopy() *PrometheusConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InputSeries.
+func (in *InputSeries) DeepCopy() *InputSeries {
 	if in == nil {
 		return nil
 	}
-	out := new(PrometheusConfig)
+	out := new(InputSeries)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
+func (in *InsightsConfig) DeepCopyInto(out *InsightsConfig) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeySelector.
-func (in *SecretKeySelector) DeepCopy() *SecretKeySelector {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InsightsConfig.
+func (in *InsightsConfig) DeepCopy() *InsightsConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretKeySelector)
+	out := new(InsightsConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ThanosQuerier) DeepCopyInto(out *ThanosQuerier) {
+func (in *MonitoringStack) DeepCopyInto(out *MonitoringStack) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerier.
-func (in *ThanosQuerier) DeepCopy() *ThanosQuerier {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStack.
+func (in *MonitoringStack) DeepCopy() *MonitoringStack {
 	if in == nil {
 		return nil
 	}
-	out := new(ThanosQuerier)
+	out := new(MonitoringStack)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ThanosQuerier) DeepCopyObject() runtime.Object {
+func (in *MonitoringStack) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -307,31 +283,65 @@ func (in *ThanosQuerier) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ThanosQuerierList) DeepCopyInto(out *ThanosQuerierList) {
+func (in *MonitoringStackInsights) DeepCopyInto(out *MonitoringStackInsights) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.TopMetrics != nil {
+		in, out := &in.TopMetrics, &out.TopMetrics
+		*out = make([]SeriesCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.TopLabelPairs != nil {
+		in, out := &in.TopLabelPairs, &out.TopLabelPairs
+		*out = make([]SeriesCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]ScrapePoolTargets, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackInsights.
+func (in *MonitoringStackInsights) DeepCopy() *MonitoringStackInsights {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackInsights)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackList) DeepCopyInto(out *MonitoringStackList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ThanosQuerier, len(*in))
+		*out = make([]MonitoringStack, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierList.
-func (in *ThanosQuerierList) DeepCopy() *ThanosQuerierList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackList.
+func (in *MonitoringStackList) DeepCopy() *MonitoringStackList {
 	if in == nil {
 		return nil
 	}
-	out := new(ThanosQuerierList)
+	out := new(MonitoringStackList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ThanosQuerierList) DeepCopyObject() runtime.Object {
+func (in *MonitoringStackList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -339,38 +349,1640 @@ func (in *ThanosQuerierList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ThanosQuerierSpec) DeepCopyInto(out *ThanosQuerierSpec) {
+func (in *MonitoringStackPolicy) DeepCopyInto(out *MonitoringStackPolicy) {
 	*out = *in
-	in.Selector.DeepCopyInto(&out.Selector)
-	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
-	if in.ReplicaLabels != nil {
-		in, out := &in.ReplicaLabels, &out.ReplicaLabels
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierSpec.
-func (in *ThanosQuerierSpec) DeepCopy() *ThanosQuerierSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackPolicy.
+func (in *MonitoringStackPolicy) DeepCopy() *MonitoringStackPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ThanosQuerierSpec)
+	out := new(MonitoringStackPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ThanosQuerierStatus) DeepCopyInto(out *ThanosQuerierStatus) {
+func (in *MonitoringStackPolicyList) DeepCopyInto(out *MonitoringStackPolicyList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MonitoringStackPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierStatus.
-func (in *ThanosQuerierStatus) DeepCopy() *ThanosQuerierStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackPolicyList.
+func (in *MonitoringStackPolicyList) DeepCopy() *MonitoringStackPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(ThanosQuerierStatus)
+	out := new(MonitoringStackPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackPolicySpec) DeepCopyInto(out *MonitoringStackPolicySpec) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.StackLabels != nil {
+		in, out := &in.StackLabels, &out.StackLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourceSelector != nil {
+		in, out := &in.ResourceSelector, &out.ResourceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxStacks != nil {
+		in, out := &in.MaxStacks, &out.MaxStacks
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackPolicySpec.
+func (in *MonitoringStackPolicySpec) DeepCopy() *MonitoringStackPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackPolicyStatus) DeepCopyInto(out *MonitoringStackPolicyStatus) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedNamespaces != nil {
+		in, out := &in.SkippedNamespaces, &out.SkippedNamespaces
+		*out = make([]SkippedNamespace, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackPolicyStatus.
+func (in *MonitoringStackPolicyStatus) DeepCopy() *MonitoringStackPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackSnapshot) DeepCopyInto(out *MonitoringStackSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSnapshot.
+func (in *MonitoringStackSnapshot) DeepCopy() *MonitoringStackSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackSnapshotList) DeepCopyInto(out *MonitoringStackSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MonitoringStackSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSnapshotList.
+func (in *MonitoringStackSnapshotList) DeepCopy() *MonitoringStackSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackSnapshotSpec) DeepCopyInto(out *MonitoringStackSnapshotSpec) {
+	*out = *in
+	in.Destination.DeepCopyInto(&out.Destination)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSnapshotSpec.
+func (in *MonitoringStackSnapshotSpec) DeepCopy() *MonitoringStackSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackSnapshotStatus) DeepCopyInto(out *MonitoringStackSnapshotStatus) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SnapshotTime != nil {
+		in, out := &in.SnapshotTime, &out.SnapshotTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSnapshotStatus.
+func (in *MonitoringStackSnapshotStatus) DeepCopy() *MonitoringStackSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackSpec) DeepCopyInto(out *MonitoringStackSpec) {
+	*out = *in
+	if in.ResourceSelector != nil {
+		in, out := &in.ResourceSelector, &out.ResourceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PrometheusConfig != nil {
+		in, out := &in.PrometheusConfig, &out.PrometheusConfig
+		*out = new(PrometheusConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	in.AlertmanagerConfig.DeepCopyInto(&out.AlertmanagerConfig)
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(HibernationConfig)
+		**out = **in
+	}
+	if in.Insights != nil {
+		in, out := &in.Insights, &out.Insights
+		*out = new(InsightsConfig)
+		**out = **in
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = new(RecommendationsConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Probing != nil {
+		in, out := &in.Probing, &out.Probing
+		*out = new(ProbingConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(FederationConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackSpec.
+func (in *MonitoringStackSpec) DeepCopy() *MonitoringStackSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackStatus) DeepCopyInto(out *MonitoringStackStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveSpec != nil {
+		in, out := &in.EffectiveSpec, &out.EffectiveSpec
+		*out = new(MonitoringStackSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Insights != nil {
+		in, out := &in.Insights, &out.Insights
+		*out = new(MonitoringStackInsights)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = new(ResourceRecommendations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ResourceValidation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackStatus.
+func (in *MonitoringStackStatus) DeepCopy() *MonitoringStackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackTemplate) DeepCopyInto(out *MonitoringStackTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackTemplate.
+func (in *MonitoringStackTemplate) DeepCopy() *MonitoringStackTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackTemplateList) DeepCopyInto(out *MonitoringStackTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MonitoringStackTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackTemplateList.
+func (in *MonitoringStackTemplateList) DeepCopy() *MonitoringStackTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringStackTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringStackTemplateSpec) DeepCopyInto(out *MonitoringStackTemplateSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]monitoringv1.RemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExternalLabels != nil {
+		in, out := &in.ExternalLabels, &out.ExternalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringStackTemplateSpec.
+func (in *MonitoringStackTemplateSpec) DeepCopy() *MonitoringStackTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringStackTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceSelector) DeepCopyInto(out *NamespaceSelector) {
+	*out = *in
+	if in.MatchNames != nil {
+		in, out := &in.MatchNames, &out.MatchNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceSelector.
+func (in *NamespaceSelector) DeepCopy() *NamespaceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeModule) DeepCopyInto(out *ProbeModule) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeModule.
+func (in *ProbeModule) DeepCopy() *ProbeModule {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbingConfig) DeepCopyInto(out *ProbingConfig) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make(map[string]ProbeModule, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbingConfig.
+func (in *ProbingConfig) DeepCopy() *ProbingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromQLExprTest) DeepCopyInto(out *PromQLExprTest) {
+	*out = *in
+	if in.ExpSamples != nil {
+		in, out := &in.ExpSamples, &out.ExpSamples
+		*out = make([]ExpectedSample, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromQLExprTest.
+func (in *PromQLExprTest) DeepCopy() *PromQLExprTest {
+	if in == nil {
+		return nil
+	}
+	out := new(PromQLExprTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusConfig) DeepCopyInto(out *PrometheusConfig) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RemoteWrite != nil {
+		in, out := &in.RemoteWrite, &out.RemoteWrite
+		*out = make([]monitoringv1.RemoteWriteSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalLabels != nil {
+		in, out := &in.ExternalLabels, &out.ExternalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.EnableOtlpHttpReceiver != nil {
+		in, out := &in.EnableOtlpHttpReceiver, &out.EnableOtlpHttpReceiver
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ScrapeInterval != nil {
+		in, out := &in.ScrapeInterval, &out.ScrapeInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.ScrapeTimeout != nil {
+		in, out := &in.ScrapeTimeout, &out.ScrapeTimeout
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.ScrapeProtocols != nil {
+		in, out := &in.ScrapeProtocols, &out.ScrapeProtocols
+		*out = make([]monitoringv1.ScrapeProtocol, len(*in))
+		copy(*out, *in)
+	}
+	if in.EvaluationInterval != nil {
+		in, out := &in.EvaluationInterval, &out.EvaluationInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.EnableNativeHistograms != nil {
+		in, out := &in.EnableNativeHistograms, &out.EnableNativeHistograms
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ScrapeClassicHistograms != nil {
+		in, out := &in.ScrapeClassicHistograms, &out.ScrapeClassicHistograms
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NativeHistogramBucketLimit != nil {
+		in, out := &in.NativeHistogramBucketLimit, &out.NativeHistogramBucketLimit
+		*out = new(uint64)
+		**out = **in
+	}
+	if in.NativeHistogramMinBucketFactor != nil {
+		in, out := &in.NativeHistogramMinBucketFactor, &out.NativeHistogramMinBucketFactor
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.WebTLSConfig != nil {
+		in, out := &in.WebTLSConfig, &out.WebTLSConfig
+		*out = new(WebTLSConfig)
+		**out = **in
+	}
+	if in.ThanosSidecarGRPCTLS != nil {
+		in, out := &in.ThanosSidecarGRPCTLS, &out.ThanosSidecarGRPCTLS
+		*out = new(GRPCTLSConfig)
+		**out = **in
+	}
+	if in.TracingConfig != nil {
+		in, out := &in.TracingConfig, &out.TracingConfig
+		*out = new(monitoringv1.PrometheusTracingConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exemplars != nil {
+		in, out := &in.Exemplars, &out.Exemplars
+		*out = new(monitoringv1.Exemplars)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Query != nil {
+		in, out := &in.Query, &out.Query
+		*out = new(QueryConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusConfig.
+func (in *PrometheusConfig) DeepCopy() *PrometheusConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRuleTest) DeepCopyInto(out *PrometheusRuleTest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRuleTest.
+func (in *PrometheusRuleTest) DeepCopy() *PrometheusRuleTest {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRuleTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrometheusRuleTest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRuleTestList) DeepCopyInto(out *PrometheusRuleTestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PrometheusRuleTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRuleTestList.
+func (in *PrometheusRuleTestList) DeepCopy() *PrometheusRuleTestList {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRuleTestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PrometheusRuleTestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRuleTestSpec) DeepCopyInto(out *PrometheusRuleTestSpec) {
+	*out = *in
+	out.RuleRef = in.RuleRef
+	if in.ExternalLabels != nil {
+		in, out := &in.ExternalLabels, &out.ExternalLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InputSeries != nil {
+		in, out := &in.InputSeries, &out.InputSeries
+		*out = make([]InputSeries, len(*in))
+		copy(*out, *in)
+	}
+	if in.AlertRuleTests != nil {
+		in, out := &in.AlertRuleTests, &out.AlertRuleTests
+		*out = make([]AlertRuleTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PromQLExprTests != nil {
+		in, out := &in.PromQLExprTests, &out.PromQLExprTests
+		*out = make([]PromQLExprTest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRuleTestSpec.
+func (in *PrometheusRuleTestSpec) DeepCopy() *PrometheusRuleTestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRuleTestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRuleTestStatus) DeepCopyInto(out *PrometheusRuleTestStatus) {
+	*out = *in
+	if in.LastEvaluationTime != nil {
+		in, out := &in.LastEvaluationTime, &out.LastEvaluationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRuleTestStatus.
+func (in *PrometheusRuleTestStatus) DeepCopy() *PrometheusRuleTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRuleTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueryConfig) DeepCopyInto(out *QueryConfig) {
+	*out = *in
+	if in.MaxConcurrency != nil {
+		in, out := &in.MaxConcurrency, &out.MaxConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.MaxSamples != nil {
+		in, out := &in.MaxSamples, &out.MaxSamples
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LookbackDelta != nil {
+		in, out := &in.LookbackDelta, &out.LookbackDelta
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueryConfig.
+func (in *QueryConfig) DeepCopy() *QueryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(QueryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecommendationsConfig) DeepCopyInto(out *RecommendationsConfig) {
+	*out = *in
+	if in.Autoscale != nil {
+		in, out := &in.Autoscale, &out.Autoscale
+		*out = new(AutoscalePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecommendationsConfig.
+func (in *RecommendationsConfig) DeepCopy() *RecommendationsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RecommendationsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RejectedResource) DeepCopyInto(out *RejectedResource) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RejectedResource.
+func (in *RejectedResource) DeepCopy() *RejectedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(RejectedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRecommendations) DeepCopyInto(out *ResourceRecommendations) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AppliedResources != nil {
+		in, out := &in.AppliedResources, &out.AppliedResources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppliedStorage != nil {
+		in, out := &in.AppliedStorage, &out.AppliedStorage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRecommendations.
+func (in *ResourceRecommendations) DeepCopy() *ResourceRecommendations {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRecommendations)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceValidation) DeepCopyInto(out *ResourceValidation) {
+	*out = *in
+	if in.Rejected != nil {
+		in, out := &in.Rejected, &out.Rejected
+		*out = make([]RejectedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceValidation.
+func (in *ResourceValidation) DeepCopy() *ResourceValidation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceValidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleReference) DeepCopyInto(out *RuleReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleReference.
+func (in *RuleReference) DeepCopy() *RuleReference {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLIRatio) DeepCopyInto(out *SLIRatio) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLIRatio.
+func (in *SLIRatio) DeepCopy() *SLIRatio {
+	if in == nil {
+		return nil
+	}
+	out := new(SLIRatio)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLO) DeepCopyInto(out *SLO) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLO.
+func (in *SLO) DeepCopy() *SLO {
+	if in == nil {
+		return nil
+	}
+	out := new(SLO)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SLO) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOAlerting) DeepCopyInto(out *SLOAlerting) {
+	*out = *in
+	if in.PageLabels != nil {
+		in, out := &in.PageLabels, &out.PageLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TicketLabels != nil {
+		in, out := &in.TicketLabels, &out.TicketLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOAlerting.
+func (in *SLOAlerting) DeepCopy() *SLOAlerting {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOAlerting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOErrorBudget) DeepCopyInto(out *SLOErrorBudget) {
+	*out = *in
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOErrorBudget.
+func (in *SLOErrorBudget) DeepCopy() *SLOErrorBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOErrorBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOList) DeepCopyInto(out *SLOList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SLO, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOList.
+func (in *SLOList) DeepCopy() *SLOList {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SLOList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOSpec) DeepCopyInto(out *SLOSpec) {
+	*out = *in
+	out.Indicator = in.Indicator
+	if in.Alerting != nil {
+		in, out := &in.Alerting, &out.Alerting
+		*out = new(SLOAlerting)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOSpec.
+func (in *SLOSpec) DeepCopy() *SLOSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOStatus) DeepCopyInto(out *SLOStatus) {
+	*out = *in
+	if in.ErrorBudget != nil {
+		in, out := &in.ErrorBudget, &out.ErrorBudget
+		*out = new(SLOErrorBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOStatus.
+func (in *SLOStatus) DeepCopy() *SLOStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScrapePoolTargets) DeepCopyInto(out *ScrapePoolTargets) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapePoolTargets.
+func (in *ScrapePoolTargets) DeepCopy() *ScrapePoolTargets {
+	if in == nil {
+		return nil
+	}
+	out := new(ScrapePoolTargets)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeySelector.
+func (in *SecretKeySelector) DeepCopy() *SecretKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeriesCount) DeepCopyInto(out *SeriesCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeriesCount.
+func (in *SeriesCount) DeepCopy() *SeriesCount {
+	if in == nil {
+		return nil
+	}
+	out := new(SeriesCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkippedNamespace) DeepCopyInto(out *SkippedNamespace) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkippedNamespace.
+func (in *SkippedNamespace) DeepCopy() *SkippedNamespace {
+	if in == nil {
+		return nil
+	}
+	out := new(SkippedNamespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotDestination) DeepCopyInto(out *SnapshotDestination) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(SnapshotPersistentVolumeClaim)
+		**out = **in
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(SnapshotObjectStorage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotDestination.
+func (in *SnapshotDestination) DeepCopy() *SnapshotDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotObjectStorage) DeepCopyInto(out *SnapshotObjectStorage) {
+	*out = *in
+	out.Config = in.Config
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotObjectStorage.
+func (in *SnapshotObjectStorage) DeepCopy() *SnapshotObjectStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotObjectStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotPersistentVolumeClaim) DeepCopyInto(out *SnapshotPersistentVolumeClaim) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotPersistentVolumeClaim.
+func (in *SnapshotPersistentVolumeClaim) DeepCopy() *SnapshotPersistentVolumeClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotPersistentVolumeClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosEndpoint) DeepCopyInto(out *ThanosEndpoint) {
+	*out = *in
+	if in.ServiceSelector != nil {
+		in, out := &in.ServiceSelector, &out.ServiceSelector
+		*out = new(ThanosServiceSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ThanosEndpointTLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosEndpoint.
+func (in *ThanosEndpoint) DeepCopy() *ThanosEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosEndpointTLSConfig) DeepCopyInto(out *ThanosEndpointTLSConfig) {
+	*out = *in
+	if in.CA != nil {
+		in, out := &in.CA, &out.CA
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
+	if in.Cert != nil {
+		in, out := &in.Cert, &out.Cert
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
+	if in.Key != nil {
+		in, out := &in.Key, &out.Key
+		*out = new(SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosEndpointTLSConfig.
+func (in *ThanosEndpointTLSConfig) DeepCopy() *ThanosEndpointTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosEndpointTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerier) DeepCopyInto(out *ThanosQuerier) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerier.
+func (in *ThanosQuerier) DeepCopy() *ThanosQuerier {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ThanosQuerier) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierGRPCTLSConfig) DeepCopyInto(out *ThanosQuerierGRPCTLSConfig) {
+	*out = *in
+	out.GRPCTLSConfig = in.GRPCTLSConfig
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierGRPCTLSConfig.
+func (in *ThanosQuerierGRPCTLSConfig) DeepCopy() *ThanosQuerierGRPCTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierGRPCTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierList) DeepCopyInto(out *ThanosQuerierList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ThanosQuerier, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierList.
+func (in *ThanosQuerierList) DeepCopy() *ThanosQuerierList {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ThanosQuerierList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierQueryConfig) DeepCopyInto(out *ThanosQuerierQueryConfig) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.MaxConcurrent != nil {
+		in, out := &in.MaxConcurrent, &out.MaxConcurrent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LookbackDelta != nil {
+		in, out := &in.LookbackDelta, &out.LookbackDelta
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.DefaultEvaluationInterval != nil {
+		in, out := &in.DefaultEvaluationInterval, &out.DefaultEvaluationInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.StoreResponseTimeout != nil {
+		in, out := &in.StoreResponseTimeout, &out.StoreResponseTimeout
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierQueryConfig.
+func (in *ThanosQuerierQueryConfig) DeepCopy() *ThanosQuerierQueryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierQueryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierSpec) DeepCopyInto(out *ThanosQuerierSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.ReplicaLabels != nil {
+		in, out := &in.ReplicaLabels, &out.ReplicaLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deduplication != nil {
+		in, out := &in.Deduplication, &out.Deduplication
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Query != nil {
+		in, out := &in.Query, &out.Query
+		*out = new(ThanosQuerierQueryConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GRPCTLS != nil {
+		in, out := &in.GRPCTLS, &out.GRPCTLS
+		*out = new(ThanosQuerierGRPCTLSConfig)
+		**out = **in
+	}
+	if in.AdditionalEndpoints != nil {
+		in, out := &in.AdditionalEndpoints, &out.AdditionalEndpoints
+		*out = make([]ThanosEndpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tenancy != nil {
+		in, out := &in.Tenancy, &out.Tenancy
+		*out = new(ThanosQuerierTenancyConfig)
+		**out = **in
+	}
+	if in.QueryFrontend != nil {
+		in, out := &in.QueryFrontend, &out.QueryFrontend
+		*out = new(ThanosQueryFrontendConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierSpec.
+func (in *ThanosQuerierSpec) DeepCopy() *ThanosQuerierSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierStatus) DeepCopyInto(out *ThanosQuerierStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MonitoringStacks != nil {
+		in, out := &in.MonitoringStacks, &out.MonitoringStacks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedMonitoringStacks != nil {
+		in, out := &in.ExcludedMonitoringStacks, &out.ExcludedMonitoringStacks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stores != nil {
+		in, out := &in.Stores, &out.Stores
+		*out = make([]ThanosStoreStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierStatus.
+func (in *ThanosQuerierStatus) DeepCopy() *ThanosQuerierStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQuerierTenancyConfig) DeepCopyInto(out *ThanosQuerierTenancyConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQuerierTenancyConfig.
+func (in *ThanosQuerierTenancyConfig) DeepCopy() *ThanosQuerierTenancyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQuerierTenancyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQueryFrontendCache) DeepCopyInto(out *ThanosQueryFrontendCache) {
+	*out = *in
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQueryFrontendCache.
+func (in *ThanosQueryFrontendCache) DeepCopy() *ThanosQueryFrontendCache {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQueryFrontendCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosQueryFrontendConfig) DeepCopyInto(out *ThanosQueryFrontendConfig) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.SplitInterval != nil {
+		in, out := &in.SplitInterval, &out.SplitInterval
+		*out = new(monitoringv1.Duration)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(ThanosQueryFrontendCache)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosQueryFrontendConfig.
+func (in *ThanosQueryFrontendConfig) DeepCopy() *ThanosQueryFrontendConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosQueryFrontendConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosServiceSelector) DeepCopyInto(out *ThanosServiceSelector) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosServiceSelector.
+func (in *ThanosServiceSelector) DeepCopy() *ThanosServiceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosServiceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThanosStoreStatus) DeepCopyInto(out *ThanosStoreStatus) {
+	*out = *in
+	if in.LastCheck != nil {
+		in, out := &in.LastCheck, &out.LastCheck
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThanosStoreStatus.
+func (in *ThanosStoreStatus) DeepCopy() *ThanosStoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ThanosStoreStatus)
 	in.DeepCopyInto(out)
 	return out
 }
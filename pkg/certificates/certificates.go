@@ -0,0 +1,316 @@
+// Package certificates manages the certificate authorities of the operator
+// and the certificates they issue to secure the gRPC connections between the
+// Thanos components.
+//
+// The serving certificates (e.g. of the Thanos sidecars) and the client
+// certificates (of the Thanos queriers) are issued by two distinct certificate
+// authorities: the servers only trust the client certificate authority and
+// the clients only the serving one. Each serving certificate is only valid
+// for the names of its own Service so that a client verifying the name of the
+// server it connects to can't be served by another server.
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// CertificateKey is the key of the certificate in the generated secrets.
+	CertificateKey = corev1.TLSCertKey
+	// PrivateKeyKey is the key of the private key in the generated secrets.
+	PrivateKeyKey = corev1.TLSPrivateKeyKey
+	// CAKey is the key of the certificate of the certificate authority in
+	// the generated secrets.
+	CAKey = "ca.crt"
+
+	// ClientNamespaceLabel labels the namespaces in which the operator
+	// issues client certificates. Only cluster administrators can usually
+	// label namespaces: the label is an allow-list of the namespaces whose
+	// workloads are trusted by the servers.
+	ClientNamespaceLabel = "monitoring.rhobs/grpc-client-certificates"
+
+	caSecretName       = "observability-operator-grpc-ca"
+	clientCASecretName = "observability-operator-grpc-client-ca"
+	caValidity         = 10 * 365 * 24 * time.Hour
+	certValidity       = 365 * 24 * time.Hour
+	// Certificates are renewed when they expire within renewBefore.
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// CA is a certificate authority issuing certificates.
+type CA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *ecdsa.PrivateKey
+}
+
+// Request describes a certificate to issue.
+type Request struct {
+	CommonName string
+	DNSNames   []string
+	Usage      x509.ExtKeyUsage
+	// Certificate authority verifying the certificates of the peers, stored
+	// along with the certificate. Defaults to the issuing certificate
+	// authority.
+	PeerCA *CA
+}
+
+func (req Request) peerCA(issuer *CA) *CA {
+	if req.PeerCA != nil {
+		return req.PeerCA
+	}
+	return issuer
+}
+
+// GetCA returns the certificate authority issuing the serving certificates,
+// stored in the given namespace (the namespace of the operator). The
+// certificate authority is generated the first time. The secret of the
+// certificate authority isn't cached by the operator: it is read with the API
+// reader r.
+func GetCA(ctx context.Context, c client.Client, r client.Reader, namespace string) (*CA, error) {
+	return getCA(ctx, c, r, namespace, caSecretName)
+}
+
+// GetClientCA returns the certificate authority issuing the client
+// certificates, stored in the given namespace like the serving one.
+func GetClientCA(ctx context.Context, c client.Client, r client.Reader, namespace string) (*CA, error) {
+	return getCA(ctx, c, r, namespace, clientCASecretName)
+}
+
+func getCA(ctx context.Context, c client.Client, r client.Reader, namespace string, name string) (*CA, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret)
+	if err == nil {
+		return parseCA(secret)
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	ca, err := newCA(name)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := encodeKey(ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			CertificateKey: ca.certPEM,
+			PrivateKeyKey:  keyPEM,
+		},
+	}
+	if err := c.Create(ctx, secret); err != nil {
+		// Another controller may have created the certificate authority in
+		// the meantime: retrying reads it back.
+		return nil, fmt.Errorf("failed to create the certificate authority: %w", err)
+	}
+
+	return ca, nil
+}
+
+// NewSecret returns a secret holding a certificate signed by the certificate
+// authority for the request, along with its private key and the certificate
+// of the certificate authority verifying the peers. The certificate of the
+// existing secret is kept unless it is about to expire, doesn't match the
+// request or isn't signed by the certificate authority.
+func (ca *CA) NewSecret(ctx context.Context, c client.Client, name string, namespace string, req Request) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+	}
+
+	existing := &corev1.Secret{}
+	err := c.Get(ctx, client.ObjectKeyFromObject(secret), existing)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if err == nil && ca.valid(existing, req) {
+		secret.Data = existing.Data
+		return secret, nil
+	}
+
+	certPEM, keyPEM, err := ca.issue(req)
+	if err != nil {
+		return nil, err
+	}
+	secret.Data = map[string][]byte{
+		CertificateKey: certPEM,
+		PrivateKeyKey:  keyPEM,
+		CAKey:          req.peerCA(ca).certPEM,
+	}
+	return secret, nil
+}
+
+// valid returns true if the secret holds a certificate issued by the
+// certificate authority for the request which doesn't expire soon.
+func (ca *CA) valid(secret *corev1.Secret, req Request) bool {
+	if !slices.Equal(secret.Data[CAKey], req.peerCA(ca).certPEM) || len(secret.Data[PrivateKeyKey]) == 0 {
+		return false
+	}
+
+	cert, err := parseCertificate(secret.Data[CertificateKey])
+	if err != nil {
+		return false
+	}
+
+	if cert.Subject.CommonName != req.CommonName || !slices.Equal(cert.DNSNames, req.DNSNames) {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		KeyUsages:   []x509.ExtKeyUsage{req.Usage},
+		CurrentTime: time.Now().Add(renewBefore),
+	})
+	return err == nil
+}
+
+func (ca *CA) issue(req Request) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.CommonName},
+		DNSNames:     req.DNSNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{req.Usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue the certificate of %q: %w", req.CommonName, err)
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+func newCA(commonName string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the certificate authority: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:     key,
+	}, nil
+}
+
+func parseCA(secret *corev1.Secret) (*CA, error) {
+	cert, err := parseCertificate(secret.Data[CertificateKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate authority in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	block, _ := pem.Decode(secret.Data[PrivateKeyKey])
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate authority in secret %s/%s: no private key", secret.Namespace, secret.Name)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate authority in secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return &CA{
+		cert:    cert,
+		certPEM: secret.Data[CertificateKey],
+		key:     key,
+	}, nil
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
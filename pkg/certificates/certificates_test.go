@@ -0,0 +1,119 @@
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetCA(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+
+	ca, err := GetCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+	assert.Assert(t, ca.cert.IsCA)
+
+	// The certificate authority is read back from the secret.
+	got, err := GetCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got.certPEM, ca.certPEM)
+	assert.Assert(t, got.key.Equal(ca.key))
+
+	// The client certificates are issued by another certificate authority.
+	clientCA, err := GetClientCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+	assert.Assert(t, clientCA.cert.IsCA)
+	assert.Assert(t, string(clientCA.certPEM) != string(ca.certPEM))
+	assert.Equal(t, clientCA.cert.Subject.CommonName, "observability-operator-grpc-client-ca")
+}
+
+func TestNewSecret(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	ca, err := GetCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+
+	req := Request{
+		CommonName: "ms-thanos-sidecar",
+		DNSNames:   []string{"ms-thanos-sidecar", "ms-thanos-sidecar.ns.svc"},
+		Usage:      x509.ExtKeyUsageServerAuth,
+	}
+
+	secret, err := ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.Equal(t, secret.Type, corev1.SecretTypeTLS)
+	assert.DeepEqual(t, secret.Data[CAKey], ca.certPEM)
+
+	pair, err := tls.X509KeyPair(secret.Data[CertificateKey], secret.Data[PrivateKeyKey])
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	assert.NilError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(secret.Data[CAKey])
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "ms-thanos-sidecar.ns.svc"})
+	assert.NilError(t, err)
+
+	// The existing certificate is kept while it matches the request.
+	assert.NilError(t, c.Create(context.Background(), secret))
+	got, err := ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got.Data, secret.Data)
+
+	req.DNSNames = []string{"ms-thanos-sidecar.ns.svc"}
+	got, err = ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.Assert(t, string(got.Data[CertificateKey]) != string(secret.Data[CertificateKey]))
+
+	// Certificates signed by another certificate authority are reissued.
+	other := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms-grpc-tls", Namespace: "ns"},
+		Data:       secret.Data,
+	}).Build()
+	otherCA, err := GetCA(context.Background(), other, other, "operator")
+	assert.NilError(t, err)
+	got, err = otherCA.NewSecret(context.Background(), other, "ms-grpc-tls", "ns", Request{
+		CommonName: "ms-thanos-sidecar",
+		DNSNames:   []string{"ms-thanos-sidecar", "ms-thanos-sidecar.ns.svc"},
+		Usage:      x509.ExtKeyUsageServerAuth,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got.Data[CAKey], otherCA.certPEM)
+}
+
+func TestNewSecretPeerCA(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	ca, err := GetCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+	clientCA, err := GetClientCA(context.Background(), c, c, "operator")
+	assert.NilError(t, err)
+
+	// The servers only trust the client certificate authority.
+	req := Request{
+		CommonName: "ms-thanos-sidecar",
+		DNSNames:   []string{"ms-thanos-sidecar.ns.svc"},
+		Usage:      x509.ExtKeyUsageServerAuth,
+		PeerCA:     clientCA,
+	}
+	secret, err := ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, secret.Data[CAKey], clientCA.certPEM)
+
+	// The certificates of the existing secrets trusting the issuing
+	// certificate authority are reissued.
+	assert.NilError(t, c.Create(context.Background(), secret))
+	got, err := ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got.Data, secret.Data)
+
+	req.PeerCA = nil
+	got, err = ca.NewSecret(context.Background(), c, "ms-grpc-tls", "ns", req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got.Data[CAKey], ca.certPEM)
+	assert.Assert(t, string(got.Data[CertificateKey]) != string(secret.Data[CertificateKey]))
+}
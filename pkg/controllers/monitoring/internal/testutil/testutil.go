@@ -0,0 +1,113 @@
+// Package testutil provides the fixtures shared by the unit tests of the
+// monitoring controllers: a fake client knowing all the types managed by the
+// operator, a fixed clock and a fake Prometheus HTTP API.
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// Now is the time returned by the clock of the controllers under test.
+var Now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// Clock returns Now.
+func Clock() time.Time {
+	return Now
+}
+
+// NewScheme returns a scheme with the Kubernetes types, the types of the
+// operator and the types of the Prometheus operator.
+func NewScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(stack.AddToScheme(scheme))
+	utilruntime.Must(monv1.AddToScheme(scheme))
+	utilruntime.Must(monv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+// NewClientBuilder returns a builder of fake clients initialized with the
+// given objects. The status of the resources of the operator is a
+// subresource, like in a real cluster.
+func NewClientBuilder(objs ...client.Object) *fake.ClientBuilder {
+	return fake.NewClientBuilder().
+		WithScheme(NewScheme()).
+		WithObjects(objs...).
+		WithStatusSubresource(
+			&stack.MonitoringStack{},
+			&stack.MonitoringStackPolicy{},
+			&stack.MonitoringStackSnapshot{},
+			&stack.PrometheusRuleTest{},
+			&stack.SLO{},
+			&stack.ThanosQuerier{},
+		)
+}
+
+// ApplyPatch emulates server-side apply which isn't supported by the fake
+// client: apply patches create the object or replace it if it already
+// exists. Other patches are sent to the fake client.
+func ApplyPatch(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	if err := c.Create(ctx, obj); !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+// WithApplyPatch returns interceptor functions handling the apply patches
+// with ApplyPatch.
+func WithApplyPatch() interceptor.Funcs {
+	return interceptor.Funcs{Patch: ApplyPatch}
+}
+
+// NewPrometheusServer starts a fake Prometheus server answering the requests
+// with the handler and returns its URL. The server is stopped at the end of
+// the test.
+func NewPrometheusServer(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// WriteSuccess writes a successful response of the Prometheus HTTP API
+// with the given data.
+func WriteSuccess(w http.ResponseWriter, data any) {
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": data})
+}
+
+// Vector returns the data of an instant query result made of a single
+// sample without labels, evaluated at Now.
+func Vector(value string) map[string]any {
+	return map[string]any{
+		"resultType": "vector",
+		"result":     []map[string]any{{"metric": map[string]string{}, "value": []any{Now.Unix(), value}}},
+	}
+}
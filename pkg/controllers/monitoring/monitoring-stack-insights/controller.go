@@ -0,0 +1,192 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstackinsights
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/common/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+	defaultLimit    = 10
+
+	// requestTimeout bounds the duration of the requests to Prometheus.
+	requestTimeout = 30 * time.Second
+)
+
+type resourceManager struct {
+	client.Client
+	// apiReader reads the certificate authority of the stacks which isn't
+	// cached by the operator.
+	apiReader client.Reader
+	logger    logr.Logger
+	now       func() time.Time
+	newClient func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (statsClient, error)
+}
+
+// statsClient retrieves statistics from the Prometheus API.
+type statsClient interface {
+	TSDBStatus(ctx context.Context, limit int) (*prometheus.TSDBStatus, error)
+	ActiveTargets(ctx context.Context) ([]prometheus.ActiveTarget, error)
+}
+
+// RBAC for publishing insights
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update;patch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		logger:    ctrl.Log.WithName("monitoring-stack-insights"),
+		now:       time.Now,
+		newClient: func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (statsClient, error) {
+			return prometheus.NewClientForStack(ctx, c, ms, host)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("monitoring-stack-insights").
+		For(&stack.MonitoringStack{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("stack", req.NamespacedName)
+
+	ms := &stack.MonitoringStack{}
+	err := rm.Get(ctx, req.NamespacedName, ms)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !ms.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	cfg := ms.Spec.Insights
+	if cfg == nil || !cfg.Enabled {
+		if ms.Status.Insights == nil {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, rm.patchInsights(ctx, ms, nil)
+	}
+
+	interval := defaultInterval
+	if cfg.Interval != "" {
+		d, err := model.ParseDuration(string(cfg.Interval))
+		if err != nil {
+			// The duration is validated by the CRD schema.
+			return ctrl.Result{}, fmt.Errorf("invalid insights interval: %w", err)
+		}
+		interval = time.Duration(d)
+	}
+
+	if ms.Suspended() {
+		logger.V(3).Info("skipping suspended stack")
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+
+	now := rm.now()
+	if last := ms.Status.Insights; last != nil && last.LastUpdateTime != nil {
+		if next := last.LastUpdateTime.Add(interval); next.After(now) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	logger.Info("Collecting monitoring stack insights")
+	insights := rm.collect(ctx, ms, limit(cfg))
+	insights.LastUpdateTime = &metav1.Time{Time: now}
+	if insights.Error != "" {
+		logger.Info("Failed to collect insights", "err", insights.Error)
+	}
+
+	if err := rm.patchInsights(ctx, ms, insights); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// collect queries Prometheus for the insights of the stack. Errors are
+// reported in the returned insights, keeping the previous statistics.
+func (rm resourceManager) collect(ctx context.Context, ms *stack.MonitoringStack, limit int) *stack.MonitoringStackInsights {
+	insights := &stack.MonitoringStackInsights{}
+	if ms.Status.Insights != nil {
+		insights = ms.Status.Insights.DeepCopy()
+	}
+	insights.Error = ""
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	host := fmt.Sprintf("%s-prometheus.%s.svc", ms.Name, ms.Namespace)
+	promClient, err := rm.newClient(ctx, rm.apiReader, ms, host)
+	if err != nil {
+		insights.Error = err.Error()
+		return insights
+	}
+
+	tsdb, err := promClient.TSDBStatus(ctx, limit)
+	if err != nil {
+		insights.Error = fmt.Sprintf("failed to get TSDB status: %s", err)
+		return insights
+	}
+
+	targets, err := promClient.ActiveTargets(ctx)
+	if err != nil {
+		insights.Error = fmt.Sprintf("failed to get targets: %s", err)
+		return insights
+	}
+
+	insights.HeadSeries = int64(tsdb.HeadStats.NumSeries)
+	insights.HeadChunks = tsdb.HeadStats.ChunkCount
+	insights.TopMetrics = seriesCounts(tsdb.SeriesCountByMetricName, limit)
+	insights.TopLabelPairs = seriesCounts(tsdb.SeriesCountByLabelValuePair, limit)
+	insights.Targets = summarizeTargets(targets)
+
+	return insights
+}
+
+// patchInsights updates the insights in the status of the stack without
+// touching the fields owned by the monitoring-stack controller.
+func (rm resourceManager) patchInsights(ctx context.Context, ms *stack.MonitoringStack, insights *stack.MonitoringStackInsights) error {
+	patch := client.MergeFrom(ms.DeepCopy())
+	ms.Status.Insights = insights
+	return rm.Status().Patch(ctx, ms, patch)
+}
+
+func limit(cfg *stack.InsightsConfig) int {
+	if cfg.Limit > 0 {
+		return int(cfg.Limit)
+	}
+	return defaultLimit
+}
@@ -0,0 +1,164 @@
+package monitoringstackinsights
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+func fakePrometheus(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var data any
+		switch r.URL.Path {
+		case "/api/v1/status/tsdb":
+			assert.Equal(t, r.URL.Query().Get("limit"), "2")
+			data = map[string]any{
+				"headStats": map[string]any{"numSeries": 1500, "chunkCount": 3000},
+				"seriesCountByMetricName": []map[string]any{
+					{"name": "http_requests_total", "value": 800},
+					{"name": "up", "value": 10},
+				},
+				"seriesCountByLabelValuePair": []map[string]any{
+					{"name": "job=api", "value": 900},
+				},
+			}
+		case "/api/v1/targets":
+			data = map[string]any{
+				"activeTargets": []map[string]any{
+					{"scrapePool": "serviceMonitor/ns/api/0", "labels": map[string]string{"job": "api"}, "health": "up"},
+					{"scrapePool": "serviceMonitor/ns/api/0", "labels": map[string]string{"job": "api"}, "health": "down", "lastError": "connection refused"},
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		testutil.WriteSuccess(w, data)
+	}
+}
+
+func newTestManager(t *testing.T, handler http.HandlerFunc, objs ...client.Object) *resourceManager {
+	t.Helper()
+
+	c := testutil.NewClientBuilder(objs...).Build()
+	url := testutil.NewPrometheusServer(t, handler)
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		logger:    logr.Discard(),
+		now:       testutil.Clock,
+		newClient: func(_ context.Context, _ client.Reader, _ *stack.MonitoringStack, _ string) (statsClient, error) {
+			return prometheus.NewClient(url, nil)
+		},
+	}
+}
+
+func newStack(insights *stack.InsightsConfig) *stack.MonitoringStack {
+	return &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec:       stack.MonitoringStackSpec{Insights: insights},
+	}
+}
+
+func TestReconcileCollectsInsights(t *testing.T) {
+	ms := newStack(&stack.InsightsConfig{Enabled: true, Interval: "10m", Limit: 2})
+	rm := newTestManager(t, fakePrometheus(t), ms)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)}
+
+	res, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, 10*time.Minute)
+
+	got := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), req.NamespacedName, got))
+	assert.Assert(t, got.Status.Insights.LastUpdateTime.Time.Equal(testutil.Now))
+	got.Status.Insights.LastUpdateTime = nil
+	assert.DeepEqual(t, got.Status.Insights, &stack.MonitoringStackInsights{
+		HeadSeries: 1500,
+		HeadChunks: 3000,
+		TopMetrics: []stack.SeriesCount{
+			{Name: "http_requests_total", Series: 800},
+			{Name: "up", Series: 10},
+		},
+		TopLabelPairs: []stack.SeriesCount{{Name: "job=api", Series: 900}},
+		Targets: []stack.ScrapePoolTargets{{
+			ScrapePool: "serviceMonitor/ns/api/0",
+			Job:        "api",
+			Up:         1,
+			Down:       1,
+			LastError:  "connection refused",
+		}},
+	})
+
+	// The next collection happens once the interval has elapsed.
+	rm.now = func() time.Time { return testutil.Now.Add(4 * time.Minute) }
+	res, err = rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, 6*time.Minute)
+}
+
+func TestReconcileKeepsInsightsOnError(t *testing.T) {
+	ms := newStack(&stack.InsightsConfig{Enabled: true})
+	ms.Status.Insights = &stack.MonitoringStackInsights{
+		LastUpdateTime: &metav1.Time{Time: testutil.Now.Add(-time.Hour)},
+		HeadSeries:     42,
+	}
+	rm := newTestManager(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, ms)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)}
+
+	res, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, defaultInterval)
+
+	got := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), req.NamespacedName, got))
+	assert.Equal(t, got.Status.Insights.HeadSeries, int64(42))
+	assert.Assert(t, got.Status.Insights.LastUpdateTime.Time.Equal(testutil.Now))
+	assert.Assert(t, got.Status.Insights.Error != "")
+}
+
+func TestReconcileDisabled(t *testing.T) {
+	ms := newStack(nil)
+	ms.Status.Insights = &stack.MonitoringStackInsights{HeadSeries: 42}
+	rm := newTestManager(t, func(_ http.ResponseWriter, _ *http.Request) {
+		t.Fatal("unexpected request to Prometheus")
+	}, ms)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)}
+
+	res, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res, ctrl.Result{})
+
+	got := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), req.NamespacedName, got))
+	assert.Assert(t, got.Status.Insights == nil)
+}
+
+func TestReconcileSuspended(t *testing.T) {
+	ms := newStack(&stack.InsightsConfig{Enabled: true})
+	ms.Status.Conditions = []stack.Condition{{Type: stack.SuspendedCondition, Status: stack.ConditionTrue}}
+	rm := newTestManager(t, func(_ http.ResponseWriter, _ *http.Request) {
+		t.Fatal("unexpected request to Prometheus")
+	}, ms)
+
+	res, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, defaultInterval)
+}
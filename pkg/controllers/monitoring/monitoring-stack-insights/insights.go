@@ -0,0 +1,59 @@
+package monitoringstackinsights
+
+import (
+	"slices"
+	"strings"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+// seriesCounts converts the TSDB statistics to series counts, keeping at most
+// limit entries.
+func seriesCounts(stats []prometheus.Stat, limit int) []stack.SeriesCount {
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	counts := make([]stack.SeriesCount, 0, len(stats))
+	for _, s := range stats {
+		counts = append(counts, stack.SeriesCount{Name: s.Name, Series: int64(s.Value)})
+	}
+
+	return counts
+}
+
+// summarizeTargets counts the targets per health state for each scrape pool.
+// Pools are sorted by name.
+func summarizeTargets(targets []prometheus.ActiveTarget) []stack.ScrapePoolTargets {
+	pools := map[string]*stack.ScrapePoolTargets{}
+	for _, t := range targets {
+		p, found := pools[t.ScrapePool]
+		if !found {
+			p = &stack.ScrapePoolTargets{ScrapePool: t.ScrapePool, Job: t.Labels["job"]}
+			pools[t.ScrapePool] = p
+		}
+
+		switch t.Health {
+		case prometheus.HealthUp:
+			p.Up++
+		case prometheus.HealthDown:
+			p.Down++
+			if p.LastError == "" {
+				p.LastError = t.LastError
+			}
+		default:
+			p.Unknown++
+		}
+	}
+
+	summary := make([]stack.ScrapePoolTargets, 0, len(pools))
+	for _, p := range pools {
+		summary = append(summary, *p)
+	}
+	slices.SortFunc(summary, func(a, b stack.ScrapePoolTargets) int {
+		return strings.Compare(a.ScrapePool, b.ScrapePool)
+	})
+
+	return summary
+}
@@ -0,0 +1,32 @@
+package monitoringstackinsights
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+func TestSeriesCounts(t *testing.T) {
+	stats := []prometheus.Stat{{Name: "a", Value: 3}, {Name: "b", Value: 2}, {Name: "c", Value: 1}}
+
+	assert.DeepEqual(t, seriesCounts(stats, 2), []stack.SeriesCount{{Name: "a", Series: 3}, {Name: "b", Series: 2}})
+	assert.Equal(t, len(seriesCounts(stats, 10)), 3)
+	assert.Equal(t, len(seriesCounts(nil, 10)), 0)
+}
+
+func TestSummarizeTargets(t *testing.T) {
+	targets := []prometheus.ActiveTarget{
+		{ScrapePool: "serviceMonitor/ns/b/0", Labels: map[string]string{"job": "b"}, Health: prometheus.HealthUp},
+		{ScrapePool: "podMonitor/ns/a/0", Labels: map[string]string{"job": "ns/a"}, Health: prometheus.HealthDown, LastError: "timeout"},
+		{ScrapePool: "podMonitor/ns/a/0", Labels: map[string]string{"job": "ns/a"}, Health: prometheus.HealthDown, LastError: "refused"},
+		{ScrapePool: "podMonitor/ns/a/0", Labels: map[string]string{"job": "ns/a"}, Health: prometheus.HealthUnknown},
+	}
+
+	assert.DeepEqual(t, summarizeTargets(targets), []stack.ScrapePoolTargets{
+		{ScrapePool: "podMonitor/ns/a/0", Job: "ns/a", Down: 2, Unknown: 1, LastError: "timeout"},
+		{ScrapePool: "serviceMonitor/ns/b/0", Job: "b", Up: 1},
+	})
+}
@@ -0,0 +1,130 @@
+package monitoringstackpolicy
+
+import (
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	// ProvisionedByLabel is set on the MonitoringStacks provisioned by a
+	// policy with the name of the policy as value.
+	ProvisionedByLabel = "monitoring.rhobs/provisioned-by"
+
+	ReconciledReason        = "MonitoringStackPolicyReconciled"
+	FailedToReconcileReason = "FailedToReconcile"
+
+	OptedOutReason     = "OptedOut"
+	LimitReachedReason = "LimitReached"
+	TerminatingReason  = "Terminating"
+
+	optOutLabelValue   = "true"
+	defaultOptOutLabel = "monitoring.rhobs/opt-out"
+	defaultStackName   = "monitoring-stack"
+)
+
+// provisioningPlan lists the namespaces in which a MonitoringStack should be
+// provisioned and the ones which are skipped.
+type provisioningPlan struct {
+	// provision lists the candidate namespaces for provisioning.
+	provision []string
+	// namespaces lists the namespaces where the stack is effectively
+	// provisioned.
+	namespaces []string
+	skipped    []stack.SkippedNamespace
+}
+
+func (p *provisioningPlan) skip(ns string, reason string) {
+	p.skipped = append(p.skipped, stack.SkippedNamespace{Name: ns, Reason: reason})
+}
+
+func (p *provisioningPlan) keeps(ns string) bool {
+	return slices.Contains(p.namespaces, ns)
+}
+
+// planProvisioning returns the provisioning plan of the policy given the
+// namespaces matching its selector and the namespaces where a stack is already
+// provisioned. Namespaces which already have a stack come first when applying
+// the limit so that a new namespace never evicts an existing stack, then
+// namespaces are ordered by age.
+func planProvisioning(policy *stack.MonitoringStackPolicy, namespaces []corev1.Namespace, existing map[string]struct{}) *provisioningPlan {
+	optOutLabel := policy.Spec.OptOutLabel
+	if optOutLabel == "" {
+		optOutLabel = defaultOptOutLabel
+	}
+
+	namespaces = slices.Clone(namespaces)
+	slices.SortFunc(namespaces, func(a, b corev1.Namespace) int {
+		_, aExists := existing[a.Name]
+		_, bExists := existing[b.Name]
+		switch {
+		case aExists && !bExists:
+			return -1
+		case !aExists && bExists:
+			return 1
+		}
+
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	plan := &provisioningPlan{}
+	for _, ns := range namespaces {
+		switch {
+		case !ns.DeletionTimestamp.IsZero():
+			plan.skip(ns.Name, TerminatingReason)
+		case ns.Labels[optOutLabel] == optOutLabelValue:
+			plan.skip(ns.Name, OptedOutReason)
+		case policy.Spec.MaxStacks != nil && len(plan.provision) >= int(*policy.Spec.MaxStacks):
+			plan.skip(ns.Name, LimitReachedReason)
+		default:
+			plan.provision = append(plan.provision, ns.Name)
+		}
+	}
+
+	return plan
+}
+
+func stackName(policy *stack.MonitoringStackPolicy) string {
+	if policy.Spec.StackName == "" {
+		return defaultStackName
+	}
+	return policy.Spec.StackName
+}
+
+func newMonitoringStack(policy *stack.MonitoringStackPolicy, namespace string) *stack.MonitoringStack {
+	name := stackName(policy)
+
+	labels := map[string]string{}
+	for k, v := range policy.Spec.StackLabels {
+		labels[k] = v
+	}
+	labels[ProvisionedByLabel] = policy.Name
+
+	resourceSelector := policy.Spec.ResourceSelector
+	if resourceSelector == nil {
+		resourceSelector = &metav1.LabelSelector{}
+	}
+
+	return &stack.MonitoringStack{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: stack.GroupVersion.String(),
+			Kind:       "MonitoringStack",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: stack.MonitoringStackSpec{
+			Template:         policy.Spec.Template,
+			ResourceSelector: resourceSelector,
+		},
+	}
+}
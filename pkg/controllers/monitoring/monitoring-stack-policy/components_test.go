@@ -0,0 +1,98 @@
+package monitoringstackpolicy
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newNamespace(name string, age time.Duration, labels map[string]string) corev1.Namespace {
+	return corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            labels,
+			CreationTimestamp: metav1.NewTime(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+		},
+	}
+}
+
+func TestPlanProvisioning(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		newNamespace("dev-c", 1*time.Hour, nil),
+		newNamespace("dev-a", 3*time.Hour, nil),
+		newNamespace("dev-b", 2*time.Hour, map[string]string{"monitoring.rhobs/opt-out": "true"}),
+		newNamespace("dev-d", 3*time.Hour, nil),
+	}
+
+	for _, tc := range []struct {
+		name      string
+		spec      stack.MonitoringStackPolicySpec
+		existing  []string
+		provision []string
+		skipped   []stack.SkippedNamespace
+	}{
+		{
+			name:      "no limit",
+			provision: []string{"dev-a", "dev-d", "dev-c"},
+			skipped:   []stack.SkippedNamespace{{Name: "dev-b", Reason: OptedOutReason}},
+		},
+		{
+			name:      "custom opt-out label",
+			spec:      stack.MonitoringStackPolicySpec{OptOutLabel: "skip-monitoring"},
+			provision: []string{"dev-a", "dev-d", "dev-b", "dev-c"},
+		},
+		{
+			name:      "limit",
+			spec:      stack.MonitoringStackPolicySpec{MaxStacks: ptr.To(int32(2))},
+			provision: []string{"dev-a", "dev-d"},
+			skipped: []stack.SkippedNamespace{
+				{Name: "dev-b", Reason: OptedOutReason},
+				{Name: "dev-c", Reason: LimitReachedReason},
+			},
+		},
+		{
+			name:      "existing stacks are kept when the limit is reached",
+			spec:      stack.MonitoringStackPolicySpec{MaxStacks: ptr.To(int32(2))},
+			existing:  []string{"dev-c"},
+			provision: []string{"dev-c", "dev-a"},
+			skipped: []stack.SkippedNamespace{
+				{Name: "dev-d", Reason: LimitReachedReason},
+				{Name: "dev-b", Reason: OptedOutReason},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			existing := map[string]struct{}{}
+			for _, ns := range tc.existing {
+				existing[ns] = struct{}{}
+			}
+
+			plan := planProvisioning(&stack.MonitoringStackPolicy{Spec: tc.spec}, namespaces, existing)
+			assert.DeepEqual(t, tc.provision, plan.provision)
+			assert.DeepEqual(t, tc.skipped, plan.skipped)
+		})
+	}
+}
+
+func TestNewMonitoringStack(t *testing.T) {
+	policy := &stack.MonitoringStackPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "self-service"},
+		Spec: stack.MonitoringStackPolicySpec{
+			Template:    "default",
+			StackLabels: map[string]string{"tier": "dev"},
+		},
+	}
+
+	ms := newMonitoringStack(policy, "dev-a")
+	assert.Equal(t, ms.Name, "monitoring-stack")
+	assert.Equal(t, ms.Namespace, "dev-a")
+	assert.DeepEqual(t, ms.Labels, map[string]string{"tier": "dev", ProvisionedByLabel: "self-service"})
+	assert.Equal(t, ms.Spec.Template, "default")
+	assert.DeepEqual(t, ms.Spec.ResourceSelector, &metav1.LabelSelector{})
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstackpolicy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+type resourceManager struct {
+	client.Client
+	scheme *runtime.Scheme
+	logger logr.Logger
+}
+
+// RBAC for managing monitoring stack policies
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstackpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstackpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstackpolicies/finalizers,verbs=update
+
+// RBAC for provisioning monitoring stacks
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client: mgr.GetClient(),
+		scheme: mgr.GetScheme(),
+		logger: ctrl.Log.WithName("monitoring-stack-policy"),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&stack.MonitoringStackPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&stack.MonitoringStack{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(rm.findPoliciesForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("policy", req.Name)
+	logger.Info("Reconciling monitoring stack policy")
+
+	policy := &stack.MonitoringStackPolicy{}
+	err := rm.Get(ctx, req.NamespacedName, policy)
+	if apierrors.IsNotFound(err) {
+		// The provisioned stacks are garbage-collected.
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !policy.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	recErr := rm.reconcilePolicy(ctx, policy)
+	if apierrors.IsAlreadyExists(recErr) || apierrors.IsConflict(recErr) {
+		logger.V(3).Info("skipping reconcile error", "err", recErr)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	policy.Status.Conditions = []stack.Condition{reconciledCondition(policy, recErr)}
+	if err := rm.Status().Update(ctx, policy); err != nil {
+		logger.Info("Failed to update status", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	return ctrl.Result{}, recErr
+}
+
+// reconcilePolicy provisions and deletes the MonitoringStacks of the policy
+// and updates its status (without persisting it).
+func (rm resourceManager) reconcilePolicy(ctx context.Context, policy *stack.MonitoringStackPolicy) error {
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := rm.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	provisioned := &stack.MonitoringStackList{}
+	if err := rm.List(ctx, provisioned, client.MatchingLabels{ProvisionedByLabel: policy.Name}); err != nil {
+		return err
+	}
+
+	name := stackName(policy)
+	existing := map[string]struct{}{}
+	for _, ms := range provisioned.Items {
+		if ms.Name == name {
+			existing[ms.Namespace] = struct{}{}
+		}
+	}
+
+	plan := planProvisioning(policy, namespaces.Items, existing)
+
+	var reconcilers []reconciler.Reconciler
+	for _, ns := range plan.provision {
+		// Don't take over a stack which hasn't been provisioned by the
+		// policy.
+		if _, found := existing[ns]; !found {
+			ms := &stack.MonitoringStack{}
+			err := rm.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, ms)
+			if err == nil {
+				plan.skip(ns, fmt.Sprintf("MonitoringStack %q already exists", name))
+				continue
+			}
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+
+		plan.namespaces = append(plan.namespaces, ns)
+		reconcilers = append(reconcilers, reconciler.NewUpdater(newMonitoringStack(policy, ns), policy))
+	}
+
+	for _, ms := range provisioned.Items {
+		if !plan.keeps(ms.Namespace) || ms.Name != name {
+			reconcilers = append(reconcilers, reconciler.NewDeleter(&ms))
+		}
+	}
+
+	policy.Status.Namespaces = plan.namespaces
+	policy.Status.Provisioned = int32(len(plan.namespaces))
+	policy.Status.SkippedNamespaces = plan.skipped
+
+	for _, r := range reconcilers {
+		if err := r.Reconcile(ctx, rm, rm.scheme); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findPoliciesForNamespace returns all policies since any of them may select
+// (or stop selecting) the namespace.
+func (rm resourceManager) findPoliciesForNamespace(ctx context.Context, _ client.Object) []reconcile.Request {
+	policies := &stack.MonitoringStackPolicyList{}
+	if err := rm.List(ctx, policies); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stack policies")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(policies.Items))
+	for _, p := range policies.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&p)})
+	}
+	return requests
+}
+
+func reconciledCondition(policy *stack.MonitoringStackPolicy, err error) stack.Condition {
+	c := stack.Condition{
+		Type:               stack.ReconciledCondition,
+		Status:             stack.ConditionTrue,
+		Reason:             ReconciledReason,
+		Message:            fmt.Sprintf("%d MonitoringStack(s) provisioned", policy.Status.Provisioned),
+		ObservedGeneration: policy.Generation,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if err != nil {
+		c.Status = stack.ConditionFalse
+		c.Reason = FailedToReconcileReason
+		c.Message = err.Error()
+	}
+
+	return c
+}
@@ -0,0 +1,134 @@
+package monitoringstackpolicy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+)
+
+// newTestManager returns a resource manager backed by a fake client.
+func newTestManager(objs ...client.Object) *resourceManager {
+	c := testutil.NewClientBuilder(objs...).WithInterceptorFuncs(testutil.WithApplyPatch()).Build()
+
+	return &resourceManager{
+		Client: c,
+		scheme: c.Scheme(),
+		logger: logr.Discard(),
+	}
+}
+
+func newPolicy(spec stack.MonitoringStackPolicySpec) *stack.MonitoringStackPolicy {
+	spec.NamespaceSelector = metav1.LabelSelector{MatchLabels: map[string]string{"team": "dev"}}
+	return &stack.MonitoringStackPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "dev", Generation: 1},
+		Spec:       spec,
+	}
+}
+
+func reconcilePolicy(t *testing.T, rm *resourceManager) *stack.MonitoringStackPolicy {
+	t.Helper()
+
+	key := client.ObjectKey{Name: "dev"}
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	assert.NilError(t, err)
+
+	got := &stack.MonitoringStackPolicy{}
+	assert.NilError(t, rm.Get(context.Background(), key, got))
+	return got
+}
+
+// provisionedNamespaces returns the namespaces of the stacks provisioned by
+// the policy.
+func provisionedNamespaces(t *testing.T, rm *resourceManager) []string {
+	t.Helper()
+
+	stacks := &stack.MonitoringStackList{}
+	assert.NilError(t, rm.List(context.Background(), stacks, client.MatchingLabels{ProvisionedByLabel: "dev"}))
+
+	var namespaces []string
+	for _, ms := range stacks.Items {
+		assert.Equal(t, ms.Name, "monitoring-stack")
+		namespaces = append(namespaces, ms.Namespace)
+	}
+	return namespaces
+}
+
+func TestReconcileOptOut(t *testing.T) {
+	dev := map[string]string{"team": "dev"}
+	optedOut := map[string]string{"team": "dev", "monitoring.rhobs/opt-out": "true"}
+	a := newNamespace("dev-a", 2*time.Hour, dev)
+	b := newNamespace("dev-b", 1*time.Hour, optedOut)
+	other := newNamespace("other", 3*time.Hour, nil)
+	rm := newTestManager(newPolicy(stack.MonitoringStackPolicySpec{}), &a, &b, &other)
+
+	got := reconcilePolicy(t, rm)
+	assert.DeepEqual(t, provisionedNamespaces(t, rm), []string{"dev-a"})
+	assert.DeepEqual(t, got.Status.Namespaces, []string{"dev-a"})
+	assert.DeepEqual(t, got.Status.SkippedNamespaces, []stack.SkippedNamespace{{Name: "dev-b", Reason: OptedOutReason}})
+	assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionTrue)
+
+	// The stack is deleted when the namespace opts out.
+	a.Labels = optedOut
+	assert.NilError(t, rm.Update(context.Background(), &a))
+	got = reconcilePolicy(t, rm)
+	assert.Equal(t, len(provisionedNamespaces(t, rm)), 0)
+	assert.Equal(t, got.Status.Provisioned, int32(0))
+}
+
+func TestReconcileMaxStacks(t *testing.T) {
+	dev := map[string]string{"team": "dev"}
+	a := newNamespace("dev-a", 3*time.Hour, dev)
+	b := newNamespace("dev-b", 2*time.Hour, dev)
+	c := newNamespace("dev-c", 1*time.Hour, dev)
+	rm := newTestManager(newPolicy(stack.MonitoringStackPolicySpec{MaxStacks: ptr.To(int32(2))}), &a, &b, &c)
+
+	// The oldest namespaces are served first.
+	got := reconcilePolicy(t, rm)
+	assert.DeepEqual(t, provisionedNamespaces(t, rm), []string{"dev-a", "dev-b"})
+	assert.DeepEqual(t, got.Status.SkippedNamespaces, []stack.SkippedNamespace{{Name: "dev-c", Reason: LimitReachedReason}})
+	assert.Equal(t, got.Status.Provisioned, int32(2))
+
+	// Lowering the limit deletes the stacks of the youngest namespaces.
+	got.Spec.MaxStacks = ptr.To(int32(1))
+	assert.NilError(t, rm.Update(context.Background(), got))
+	got = reconcilePolicy(t, rm)
+	assert.DeepEqual(t, provisionedNamespaces(t, rm), []string{"dev-a"})
+	assert.DeepEqual(t, got.Status.SkippedNamespaces, []stack.SkippedNamespace{
+		{Name: "dev-b", Reason: LimitReachedReason},
+		{Name: "dev-c", Reason: LimitReachedReason},
+	})
+}
+
+func TestReconcileExistingStack(t *testing.T) {
+	dev := map[string]string{"team": "dev"}
+	a := newNamespace("dev-a", 2*time.Hour, dev)
+	b := newNamespace("dev-b", 1*time.Hour, dev)
+	existing := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "monitoring-stack", Namespace: "dev-a"},
+	}
+	rm := newTestManager(newPolicy(stack.MonitoringStackPolicySpec{}), &a, &b, existing)
+
+	// The stack which hasn't been provisioned by the policy is left
+	// untouched.
+	got := reconcilePolicy(t, rm)
+	assert.DeepEqual(t, provisionedNamespaces(t, rm), []string{"dev-b"})
+	assert.DeepEqual(t, got.Status.Namespaces, []string{"dev-b"})
+	assert.DeepEqual(t, got.Status.SkippedNamespaces, []stack.SkippedNamespace{
+		{Name: "dev-a", Reason: `MonitoringStack "monitoring-stack" already exists`},
+	})
+
+	ms := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(existing), ms))
+	assert.Equal(t, len(ms.Labels), 0)
+	assert.Equal(t, len(ms.OwnerReferences), 0)
+}
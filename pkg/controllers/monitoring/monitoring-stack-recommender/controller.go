@@ -0,0 +1,310 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstackrecommender
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringstack "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+const (
+	// recomputeInterval is the interval between two computations of the
+	// recommendations.
+	recomputeInterval = 30 * time.Minute
+
+	defaultWindow         = "24h"
+	defaultTolerance      = 10
+	defaultCooldownPeriod = 6 * time.Hour
+
+	// requestTimeout bounds the duration of the requests to Prometheus.
+	requestTimeout = time.Minute
+)
+
+type resourceManager struct {
+	client.Client
+	// apiReader reads the PVCs and the certificate authority of the stacks
+	// without caching all the PVCs and Secrets of the cluster.
+	apiReader client.Reader
+	logger    logr.Logger
+	now       func() time.Time
+	newClient func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (querier, error)
+}
+
+// RBAC for publishing recommendations
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update;patch
+
+// RBAC for expanding the Prometheus volumes
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;patch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		logger:    ctrl.Log.WithName("monitoring-stack-recommender"),
+		now:       time.Now,
+		newClient: func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (querier, error) {
+			return prometheus.NewClientForStack(ctx, c, ms, host)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("monitoring-stack-recommender").
+		For(&stack.MonitoringStack{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("stack", req.NamespacedName)
+
+	ms := &stack.MonitoringStack{}
+	err := rm.Get(ctx, req.NamespacedName, ms)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !ms.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	cfg := ms.Spec.Recommendations
+	if cfg == nil || !cfg.Enabled {
+		if ms.Status.Recommendations == nil {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, rm.patchRecommendations(ctx, ms, nil)
+	}
+
+	if ms.Suspended() {
+		logger.V(3).Info("skipping suspended stack")
+		return ctrl.Result{RequeueAfter: recomputeInterval}, nil
+	}
+
+	// The effective specification, merged with the template and defaulted
+	// by the monitoring-stack controller, isn't reported when the template
+	// can't be resolved: the current resources of the stack are unknown.
+	spec := ms.Status.EffectiveSpec
+	if spec == nil {
+		logger.V(3).Info("skipping stack without effective specification")
+		return ctrl.Result{RequeueAfter: recomputeInterval}, nil
+	}
+
+	now := rm.now()
+	if last := ms.Status.Recommendations; last != nil && last.LastUpdateTime != nil {
+		if next := last.LastUpdateTime.Add(recomputeInterval); next.After(now) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	logger.Info("Computing monitoring stack resource recommendations")
+	recs := &stack.ResourceRecommendations{}
+	if ms.Status.Recommendations != nil {
+		recs = ms.Status.Recommendations.DeepCopy()
+	}
+	recs.LastUpdateTime = &metav1.Time{Time: now}
+	recs.Error = ""
+
+	if err := rm.computeRecommendations(ctx, ms, spec, recs); err != nil {
+		logger.Info("Failed to compute recommendations", "err", err)
+		recs.Error = err.Error()
+	} else if cfg.Autoscale != nil && cfg.Autoscale.Enabled {
+		if err := rm.autoscale(ctx, ms, spec, recs, now); err != nil {
+			logger.Info("Failed to apply recommendations", "err", err)
+			recs.Error = err.Error()
+		}
+	}
+
+	if err := rm.patchRecommendations(ctx, ms, recs); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: recomputeInterval}, nil
+}
+
+// computeRecommendations observes the usage of the Prometheus pods and
+// updates the recommended resources and storage.
+func (rm resourceManager) computeRecommendations(ctx context.Context, ms *stack.MonitoringStack, spec *stack.MonitoringStackSpec, recs *stack.ResourceRecommendations) error {
+	window := string(ms.Spec.Recommendations.Window)
+	if window == "" {
+		window = defaultWindow
+	}
+
+	retention, err := model.ParseDuration(string(spec.Retention))
+	if err != nil {
+		return fmt.Errorf("invalid retention: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	host := fmt.Sprintf("%s-prometheus.%s.svc", ms.Name, ms.Namespace)
+	q, err := rm.newClient(ctx, rm.apiReader, ms, host)
+	if err != nil {
+		return err
+	}
+
+	u, err := observe(ctx, q, window)
+	if err != nil {
+		return err
+	}
+
+	resources, storage := recommend(u, time.Duration(retention))
+	recs.Resources = &resources
+	recs.Storage = &storage
+	recs.HeadSeries = int64(u.headSeries)
+	recs.HeadSeriesGrowthPerDay = int64(u.seriesGrowth * (24 * time.Hour).Seconds())
+
+	return nil
+}
+
+// autoscale applies the recommendations within the bounds of the policy. The
+// resources are only updated when the stack is available (e.g. no rollout is
+// in progress), the change is significant and the cooldown period has
+// elapsed. The monitoring-stack controller then rolls out the applied
+// resources.
+func (rm resourceManager) autoscale(ctx context.Context, ms *stack.MonitoringStack, spec *stack.MonitoringStackSpec, recs *stack.ResourceRecommendations, now time.Time) error {
+	policy := ms.Spec.Recommendations.Autoscale
+
+	cooldown := defaultCooldownPeriod
+	if policy.CooldownPeriod != "" {
+		d, err := model.ParseDuration(string(policy.CooldownPeriod))
+		if err != nil {
+			return fmt.Errorf("invalid cooldown period: %w", err)
+		}
+		cooldown = time.Duration(d)
+	}
+
+	tolerance := policy.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultTolerance
+	}
+
+	target := boundedResources(*recs.Resources, policy)
+	current := spec.Resources
+	if recs.AppliedResources != nil {
+		current = *recs.AppliedResources
+	}
+
+	coolingDown := recs.LastAppliedTime != nil && recs.LastAppliedTime.Add(cooldown).After(now)
+	if hasCondition(ms, stack.AvailableCondition) && !coolingDown && significantChange(current, target, tolerance) {
+		recs.AppliedResources = &target
+		recs.LastAppliedTime = &metav1.Time{Time: now}
+	}
+
+	return rm.expandVolumes(ctx, ms, spec, recs, boundedStorage(*recs.Storage, policy))
+}
+
+// expandVolumes grows the persistent volume claims of the Prometheus pods to
+// the given size. Claims are never shrunk and only expanded if their storage
+// class allows it.
+func (rm resourceManager) expandVolumes(ctx context.Context, ms *stack.MonitoringStack, spec *stack.MonitoringStackSpec, recs *stack.ResourceRecommendations, size resource.Quantity) error {
+	if spec.PrometheusConfig == nil || spec.PrometheusConfig.PersistentVolumeClaim == nil {
+		return nil
+	}
+
+	replicas := 1
+	if spec.PrometheusConfig.Replicas != nil {
+		replicas = int(*spec.PrometheusConfig.Replicas)
+	}
+
+	for i := 0; i < replicas; i++ {
+		pvc := &corev1.PersistentVolumeClaim{}
+		key := client.ObjectKey{Namespace: ms.Namespace, Name: monitoringstack.PrometheusPVCName(ms, monitoringstack.PrometheusPodName(ms, i))}
+		if err := rm.apiReader.Get(ctx, key, pvc); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; current.Cmp(size) >= 0 {
+			continue
+		}
+
+		expandable, err := rm.allowsExpansion(ctx, pvc)
+		if err != nil {
+			return err
+		}
+		if !expandable {
+			return fmt.Errorf("the storage class of PersistentVolumeClaim %q doesn't allow volume expansion", pvc.Name)
+		}
+
+		patch := client.MergeFrom(pvc.DeepCopy())
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		if err := rm.Patch(ctx, pvc, patch); err != nil {
+			return fmt.Errorf("failed to expand PersistentVolumeClaim %q: %w", pvc.Name, err)
+		}
+
+		recs.AppliedStorage = &size
+	}
+
+	return nil
+}
+
+func (rm resourceManager) allowsExpansion(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return false, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := rm.Get(ctx, client.ObjectKey{Name: *pvc.Spec.StorageClassName}, sc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// patchRecommendations updates the recommendations in the status of the stack
+// without touching the fields owned by the other controllers.
+func (rm resourceManager) patchRecommendations(ctx context.Context, ms *stack.MonitoringStack, recs *stack.ResourceRecommendations) error {
+	patch := client.MergeFrom(ms.DeepCopy())
+	ms.Status.Recommendations = recs
+	return rm.Status().Patch(ctx, ms, patch)
+}
+
+func hasCondition(ms *stack.MonitoringStack, ct stack.ConditionType) bool {
+	for _, c := range ms.Status.Conditions {
+		if c.Type == ct {
+			return c.Status == stack.ConditionTrue
+		}
+	}
+	return false
+}
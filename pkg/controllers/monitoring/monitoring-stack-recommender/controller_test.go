@@ -0,0 +1,211 @@
+package monitoringstackrecommender
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+// fakePrometheus answers the usage queries with a steady usage of 1000Mi of
+// memory, 200m of cpu, 10k samples/s and 5GiB of disk.
+func fakePrometheus(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/api/v1/query")
+		assert.NilError(t, r.ParseForm())
+
+		query := r.PostForm.Get("query")
+		value := "0"
+		switch {
+		case strings.Contains(query, "process_resident_memory_bytes"):
+			value = "1048576000"
+		case strings.Contains(query, "process_cpu_seconds_total"):
+			value = "0.2"
+		case strings.Contains(query, "prometheus_tsdb_head_samples_appended_total"):
+			value = "10000"
+		case strings.Contains(query, "deriv"):
+			value = "0"
+		case strings.Contains(query, "prometheus_tsdb_head_series"):
+			value = "100000"
+		case strings.Contains(query, "prometheus_tsdb_storage_blocks_bytes"):
+			value = "5368709120"
+		}
+
+		testutil.WriteSuccess(w, testutil.Vector(value))
+	}
+}
+
+func newTestManager(t *testing.T, objs ...client.Object) *resourceManager {
+	t.Helper()
+
+	c := testutil.NewClientBuilder(objs...).Build()
+	url := testutil.NewPrometheusServer(t, fakePrometheus(t))
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		logger:    logr.Discard(),
+		now:       testutil.Clock,
+		newClient: func(_ context.Context, _ client.Reader, _ *stack.MonitoringStack, _ string) (querier, error) {
+			return prometheus.NewClient(url, nil)
+		},
+	}
+}
+
+func newStack(autoscale *stack.AutoscalePolicy) *stack.MonitoringStack {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			Resources: resources("100m", "256Mi", "500m", "512Mi"),
+			PrometheusConfig: &stack.PrometheusConfig{
+				Replicas:              ptr.To(int32(1)),
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{},
+			},
+			Recommendations: &stack.RecommendationsConfig{Enabled: true, Autoscale: autoscale},
+		},
+		Status: stack.MonitoringStackStatus{
+			Conditions: []stack.Condition{{Type: stack.AvailableCondition, Status: stack.ConditionTrue}},
+		},
+	}
+
+	// The effective specification is reported by the monitoring-stack
+	// controller.
+	spec := *ms.Spec.DeepCopy()
+	spec.Retention = "120h"
+	ms.Status.EffectiveSpec = &spec
+	return ms
+}
+
+func newPVC(size string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus-ms-db-prometheus-ms-0", Namespace: "ns"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: ptr.To("standard"),
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+}
+
+func getStack(t *testing.T, rm *resourceManager) *stack.MonitoringStack {
+	t.Helper()
+
+	ms := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "ms"}, ms))
+	return ms
+}
+
+func TestReconcileRecommendationsOnly(t *testing.T) {
+	rm := newTestManager(t, newStack(nil), newPVC("1Gi"))
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "ms"}}
+
+	res, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, recomputeInterval)
+
+	recs := getStack(t, rm).Status.Recommendations
+	assert.Equal(t, recs.Error, "")
+	assert.DeepEqual(t, *recs.Resources, resources("240m", "1200Mi", "280m", "1400Mi"))
+	assert.DeepEqual(t, *recs.Storage, resource.MustParse("10Gi"))
+	assert.Equal(t, recs.HeadSeries, int64(100000))
+	assert.Assert(t, recs.AppliedResources == nil)
+	assert.Assert(t, recs.AppliedStorage == nil)
+
+	// Recommendations aren't recomputed before the interval has elapsed.
+	rm.now = func() time.Time { return testutil.Now.Add(10 * time.Minute) }
+	res, err = rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, 20*time.Minute)
+}
+
+func TestReconcileNoEffectiveSpec(t *testing.T) {
+	// The template of the stack can't be resolved.
+	ms := newStack(nil)
+	ms.Status.EffectiveSpec = nil
+	rm := newTestManager(t, ms)
+
+	res, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, recomputeInterval)
+	assert.Assert(t, getStack(t, rm).Status.Recommendations == nil)
+}
+
+func TestReconcileAutoscale(t *testing.T) {
+	policy := &stack.AutoscalePolicy{
+		Enabled: true,
+		MaxAllowed: corev1.ResourceList{
+			corev1.ResourceMemory:  resource.MustParse("1Gi"),
+			corev1.ResourceStorage: resource.MustParse("6Gi"),
+		},
+	}
+	sc := &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: "standard"},
+		AllowVolumeExpansion: ptr.To(true),
+	}
+	rm := newTestManager(t, newStack(policy), newPVC("1Gi"), sc)
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "ms"}}
+
+	_, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+
+	recs := getStack(t, rm).Status.Recommendations
+	assert.Equal(t, recs.Error, "")
+	assert.DeepEqual(t, *recs.AppliedResources, resources("240m", "1Gi", "280m", "1Gi"))
+	assert.Assert(t, recs.LastAppliedTime.Time.Equal(testutil.Now))
+	assert.DeepEqual(t, *recs.AppliedStorage, resource.MustParse("6Gi"))
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "prometheus-ms-db-prometheus-ms-0"}, pvc))
+	assert.DeepEqual(t, pvc.Spec.Resources.Requests[corev1.ResourceStorage], resource.MustParse("6Gi"))
+}
+
+func TestReconcileAutoscaleCooldown(t *testing.T) {
+	ms := newStack(&stack.AutoscalePolicy{Enabled: true, CooldownPeriod: "1h"})
+	applied := resources("100m", "256Mi", "500m", "512Mi")
+	ms.Status.Recommendations = &stack.ResourceRecommendations{
+		LastUpdateTime:   &metav1.Time{Time: testutil.Now.Add(-recomputeInterval)},
+		AppliedResources: &applied,
+		LastAppliedTime:  &metav1.Time{Time: testutil.Now.Add(-30 * time.Minute)},
+	}
+	rm := newTestManager(t, ms)
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)}
+
+	_, err := rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *getStack(t, rm).Status.Recommendations.AppliedResources, applied)
+
+	// The recommendation is applied once the cooldown period has elapsed.
+	rm.now = func() time.Time { return testutil.Now.Add(time.Hour) }
+	_, err = rm.Reconcile(context.Background(), req)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *getStack(t, rm).Status.Recommendations.AppliedResources, resources("240m", "1200Mi", "280m", "1400Mi"))
+}
+
+func TestReconcileAutoscaleNotExpandable(t *testing.T) {
+	rm := newTestManager(t, newStack(&stack.AutoscalePolicy{Enabled: true}), newPVC("1Gi"))
+
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "ms"}})
+	assert.NilError(t, err)
+
+	recs := getStack(t, rm).Status.Recommendations
+	assert.Assert(t, strings.Contains(recs.Error, "doesn't allow volume expansion"), recs.Error)
+	assert.Assert(t, recs.AppliedStorage == nil)
+}
@@ -0,0 +1,196 @@
+package monitoringstackrecommender
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+const (
+	// selfScrapeSelector selects the series of the Prometheus pods of the
+	// stack, scraped by the self-monitoring job.
+	selfScrapeSelector = `{job="prometheus-self"}`
+
+	// headroom is the relative margin added on top of the observed usage.
+	headroom = 0.2
+
+	// growthHorizon is the duration over which the growth of the number of
+	// head series is projected.
+	growthHorizon = 7 * 24 * time.Hour
+
+	// bytesPerSample is the upper bound of the on-disk size of a compressed
+	// sample.
+	bytesPerSample = 2
+
+	mebi = 1 << 20
+	gibi = 1 << 30
+)
+
+// usage is the resource usage of the Prometheus pods observed over the
+// recommendation window. The values are the maximum across pods.
+type usage struct {
+	memoryAvg  float64 // bytes
+	memoryPeak float64 // bytes
+	cpuAvg     float64 // cores
+	cpuPeak    float64 // cores
+
+	headSeries   float64
+	seriesGrowth float64 // series per second
+	samplesRate  float64 // samples per second
+	diskUsage    float64 // bytes
+}
+
+// querier evaluates instant queries against the Prometheus API.
+type querier interface {
+	Query(ctx context.Context, query string) ([]prometheus.Sample, error)
+}
+
+// observe queries Prometheus for the usage of its own pods over the window.
+func observe(ctx context.Context, q querier, window string) (usage, error) {
+	var u usage
+	queries := []struct {
+		value *float64
+		query string
+	}{
+		{&u.memoryAvg, fmt.Sprintf("max(avg_over_time(process_resident_memory_bytes%s[%s]))", selfScrapeSelector, window)},
+		{&u.memoryPeak, fmt.Sprintf("max(max_over_time(process_resident_memory_bytes%s[%s]))", selfScrapeSelector, window)},
+		{&u.cpuAvg, fmt.Sprintf("max(rate(process_cpu_seconds_total%s[%s]))", selfScrapeSelector, window)},
+		{&u.cpuPeak, fmt.Sprintf("max(max_over_time(rate(process_cpu_seconds_total%s[5m])[%s:1m]))", selfScrapeSelector, window)},
+		{&u.headSeries, fmt.Sprintf("max(prometheus_tsdb_head_series%s)", selfScrapeSelector)},
+		{&u.seriesGrowth, fmt.Sprintf("max(deriv(prometheus_tsdb_head_series%s[%s]))", selfScrapeSelector, window)},
+		{&u.samplesRate, fmt.Sprintf("max(sum by (pod) (rate(prometheus_tsdb_head_samples_appended_total%s[%s])))", selfScrapeSelector, window)},
+		{&u.diskUsage, fmt.Sprintf("max(prometheus_tsdb_storage_blocks_bytes%s + prometheus_tsdb_wal_storage_size_bytes%s)", selfScrapeSelector, selfScrapeSelector)},
+	}
+
+	for _, m := range queries {
+		samples, err := q.Query(ctx, m.query)
+		if err != nil {
+			return u, err
+		}
+		if len(samples) == 0 {
+			return u, fmt.Errorf("no data returned by %q, is the stack monitoring itself?", m.query)
+		}
+		*m.value = samples[0].Value
+	}
+
+	return u, nil
+}
+
+// growthFactor returns the expected relative increase of the number of head
+// series over the growth horizon. It is never less than 1.
+func (u usage) growthFactor() float64 {
+	if u.headSeries <= 0 || u.seriesGrowth <= 0 {
+		return 1
+	}
+	return (u.headSeries + u.seriesGrowth*growthHorizon.Seconds()) / u.headSeries
+}
+
+// recommend returns the resources and the storage size recommended for the
+// observed usage and the given retention. Memory and storage are scaled with
+// the projected growth of the number of series.
+func recommend(u usage, retention time.Duration) (corev1.ResourceRequirements, resource.Quantity) {
+	factor := u.growthFactor()
+
+	memoryRequest := u.memoryAvg * factor * (1 + headroom)
+	memoryLimit := math.Max(u.memoryPeak*factor*(1+2*headroom), memoryRequest)
+	cpuRequest := math.Max(u.cpuAvg*(1+headroom), 0.01)
+	cpuLimit := math.Max(u.cpuPeak*(1+2*headroom), cpuRequest)
+
+	storage := math.Max(u.samplesRate*retention.Seconds()*bytesPerSample, u.diskUsage) * factor * (1 + headroom)
+
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    cpuQuantity(cpuRequest),
+			corev1.ResourceMemory: bytesQuantity(memoryRequest, mebi),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    cpuQuantity(cpuLimit),
+			corev1.ResourceMemory: bytesQuantity(memoryLimit, mebi),
+		},
+	}, bytesQuantity(storage, gibi)
+}
+
+// cpuQuantity rounds up the number of cores to the next 10 millicores.
+func cpuQuantity(cores float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Ceil(cores*100))*10, resource.DecimalSI)
+}
+
+// bytesQuantity rounds up the number of bytes to the next multiple of unit
+// (at least one unit).
+func bytesQuantity(bytes float64, unit int64) resource.Quantity {
+	n := int64(math.Ceil(bytes / float64(unit)))
+	if n < 1 {
+		n = 1
+	}
+	return *resource.NewQuantity(n*unit, resource.BinarySI)
+}
+
+// boundedResources returns the recommended resources within the bounds of the
+// autoscale policy: requests are at least the minimum, requests and limits at
+// most the maximum, and limits never less than requests.
+func boundedResources(rec corev1.ResourceRequirements, policy *stack.AutoscalePolicy) corev1.ResourceRequirements {
+	bounded := *rec.DeepCopy()
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		request, hasRequest := bounded.Requests[name]
+		if hasRequest {
+			if lower, found := policy.MinAllowed[name]; found && request.Cmp(lower) < 0 {
+				request = lower.DeepCopy()
+			}
+			if upper, found := policy.MaxAllowed[name]; found && request.Cmp(upper) > 0 {
+				request = upper.DeepCopy()
+			}
+			bounded.Requests[name] = request
+		}
+
+		if limit, found := bounded.Limits[name]; found {
+			if upper, found := policy.MaxAllowed[name]; found && limit.Cmp(upper) > 0 {
+				limit = upper.DeepCopy()
+			}
+			if hasRequest && limit.Cmp(request) < 0 {
+				limit = request.DeepCopy()
+			}
+			bounded.Limits[name] = limit
+		}
+	}
+
+	return bounded
+}
+
+// boundedStorage returns the recommended storage size within the maximum of
+// the autoscale policy.
+func boundedStorage(rec resource.Quantity, policy *stack.AutoscalePolicy) resource.Quantity {
+	if upper, found := policy.MaxAllowed[corev1.ResourceStorage]; found && rec.Cmp(upper) > 0 {
+		return upper.DeepCopy()
+	}
+	return rec
+}
+
+// significantChange returns true if the cpu or memory requests differ by more
+// than tolerance percent.
+func significantChange(current, target corev1.ResourceRequirements, tolerance int32) bool {
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		cur, hasCur := current.Requests[name]
+		tgt, hasTgt := target.Requests[name]
+		if hasCur != hasTgt {
+			return true
+		}
+		if !hasCur || cur.IsZero() {
+			continue
+		}
+
+		diff := math.Abs(tgt.AsApproximateFloat64()-cur.AsApproximateFloat64()) / cur.AsApproximateFloat64()
+		if diff*100 > float64(tolerance) {
+			return true
+		}
+	}
+
+	return false
+}
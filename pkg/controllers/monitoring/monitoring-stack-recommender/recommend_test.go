@@ -0,0 +1,111 @@
+package monitoringstackrecommender
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func resources(reqCPU, reqMem, limCPU, limMem string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(reqCPU),
+			corev1.ResourceMemory: resource.MustParse(reqMem),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(limCPU),
+			corev1.ResourceMemory: resource.MustParse(limMem),
+		},
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		usage     usage
+		resources corev1.ResourceRequirements
+		storage   string
+	}{
+		{
+			name: "steady usage",
+			usage: usage{
+				memoryAvg:   1000 * mebi,
+				memoryPeak:  1500 * mebi,
+				cpuAvg:      0.2,
+				cpuPeak:     0.5,
+				headSeries:  100000,
+				samplesRate: 10000,
+				diskUsage:   5 * gibi,
+			},
+			// 1000Mi * 1.2, 1500Mi * 1.4, 0.2 * 1.2, 0.5 * 1.4
+			resources: resources("240m", "1200Mi", "700m", "2100Mi"),
+			// 10000 samples/s * 120h * 2 bytes = 8.05GiB * 1.2
+			storage: "10Gi",
+		},
+		{
+			name: "growing series",
+			usage: usage{
+				memoryAvg:  1000 * mebi,
+				memoryPeak: 1000 * mebi,
+				cpuAvg:     0.001,
+				cpuPeak:    0.001,
+				headSeries: 100000,
+				// +50% over the growth horizon.
+				seriesGrowth: 50000 / growthHorizon.Seconds(),
+				diskUsage:    10 * gibi,
+			},
+			resources: resources("10m", "1800Mi", "10m", "2100Mi"),
+			storage:   "18Gi",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, storage := recommend(tc.usage, 120*time.Hour)
+			assert.DeepEqual(t, res, tc.resources)
+			assert.Equal(t, storage.String(), tc.storage)
+		})
+	}
+}
+
+func TestBoundedResources(t *testing.T) {
+	policy := &stack.AutoscalePolicy{
+		MinAllowed: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+		MaxAllowed: corev1.ResourceList{
+			corev1.ResourceCPU:     resource.MustParse("1"),
+			corev1.ResourceMemory:  resource.MustParse("4Gi"),
+			corev1.ResourceStorage: resource.MustParse("50Gi"),
+		},
+	}
+
+	assert.DeepEqual(t,
+		boundedResources(resources("10m", "8Gi", "50m", "16Gi"), policy),
+		resources("100m", "4Gi", "100m", "4Gi"),
+	)
+	assert.DeepEqual(t,
+		boundedResources(resources("200m", "1Gi", "2", "2Gi"), policy),
+		resources("200m", "1Gi", "1", "2Gi"),
+	)
+	assert.DeepEqual(t,
+		boundedResources(resources("200m", "1Gi", "2", "2Gi"), &stack.AutoscalePolicy{}),
+		resources("200m", "1Gi", "2", "2Gi"),
+	)
+
+	assert.DeepEqual(t, boundedStorage(resource.MustParse("100Gi"), policy), resource.MustParse("50Gi"))
+	assert.DeepEqual(t, boundedStorage(resource.MustParse("10Gi"), policy), resource.MustParse("10Gi"))
+}
+
+func TestSignificantChange(t *testing.T) {
+	current := resources("100m", "1Gi", "1", "2Gi")
+
+	assert.Assert(t, !significantChange(current, resources("105m", "1Gi", "2", "4Gi"), 10))
+	assert.Assert(t, significantChange(current, resources("120m", "1Gi", "1", "2Gi"), 10))
+	assert.Assert(t, significantChange(current, resources("100m", "800Mi", "1", "2Gi"), 10))
+	assert.Assert(t, significantChange(corev1.ResourceRequirements{}, current, 10))
+}
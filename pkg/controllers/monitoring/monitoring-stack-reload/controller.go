@@ -0,0 +1,286 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstackreload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	dto "github.com/prometheus/client_model/go"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+	"github.com/rhobs/observability-operator/pkg/selection"
+)
+
+const (
+	// checkInterval is the interval between two checks of the configuration
+	// reload status.
+	checkInterval = time.Minute
+
+	// reloaderPort is the port of the config-reloader sidecar (reloader-web).
+	reloaderPort = 8080
+
+	// logTailLines is the number of log lines inspected to identify the
+	// cause of a reload failure.
+	logTailLines = 200
+
+	requestTimeout = 10 * time.Second
+)
+
+type resourceManager struct {
+	client.Client
+	// apiReader reads the Prometheus pods and the certificate authority of
+	// the stacks without caching all the pods and Secrets of the cluster.
+	apiReader client.Reader
+	logger    logr.Logger
+	// newClient returns a metrics client for the Prometheus pod reachable at
+	// host.
+	newClient func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (metricsClient, error)
+	// newReloaderClient returns a metrics client for the config-reloader
+	// sidecar reachable at host.
+	newReloaderClient func(host string) (metricsClient, error)
+	// readLogs returns the last lines of the Prometheus container logs.
+	readLogs func(ctx context.Context, pod *corev1.Pod) (string, error)
+}
+
+// metricsClient scrapes the metrics of a pod.
+type metricsClient interface {
+	Metrics(ctx context.Context) (map[string]*dto.MetricFamily, error)
+}
+
+// RBAC for reporting the configuration reload status
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusrules,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	rm := &resourceManager{
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		logger:    ctrl.Log.WithName("monitoring-stack-reload"),
+		newClient: func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (metricsClient, error) {
+			return prometheus.NewClientForStack(ctx, c, ms, host)
+		},
+		newReloaderClient: func(host string) (metricsClient, error) {
+			return prometheus.NewClient("http://"+net.JoinHostPort(host, strconv.Itoa(reloaderPort)), nil)
+		},
+		readLogs: func(ctx context.Context, pod *corev1.Pod) (string, error) {
+			r, err := kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: "prometheus",
+				TailLines: ptr.To(int64(logTailLines)),
+			}).Stream(ctx)
+			if err != nil {
+				return "", err
+			}
+			defer r.Close()
+
+			b, err := io.ReadAll(r)
+			return string(b), err
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("monitoring-stack-reload").
+		For(&stack.MonitoringStack{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("stack", req.NamespacedName)
+
+	ms := &stack.MonitoringStack{}
+	err := rm.Get(ctx, req.NamespacedName, ms)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !ms.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if ms.Suspended() {
+		return ctrl.Result{RequeueAfter: checkInterval}, nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := rm.apiReader.List(ctx, pods,
+		client.InNamespace(ms.Namespace),
+		client.MatchingLabels{
+			"app.kubernetes.io/name":      "prometheus",
+			"operator.prometheus.io/name": ms.Name,
+		},
+	); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var (
+		running  int
+		failures []reloadFailure
+		checkErr error
+	)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		running++
+
+		f, err := rm.checkPod(ctx, ms, pod)
+		if err != nil {
+			checkErr = errors.Join(checkErr, err)
+			continue
+		}
+		if f != nil {
+			failures = append(failures, *f)
+		}
+	}
+
+	cond := configurationLoadedCondition(ms, running, failures, checkErr)
+	conditions, changed := setCondition(ms.Status.Conditions, cond)
+	if changed {
+		logger.Info("Configuration reload status changed", "status", cond.Status, "reason", cond.Reason)
+
+		// The conditions are an atomic list shared with the monitoring-stack
+		// controller: the optimistic lock avoids overwriting its changes.
+		patch := client.MergeFromWithOptions(ms.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		ms.Status.Conditions = conditions
+		if err := rm.Status().Patch(ctx, ms, patch); err != nil {
+			if apierrors.IsConflict(err) {
+				return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: checkInterval}, nil
+}
+
+// checkPod returns a non-nil failure if Prometheus or its config-reloader
+// failed to load the latest configuration.
+func (rm resourceManager) checkPod(ctx context.Context, ms *stack.MonitoringStack, pod *corev1.Pod) (*reloadFailure, error) {
+	logger := rm.logger.WithValues("pod", client.ObjectKeyFromObject(pod))
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	promClient, err := rm.newClient(ctx, rm.apiReader, ms, pod.Status.PodIP)
+	if err != nil {
+		return nil, err
+	}
+
+	families, err := promClient.Metrics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the metrics of pod %s: %w", pod.Name, err)
+	}
+
+	var f *reloadFailure
+	if !lastReloadSuccessful(families, prometheusReloadMetric) {
+		f = &reloadFailure{pod: pod.Name, component: "prometheus"}
+	} else {
+		// The config-reloader metrics are best effort: the reload status
+		// reported by Prometheus is authoritative.
+		reloaderClient, err := rm.newReloaderClient(pod.Status.PodIP)
+		if err == nil {
+			families, err = reloaderClient.Metrics(ctx)
+		}
+		if err != nil {
+			logger.V(3).Info("failed to get config-reloader metrics", "err", err)
+		} else if !lastReloadSuccessful(families, reloaderReloadMetric) {
+			f = &reloadFailure{pod: pod.Name, component: "config-reloader"}
+		}
+	}
+
+	if f == nil {
+		return nil, nil
+	}
+
+	logs, err := rm.readLogs(ctx, pod)
+	if err != nil {
+		logger.Info("failed to read Prometheus logs", "err", err)
+		return f, nil
+	}
+
+	msg := reloadError(logs)
+	if msg == "" {
+		return f, nil
+	}
+	f.err = truncate(msg, maxErrorLength)
+
+	var rules []*monv1.PrometheusRule
+	if ruleFileRE.MatchString(msg) {
+		rules, err = rm.selectedRules(ctx, ms)
+		if err != nil {
+			logger.Info("failed to list PrometheusRules", "err", err)
+		}
+	}
+	f.object = offendingObject(msg, rules)
+
+	return f, nil
+}
+
+// selectedRules returns the PrometheusRules selected by the stack, the only
+// ones which can be loaded by its Prometheus pods.
+func (rm resourceManager) selectedRules(ctx context.Context, ms *stack.MonitoringStack) ([]*monv1.PrometheusRule, error) {
+	if ms.Spec.ResourceSelector == nil {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(ms.Spec.ResourceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource selector: %w", err)
+	}
+
+	namespaces, err := selection.StackNamespaces(ctx, rm, ms)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*monv1.PrometheusRule
+	for _, ns := range namespaces {
+		list := &monv1.PrometheusRuleList{}
+		if err := rm.List(ctx, list, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		rules = append(rules, list.Items...)
+	}
+	return rules, nil
+}
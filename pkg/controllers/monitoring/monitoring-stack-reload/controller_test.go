@@ -0,0 +1,186 @@
+package monitoringstackreload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	dto "github.com/prometheus/client_model/go"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+)
+
+// fakeMetrics exposes a single "last reload successful" gauge.
+type fakeMetrics struct {
+	name  string
+	value float64
+	err   error
+}
+
+func (f fakeMetrics) Metrics(context.Context) (map[string]*dto.MetricFamily, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string]*dto.MetricFamily{
+		f.name: {
+			Name:   ptr.To(f.name),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: ptr.To(f.value)}}},
+		},
+	}, nil
+}
+
+func newTestManager(t *testing.T, prom, reloader fakeMetrics, objs ...client.Object) *resourceManager {
+	t.Helper()
+
+	c := testutil.NewClientBuilder(objs...).Build()
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		logger:    logr.Discard(),
+		newClient: func(context.Context, client.Reader, *stack.MonitoringStack, string) (metricsClient, error) {
+			return prom, nil
+		},
+		newReloaderClient: func(string) (metricsClient, error) {
+			return reloader, nil
+		},
+		readLogs: func(context.Context, *corev1.Pod) (string, error) {
+			return ruleFailureLogs, nil
+		},
+	}
+}
+
+func newTestObjects() []client.Object {
+	return []client.Object{
+		&stack.MonitoringStack{
+			ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns", Generation: 3},
+			Spec: stack.MonitoringStackSpec{
+				ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus-ms-0",
+				Namespace: "ns",
+				Labels: map[string]string{
+					"app.kubernetes.io/name":      "prometheus",
+					"operator.prometheus.io/name": "ms",
+				},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+		},
+		&monv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      "bad-rules",
+				UID:       "1b2c3d4e-0000-0000-0000-000000000001",
+				Labels:    map[string]string{"app": "demo"},
+			},
+		},
+	}
+}
+
+func reconcileCondition(t *testing.T, rm *resourceManager) stack.Condition {
+	t.Helper()
+
+	res, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "ms"}})
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, checkInterval)
+
+	ms := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "ms"}, ms))
+	for _, c := range ms.Status.Conditions {
+		if c.Type == stack.ConfigurationLoadedCondition {
+			return c
+		}
+	}
+
+	t.Fatal("ConfigurationLoaded condition not found")
+	return stack.Condition{}
+}
+
+func TestReconcile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		prom     fakeMetrics
+		reloader fakeMetrics
+		objs     []client.Object
+		expected stack.Condition
+	}{
+		{
+			name:     "configuration loaded",
+			prom:     fakeMetrics{name: prometheusReloadMetric, value: 1},
+			reloader: fakeMetrics{name: reloaderReloadMetric, value: 1},
+			objs:     newTestObjects(),
+			expected: stack.Condition{Status: stack.ConditionTrue, Reason: ConfigurationLoadedReason, Message: ConfigurationLoadedMessage, ObservedGeneration: 3},
+		},
+		{
+			name:     "prometheus reload failure",
+			prom:     fakeMetrics{name: prometheusReloadMetric, value: 0},
+			reloader: fakeMetrics{name: reloaderReloadMetric, value: 1},
+			objs:     newTestObjects(),
+			expected: stack.Condition{
+				Status:             stack.ConditionFalse,
+				Reason:             ReloadFailedReason,
+				Message:            `Prometheus pod prometheus-ms-0 failed to reload its configuration because of PrometheusRule ns/bad-rules: /etc/prometheus/rules/prometheus-ms-rulefiles-0/ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: group "app", rule 1, "HighLatency": could not parse expression: 1:10: parse error: unexpected "}"`,
+				ObservedGeneration: 3,
+			},
+		},
+		{
+			// Only the rules selected by the stack are looked up.
+			name:     "prometheus reload failure with unselected rule",
+			prom:     fakeMetrics{name: prometheusReloadMetric, value: 0},
+			reloader: fakeMetrics{name: reloaderReloadMetric, value: 1},
+			objs: func() []client.Object {
+				objs := newTestObjects()
+				objs[2].SetLabels(nil)
+				return objs
+			}(),
+			expected: stack.Condition{
+				Status:             stack.ConditionFalse,
+				Reason:             ReloadFailedReason,
+				Message:            `Prometheus pod prometheus-ms-0 failed to reload its configuration because of rule file ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: /etc/prometheus/rules/prometheus-ms-rulefiles-0/ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: group "app", rule 1, "HighLatency": could not parse expression: 1:10: parse error: unexpected "}"`,
+				ObservedGeneration: 3,
+			},
+		},
+		{
+			name:     "config-reloader failure",
+			prom:     fakeMetrics{name: prometheusReloadMetric, value: 1},
+			reloader: fakeMetrics{name: reloaderReloadMetric, value: 0},
+			objs:     newTestObjects(),
+			expected: stack.Condition{
+				Status:             stack.ConditionFalse,
+				Reason:             ReloadFailedReason,
+				Message:            `the config-reloader of Prometheus pod prometheus-ms-0 failed to apply the configuration because of PrometheusRule ns/bad-rules: /etc/prometheus/rules/prometheus-ms-rulefiles-0/ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: group "app", rule 1, "HighLatency": could not parse expression: 1:10: parse error: unexpected "}"`,
+				ObservedGeneration: 3,
+			},
+		},
+		{
+			name:     "prometheus unreachable",
+			prom:     fakeMetrics{err: errors.New("connection refused")},
+			objs:     newTestObjects(),
+			expected: stack.Condition{Status: stack.ConditionUnknown, Reason: CannotCheckReloadReason, Message: "failed to get the metrics of pod prometheus-ms-0: connection refused", ObservedGeneration: 3},
+		},
+		{
+			name:     "no running pod",
+			objs:     newTestObjects()[:1],
+			expected: stack.Condition{Status: stack.ConditionUnknown, Reason: NoRunningPodReason, Message: NoRunningPodMessage, ObservedGeneration: 3},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rm := newTestManager(t, tc.prom, tc.reloader, tc.objs...)
+			c := reconcileCondition(t, rm)
+			assert.Check(t, tc.expected.Equal(c), "got %v", c)
+		})
+	}
+}
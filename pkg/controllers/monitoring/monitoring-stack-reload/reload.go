@@ -0,0 +1,208 @@
+package monitoringstackreload
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	ConfigurationLoadedReason  = "ConfigurationLoaded"
+	ReloadFailedReason         = "ConfigurationReloadFailed"
+	NoRunningPodReason         = "NoRunningPrometheusPod"
+	CannotCheckReloadReason    = "CannotCheckConfigurationReload"
+	ConfigurationLoadedMessage = "Prometheus successfully loaded its configuration"
+	NoRunningPodMessage        = "No running Prometheus pod"
+
+	prometheusReloadMetric = "prometheus_config_last_reload_successful"
+	reloaderReloadMetric   = "reloader_last_reload_successful"
+
+	// maxErrorLength bounds the length of the error reported in the
+	// condition message.
+	maxErrorLength = 512
+)
+
+var (
+	// ruleFileRE matches the path of the rule files generated by
+	// prometheus-operator (<namespace>-<name>-<uid>.yaml).
+	ruleFileRE = regexp.MustCompile(`/etc/prometheus/rules/[^/\s"]+/([^/\s":]+)\.yaml`)
+
+	// scrapeJobRE matches the names of the scrape jobs generated by
+	// prometheus-operator (<kind>/<namespace>/<name>[/<endpoint>]).
+	scrapeJobRE = regexp.MustCompile(`\b(serviceMonitor|podMonitor|probe|scrapeConfig)/([a-z0-9][a-z0-9.-]*)/([a-z0-9][a-z0-9.-]*)`)
+
+	errFieldRE = regexp.MustCompile(`\berr="((?:[^"\\]|\\.)*)"`)
+
+	scrapeJobKinds = map[string]string{
+		"serviceMonitor": monv1.ServiceMonitorsKind,
+		"podMonitor":     monv1.PodMonitorsKind,
+		"probe":          monv1.ProbesKind,
+		"scrapeConfig":   monv1alpha1.ScrapeConfigsKind,
+	}
+)
+
+// reloadFailure describes a Prometheus pod which failed to load its
+// configuration.
+type reloadFailure struct {
+	pod string
+	// component is either "prometheus" or "config-reloader".
+	component string
+	// object identifies the offending object, if known.
+	object string
+	// err is the error logged by Prometheus, if any.
+	err string
+}
+
+func (f reloadFailure) String() string {
+	var sb strings.Builder
+	if f.component == "config-reloader" {
+		fmt.Fprintf(&sb, "the config-reloader of Prometheus pod %s failed to apply the configuration", f.pod)
+	} else {
+		fmt.Fprintf(&sb, "Prometheus pod %s failed to reload its configuration", f.pod)
+	}
+	if f.object != "" {
+		fmt.Fprintf(&sb, " because of %s", f.object)
+	}
+	if f.err != "" {
+		fmt.Fprintf(&sb, ": %s", f.err)
+	}
+	return sb.String()
+}
+
+// lastReloadSuccessful returns the value of the given "last reload
+// successful" gauge. It returns true if the metric isn't exposed.
+func lastReloadSuccessful(families map[string]*dto.MetricFamily, name string) bool {
+	mf, found := families[name]
+	if !found {
+		return true
+	}
+
+	for _, m := range mf.GetMetric() {
+		if m.GetGauge().GetValue() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// reloadError returns the last error logged by Prometheus about loading its
+// configuration or rules. Errors referencing a rule file or a scrape job are
+// preferred since they identify the offending object.
+func reloadError(logs string) string {
+	var fallback string
+	lines := strings.Split(logs, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if !strings.Contains(line, "level=error") {
+			continue
+		}
+
+		msg := line
+		if m := errFieldRE.FindStringSubmatch(line); m != nil {
+			if s, err := strconv.Unquote(`"` + m[1] + `"`); err == nil {
+				msg = s
+			} else {
+				msg = m[1]
+			}
+		}
+
+		if ruleFileRE.MatchString(msg) || scrapeJobRE.MatchString(msg) {
+			return msg
+		}
+		if fallback == "" && (strings.Contains(line, "reloading config") || strings.Contains(line, "loading groups failed")) {
+			fallback = msg
+		}
+	}
+
+	return fallback
+}
+
+// offendingObject returns a reference to the object identified by the error
+// message. Rule files are resolved to PrometheusRules by UID.
+func offendingObject(msg string, rules []*monv1.PrometheusRule) string {
+	if m := ruleFileRE.FindStringSubmatch(msg); m != nil {
+		for _, r := range rules {
+			if fmt.Sprintf("%s-%s-%s", r.Namespace, r.Name, r.UID) == m[1] {
+				return fmt.Sprintf("%s %s/%s", monv1.PrometheusRuleKind, r.Namespace, r.Name)
+			}
+		}
+		return fmt.Sprintf("rule file %s.yaml", m[1])
+	}
+
+	if m := scrapeJobRE.FindStringSubmatch(msg); m != nil {
+		return fmt.Sprintf("%s %s/%s", scrapeJobKinds[m[1]], m[2], m[3])
+	}
+
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// configurationLoadedCondition returns the ConfigurationLoaded condition for
+// the given check results.
+func configurationLoadedCondition(ms *stack.MonitoringStack, running int, failures []reloadFailure, checkErr error) stack.Condition {
+	c := stack.Condition{
+		Type:               stack.ConfigurationLoadedCondition,
+		Status:             stack.ConditionTrue,
+		Reason:             ConfigurationLoadedReason,
+		Message:            ConfigurationLoadedMessage,
+		ObservedGeneration: ms.Generation,
+	}
+
+	switch {
+	case len(failures) > 0:
+		msgs := make([]string, 0, len(failures))
+		for _, f := range failures {
+			msgs = append(msgs, f.String())
+		}
+		c.Status = stack.ConditionFalse
+		c.Reason = ReloadFailedReason
+		c.Message = strings.Join(msgs, "; ")
+	case running == 0:
+		c.Status = stack.ConditionUnknown
+		c.Reason = NoRunningPodReason
+		c.Message = NoRunningPodMessage
+	case checkErr != nil:
+		c.Status = stack.ConditionUnknown
+		c.Reason = CannotCheckReloadReason
+		c.Message = checkErr.Error()
+	}
+
+	return c
+}
+
+// setCondition replaces (or adds) the condition in the list. The transition
+// time is only updated when the status changes.
+func setCondition(conditions []stack.Condition, c stack.Condition) ([]stack.Condition, bool) {
+	conditions = append([]stack.Condition(nil), conditions...)
+	for i, existing := range conditions {
+		if existing.Type != c.Type {
+			continue
+		}
+		if existing.Equal(c) {
+			return conditions, false
+		}
+		c.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != c.Status {
+			c.LastTransitionTime = metav1.Now()
+		}
+		conditions[i] = c
+		return conditions, true
+	}
+
+	c.LastTransitionTime = metav1.Now()
+	return append(conditions, c), true
+}
@@ -0,0 +1,59 @@
+package monitoringstackreload
+
+import (
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const ruleFailureLogs = `ts=2024-06-01T12:00:00.000Z caller=main.go:1386 level=info msg="Loading configuration file" filename=/etc/prometheus/config_out/prometheus.env.yaml
+ts=2024-06-01T12:00:00.010Z caller=manager.go:1012 level=error component="rule manager" msg="loading groups failed" err="/etc/prometheus/rules/prometheus-ms-rulefiles-0/ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: group \"app\", rule 1, \"HighLatency\": could not parse expression: 1:10: parse error: unexpected \"}\""
+ts=2024-06-01T12:00:00.011Z caller=main.go:1395 level=error msg="Failed to apply configuration" err="error loading rules, previous rule set restored"
+ts=2024-06-01T12:00:00.012Z caller=main.go:1131 level=error msg="Error reloading config" err="one or more errors occurred while applying the new configuration (--config.file=\"/etc/prometheus/config_out/prometheus.env.yaml\")"
+`
+
+func TestReloadError(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		logs     string
+		expected string
+	}{
+		{
+			name:     "rule file",
+			logs:     ruleFailureLogs,
+			expected: `/etc/prometheus/rules/prometheus-ms-rulefiles-0/ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml: group "app", rule 1, "HighLatency": could not parse expression: 1:10: parse error: unexpected "}"`,
+		},
+		{
+			name:     "scrape job",
+			logs:     `ts=2024-06-01T12:00:00.012Z caller=main.go:1131 level=error msg="Error reloading config" err="couldn't load configuration (--config.file=\"/etc/prometheus/config_out/prometheus.env.yaml\"): parsing YAML file /etc/prometheus/config_out/prometheus.env.yaml: found multiple scrape configs with job name \"scrapeConfig/ns/duplicate\""`,
+			expected: `couldn't load configuration (--config.file="/etc/prometheus/config_out/prometheus.env.yaml"): parsing YAML file /etc/prometheus/config_out/prometheus.env.yaml: found multiple scrape configs with job name "scrapeConfig/ns/duplicate"`,
+		},
+		{
+			name:     "unidentified",
+			logs:     `ts=2024-06-01T12:00:00.012Z caller=main.go:1131 level=error msg="Error reloading config" err="out of memory"`,
+			expected: "out of memory",
+		},
+		{
+			name: "no error",
+			logs: `ts=2024-06-01T12:00:00.000Z caller=main.go:1386 level=info msg="Completed loading of configuration file"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, reloadError(tc.logs), tc.expected)
+		})
+	}
+}
+
+func TestOffendingObject(t *testing.T) {
+	rules := []*monv1.PrometheusRule{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "good", UID: "1b2c3d4e-0000-0000-0000-000000000000"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bad-rules", UID: "1b2c3d4e-0000-0000-0000-000000000001"}},
+	}
+
+	assert.Equal(t, offendingObject(reloadError(ruleFailureLogs), rules), "PrometheusRule ns/bad-rules")
+	assert.Equal(t, offendingObject(reloadError(ruleFailureLogs), nil), "rule file ns-bad-rules-1b2c3d4e-0000-0000-0000-000000000001.yaml")
+	assert.Equal(t, offendingObject(`error in job "serviceMonitor/app-ns/api/0"`, nil), "ServiceMonitor app-ns/api")
+	assert.Equal(t, offendingObject("out of memory", rules), "")
+}
@@ -0,0 +1,198 @@
+package monitoringstacksnapshot
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringstack "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack"
+)
+
+const (
+	// prometheusDataMountPath and prometheusDataSubPath mirror how
+	// prometheus-operator mounts the storage volume in the Prometheus pods.
+	prometheusDataMountPath = "/prometheus"
+	prometheusDataSubPath   = "prometheus-db"
+
+	destinationMountPath = "/snapshot"
+	objstoreMountPath    = "/etc/thanos/objstore"
+)
+
+// prometheusPodName returns the name of the Prometheus pod snapshots are taken from.
+func prometheusPodName(ms *stack.MonitoringStack) string {
+	return monitoringstack.PrometheusPodName(ms, 0)
+}
+
+func snapshotJobName(snap *stack.MonitoringStackSnapshot) string {
+	return "snapshot-" + snap.Name
+}
+
+func snapshotSourcePath(snap *stack.MonitoringStackSnapshot) string {
+	return path.Join(prometheusDataMountPath, "snapshots", snap.Status.SnapshotName)
+}
+
+func destinationPath(snap *stack.MonitoringStackSnapshot) string {
+	p := snap.Spec.Destination.PersistentVolumeClaim.Path
+	if p == "" {
+		p = snap.Name
+	}
+	return p
+}
+
+// snapshotLocation returns a human readable location of the copied snapshot.
+func snapshotLocation(snap *stack.MonitoringStackSnapshot) string {
+	dest := snap.Spec.Destination
+	if dest.PersistentVolumeClaim != nil {
+		return fmt.Sprintf("pvc://%s/%s", dest.PersistentVolumeClaim.ClaimName, destinationPath(snap))
+	}
+	return fmt.Sprintf("objstore://%s/%s", dest.ObjectStorage.Config.Name, dest.ObjectStorage.Config.Key)
+}
+
+// copyScript returns the shell script run by the copy job. The script writes
+// the size of the snapshot (in KiB) to the termination log so that it can be
+// reported in the status.
+func copyScript(snap *stack.MonitoringStackSnapshot) string {
+	src := snapshotSourcePath(snap)
+
+	if snap.Spec.Destination.PersistentVolumeClaim != nil {
+		dst := path.Join(destinationMountPath, destinationPath(snap))
+		return fmt.Sprintf(`set -e
+mkdir -p %[2]q
+cp -R %[1]q/. %[2]q/
+echo "$(du -sk %[2]q | cut -f1)Ki" > /dev/termination-log
+rm -rf %[1]q
+`, src, dst)
+	}
+
+	// The snapshot contains the blocks compacted by Prometheus which are
+	// skipped by the shipper unless --shipper.upload-compacted is set.
+	cfg := snap.Spec.Destination.ObjectStorage.Config
+	return fmt.Sprintf(`set -e
+size="$(du -sk %[1]q | cut -f1)Ki"
+thanos tools bucket upload-blocks --path=%[1]q --objstore.config-file=%[2]q --label='snapshot="%[3]s/%[4]s"' --shipper.upload-compacted
+echo "$size" > /dev/termination-log
+rm -rf %[1]q
+`, src, filepath.Join(objstoreMountPath, cfg.Key), snap.Namespace, snap.Name)
+}
+
+// newCopyJob returns the Job copying the snapshot out of the Prometheus data
+// volume. The job is scheduled on the node running the Prometheus pod so that
+// it can mount ReadWriteOnce volumes.
+func newCopyJob(snap *stack.MonitoringStackSnapshot, ms *stack.MonitoringStack, image string) *batchv1.Job {
+	name := snapshotJobName(snap)
+	labels := map[string]string{
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/component":  "snapshot",
+		"app.kubernetes.io/part-of":    ms.Name,
+		"app.kubernetes.io/managed-by": "observability-operator",
+	}
+
+	volumes := []corev1.Volume{{
+		Name: "prometheus-data",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: monitoringstack.PrometheusPVCName(ms, snap.Status.Pod),
+			},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      "prometheus-data",
+		MountPath: prometheusDataMountPath,
+		SubPath:   prometheusDataSubPath,
+	}}
+
+	dest := snap.Spec.Destination
+	if dest.PersistentVolumeClaim != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "destination",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: dest.PersistentVolumeClaim.ClaimName,
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "destination",
+			MountPath: destinationMountPath,
+		})
+	} else {
+		volumes = append(volumes, corev1.Volume{
+			Name: "objstore-config",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: dest.ObjectStorage.Config.Name,
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "objstore-config",
+			MountPath: objstoreMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: snap.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To(int32(2)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Affinity: &corev1.Affinity{
+						PodAffinity: &corev1.PodAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+								TopologyKey: "kubernetes.io/hostname",
+								LabelSelector: &metav1.LabelSelector{
+									MatchLabels: map[string]string{
+										"statefulset.kubernetes.io/pod-name": snap.Status.Pod,
+									},
+								},
+							}},
+						},
+					},
+					NodeSelector: ms.Spec.NodeSelector,
+					Tolerations:  ms.Spec.Tolerations,
+					SecurityContext: &corev1.PodSecurityContext{
+						FSGroup:      ptr.To(monitoringstack.PrometheusUserFSGroupID),
+						RunAsNonRoot: ptr.To(true),
+						RunAsUser:    ptr.To(monitoringstack.PrometheusUserFSGroupID),
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{{
+						Name:                     "copy",
+						Image:                    image,
+						Command:                  []string{"/bin/sh", "-c", copyScript(snap)},
+						VolumeMounts:             mounts,
+						TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: ptr.To(false),
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{"ALL"},
+							},
+						},
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package monitoringstacksnapshot
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestNewCopyJob(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		destination stack.SnapshotDestination
+		volume      string
+		location    string
+		command     string
+	}{
+		{
+			name: "pvc",
+			destination: stack.SnapshotDestination{
+				PersistentVolumeClaim: &stack.SnapshotPersistentVolumeClaim{ClaimName: "backup", Path: "daily/1"},
+			},
+			volume:   "destination",
+			location: "pvc://backup/daily/1",
+			command:  `cp -R "/prometheus/snapshots/20240101T000000Z-1234"/. "/snapshot/daily/1"/`,
+		},
+		{
+			name: "object storage",
+			destination: stack.SnapshotDestination{
+				ObjectStorage: &stack.SnapshotObjectStorage{
+					Config: stack.SecretKeySelector{Name: "thanos-objstore", Key: "objstore.yaml"},
+				},
+			},
+			volume:   "objstore-config",
+			location: "objstore://thanos-objstore/objstore.yaml",
+			command:  `thanos tools bucket upload-blocks --path="/prometheus/snapshots/20240101T000000Z-1234" --objstore.config-file="/etc/thanos/objstore/objstore.yaml" --label='snapshot="ns/snap"' --shipper.upload-compacted`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			snap := &stack.MonitoringStackSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "snap", Namespace: "ns"},
+				Spec: stack.MonitoringStackSnapshotSpec{
+					MonitoringStack: "ms",
+					Destination:     tc.destination,
+				},
+				Status: stack.MonitoringStackSnapshotStatus{
+					Pod:          "prometheus-ms-0",
+					SnapshotName: "20240101T000000Z-1234",
+				},
+			}
+
+			job := newCopyJob(snap, ms, "thanos")
+			assert.Equal(t, job.Name, "snapshot-snap")
+			assert.Equal(t, job.Namespace, "ns")
+
+			pod := job.Spec.Template.Spec
+			assert.Equal(t, len(pod.Volumes), 2)
+			assert.Equal(t, pod.Volumes[0].PersistentVolumeClaim.ClaimName, "prometheus-ms-db-prometheus-ms-0")
+			assert.Equal(t, pod.Volumes[1].Name, tc.volume)
+			assert.Equal(t, pod.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0].LabelSelector.MatchLabels["statefulset.kubernetes.io/pod-name"], "prometheus-ms-0")
+
+			script := pod.Containers[0].Command[2]
+			assert.Assert(t, strings.Contains(script, tc.command), script)
+			assert.Assert(t, strings.Contains(script, "/dev/termination-log"), script)
+			assert.Equal(t, snapshotLocation(snap), tc.location)
+		})
+	}
+}
@@ -0,0 +1,408 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstacksnapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	monitoringstack "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+const (
+	finalizerName = "monitoring.observability.openshift.io/snapshot-finalizer"
+
+	// snapshotTimeout is the maximum duration to wait for Prometheus to
+	// take the snapshot once the admin API has been enabled.
+	snapshotTimeout = 10 * time.Minute
+
+	retryInterval = 5 * time.Second
+)
+
+type resourceManager struct {
+	client.Client
+	// apiReader reads the pods, the PVCs and the certificate authority of
+	// the stacks without caching all the pods, PVCs and Secrets of the
+	// cluster.
+	apiReader client.Reader
+	scheme    *runtime.Scheme
+	logger    logr.Logger
+	thanos    ThanosConfiguration
+	newClient func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (snapshotter, error)
+}
+
+// snapshotter takes TSDB snapshots through the Prometheus admin API.
+type snapshotter interface {
+	Snapshot(ctx context.Context, skipHead bool) (string, error)
+}
+
+type ThanosConfiguration struct {
+	Image string
+}
+
+// Options allows for controller options to be set
+type Options struct {
+	Thanos ThanosConfiguration
+}
+
+// RBAC for managing monitoring stack snapshots
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacksnapshots,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacksnapshots/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacksnapshots/finalizers,verbs=update
+
+// RBAC for toggling the Prometheus admin API
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheuses,verbs=get;patch
+
+// RBAC for copying snapshots
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods;persistentvolumeclaims,verbs=get;list
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
+	rm := &resourceManager{
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		scheme:    mgr.GetScheme(),
+		logger:    ctrl.Log.WithName("monitoring-stack-snapshot"),
+		thanos:    opts.Thanos,
+		newClient: func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (snapshotter, error) {
+			return prometheus.NewClientForStack(ctx, c, ms, host)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&stack.MonitoringStackSnapshot{}).
+		Owns(&batchv1.Job{}).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("snapshot", req.NamespacedName)
+	logger.Info("Reconciling monitoring stack snapshot")
+
+	snap := &stack.MonitoringStackSnapshot{}
+	err := rm.Get(ctx, req.NamespacedName, snap)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Make sure that the admin API isn't left enabled once the snapshot is
+	// deleted or has reached a terminal phase.
+	if !snap.DeletionTimestamp.IsZero() ||
+		snap.Status.Phase == stack.SnapshotCompleted ||
+		snap.Status.Phase == stack.SnapshotFailed {
+		return ctrl.Result{}, rm.finalize(ctx, snap)
+	}
+
+	if !slices.Contains(snap.Finalizers, finalizerName) {
+		snap.Finalizers = append(snap.Finalizers, finalizerName)
+		if err := rm.Update(ctx, snap); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	ms := &stack.MonitoringStack{}
+	if err := rm.Get(ctx, client.ObjectKey{Namespace: snap.Namespace, Name: snap.Spec.MonitoringStack}, ms); err != nil {
+		if apierrors.IsNotFound(err) {
+			return rm.fail(ctx, snap, fmt.Sprintf("MonitoringStack %q not found", snap.Spec.MonitoringStack))
+		}
+		return ctrl.Result{}, err
+	}
+
+	switch snap.Status.Phase {
+	case "", stack.SnapshotPending:
+		return rm.start(ctx, snap, ms)
+	case stack.SnapshotSnapshotting:
+		return rm.takeSnapshot(ctx, snap, ms)
+	case stack.SnapshotCopying:
+		return rm.copySnapshot(ctx, snap, ms)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// start validates that the stack can be snapshotted and enables the admin API
+// of its Prometheus instance.
+func (rm resourceManager) start(ctx context.Context, snap *stack.MonitoringStackSnapshot, ms *stack.MonitoringStack) (ctrl.Result, error) {
+	if ms.Spec.PrometheusConfig == nil || ms.Spec.PrometheusConfig.PersistentVolumeClaim == nil {
+		return rm.fail(ctx, snap, "the MonitoringStack has no persistent storage configured for Prometheus")
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	pod := prometheusPodName(ms)
+	if err := rm.apiReader.Get(ctx, client.ObjectKey{Namespace: ms.Namespace, Name: monitoringstack.PrometheusPVCName(ms, pod)}, pvc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return rm.fail(ctx, snap, fmt.Sprintf("PersistentVolumeClaim of Prometheus pod %q not found", pod))
+		}
+		return ctrl.Result{}, err
+	}
+
+	if snap.Spec.Destination.PersistentVolumeClaim != nil {
+		claim := snap.Spec.Destination.PersistentVolumeClaim.ClaimName
+		if err := rm.apiReader.Get(ctx, client.ObjectKey{Namespace: snap.Namespace, Name: claim}, &corev1.PersistentVolumeClaim{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return rm.fail(ctx, snap, fmt.Sprintf("destination PersistentVolumeClaim %q not found", claim))
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := rm.enableAdminAPI(ctx, snap, ms); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := metav1.Now()
+	snap.Status.Phase = stack.SnapshotSnapshotting
+	snap.Status.Message = "Waiting for Prometheus to enable the admin API"
+	snap.Status.Pod = pod
+	snap.Status.StartTime = &now
+	if err := rm.Status().Update(ctx, snap); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Prometheus needs to be restarted by prometheus-operator before the admin
+	// API becomes available.
+	return ctrl.Result{RequeueAfter: retryInterval}, nil
+}
+
+// takeSnapshot calls the admin API until the snapshot is taken and then
+// starts the copy job.
+func (rm resourceManager) takeSnapshot(ctx context.Context, snap *stack.MonitoringStackSnapshot, ms *stack.MonitoringStack) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("snapshot", client.ObjectKeyFromObject(snap))
+
+	if snap.Status.StartTime != nil && time.Since(snap.Status.StartTime.Time) > snapshotTimeout {
+		return rm.fail(ctx, snap, fmt.Sprintf("timed out after %s waiting for the Prometheus admin API", snapshotTimeout))
+	}
+
+	if snap.Status.SnapshotName == "" {
+		pod := &corev1.Pod{}
+		if err := rm.apiReader.Get(ctx, client.ObjectKey{Namespace: ms.Namespace, Name: snap.Status.Pod}, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				return ctrl.Result{RequeueAfter: retryInterval}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if pod.Status.PodIP == "" || !podReady(pod) {
+			logger.V(3).Info("waiting for Prometheus pod to be ready", "pod", pod.Name)
+			return ctrl.Result{RequeueAfter: retryInterval}, nil
+		}
+
+		promClient, err := rm.newClient(ctx, rm.apiReader, ms, pod.Status.PodIP)
+		if err != nil {
+			return rm.fail(ctx, snap, err.Error())
+		}
+
+		name, err := promClient.Snapshot(ctx, snap.Spec.SkipHead)
+		if err != nil {
+			// The admin API returns "unavailable" until Prometheus has been
+			// restarted with the --web.enable-admin-api flag.
+			var apiErr *prometheus.APIError
+			if errors.As(err, &apiErr) && apiErr.Type != "unavailable" {
+				return rm.fail(ctx, snap, err.Error())
+			}
+			logger.V(3).Info("snapshot not yet possible", "err", err)
+			return ctrl.Result{RequeueAfter: retryInterval}, nil
+		}
+
+		now := metav1.Now()
+		snap.Status.SnapshotName = name
+		snap.Status.SnapshotTime = &now
+		snap.Status.Phase = stack.SnapshotCopying
+		snap.Status.Message = "Copying the snapshot to its destination"
+		if err := rm.Status().Update(ctx, snap); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return rm.copySnapshot(ctx, snap, ms)
+}
+
+// copySnapshot ensures that the copy job exists and reports its outcome.
+func (rm resourceManager) copySnapshot(ctx context.Context, snap *stack.MonitoringStackSnapshot, ms *stack.MonitoringStack) (ctrl.Result, error) {
+	job := newCopyJob(snap, ms, rm.thanos.Image)
+	if err := reconciler.NewUpdater(job, snap).Reconcile(ctx, rm, rm.scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch c.Type {
+		case batchv1.JobComplete:
+			return rm.complete(ctx, snap, job)
+		case batchv1.JobFailed:
+			return rm.fail(ctx, snap, fmt.Sprintf("copy job %q failed: %s", job.Name, c.Message))
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (rm resourceManager) complete(ctx context.Context, snap *stack.MonitoringStackSnapshot, job *batchv1.Job) (ctrl.Result, error) {
+	size, err := rm.copiedSize(ctx, job)
+	if err != nil {
+		rm.logger.Info("failed to determine the snapshot size", "err", err)
+	}
+
+	now := metav1.Now()
+	snap.Status.Phase = stack.SnapshotCompleted
+	snap.Status.Message = ""
+	snap.Status.Location = snapshotLocation(snap)
+	snap.Status.Size = size
+	snap.Status.CompletionTime = &now
+	if err := rm.Status().Update(ctx, snap); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, rm.finalize(ctx, snap)
+}
+
+// copiedSize returns the size of the copied snapshot as reported in the
+// termination message of the successful job pod.
+func (rm resourceManager) copiedSize(ctx context.Context, job *batchv1.Job) (*resource.Quantity, error) {
+	pods := &corev1.PodList{}
+	if err := rm.apiReader.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels(job.Spec.Template.Labels)); err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Terminated == nil {
+				continue
+			}
+			q, err := resource.ParseQuantity(strings.TrimSpace(cs.State.Terminated.Message))
+			if err != nil {
+				return nil, fmt.Errorf("invalid size %q reported by pod %s: %w", cs.State.Terminated.Message, pod.Name, err)
+			}
+			return &q, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no succeeded pod found for job %s", job.Name)
+}
+
+func (rm resourceManager) fail(ctx context.Context, snap *stack.MonitoringStackSnapshot, msg string) (ctrl.Result, error) {
+	now := metav1.Now()
+	snap.Status.Phase = stack.SnapshotFailed
+	snap.Status.Message = msg
+	snap.Status.CompletionTime = &now
+	if err := rm.Status().Update(ctx, snap); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, rm.finalize(ctx, snap)
+}
+
+// finalize disables the admin API (if needed) and removes the finalizer.
+func (rm resourceManager) finalize(ctx context.Context, snap *stack.MonitoringStackSnapshot) error {
+	if !slices.Contains(snap.Finalizers, finalizerName) {
+		return nil
+	}
+
+	if err := rm.releaseAdminAPI(ctx, snap); err != nil {
+		return err
+	}
+
+	snap.Finalizers = slices.DeleteFunc(snap.Finalizers, func(f string) bool {
+		return f == finalizerName
+	})
+	return rm.Update(ctx, snap)
+}
+
+// adminAPIFieldManager returns the server-side apply field manager owning the
+// enableAdminAPI field on behalf of the given snapshot. Using a dedicated field
+// manager per snapshot means that the field stays set as long as at least one
+// snapshot is in progress and that the monitoring stack controller (which
+// never sets it) doesn't revert it.
+func adminAPIFieldManager(snap *stack.MonitoringStackSnapshot) client.FieldOwner {
+	return client.FieldOwner("observability-operator/snapshot-" + string(snap.UID))
+}
+
+func newPrometheusApplyConfig(name, namespace string) *unstructured.Unstructured {
+	prom := &unstructured.Unstructured{}
+	prom.SetGroupVersionKind(monv1.SchemeGroupVersion.WithKind(monv1.PrometheusesKind))
+	prom.SetName(name)
+	prom.SetNamespace(namespace)
+	return prom
+}
+
+func (rm resourceManager) enableAdminAPI(ctx context.Context, snap *stack.MonitoringStackSnapshot, ms *stack.MonitoringStack) error {
+	prom := newPrometheusApplyConfig(ms.Name, ms.Namespace)
+	if err := unstructured.SetNestedField(prom.Object, true, "spec", "enableAdminAPI"); err != nil {
+		return err
+	}
+
+	if err := rm.Patch(ctx, prom, client.Apply, client.ForceOwnership, adminAPIFieldManager(snap)); err != nil {
+		return fmt.Errorf("failed to enable the admin API of Prometheus %s/%s: %w", ms.Namespace, ms.Name, err)
+	}
+	return nil
+}
+
+// releaseAdminAPI removes the field ownership of the snapshot on the
+// enableAdminAPI field, which disables the admin API unless another snapshot
+// is still in progress.
+func (rm resourceManager) releaseAdminAPI(ctx context.Context, snap *stack.MonitoringStackSnapshot) error {
+	if snap.Status.StartTime == nil {
+		// The admin API was never enabled for this snapshot.
+		return nil
+	}
+
+	// Check that the Prometheus object exists since an apply request would
+	// otherwise create it.
+	key := client.ObjectKey{Namespace: snap.Namespace, Name: snap.Spec.MonitoringStack}
+	if err := rm.Get(ctx, key, &monv1.Prometheus{}); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	prom := newPrometheusApplyConfig(snap.Spec.MonitoringStack, snap.Namespace)
+	if err := rm.Patch(ctx, prom, client.Apply, client.ForceOwnership, adminAPIFieldManager(snap)); err != nil {
+		return fmt.Errorf("failed to release the admin API of Prometheus %s/%s: %w", snap.Namespace, snap.Spec.MonitoringStack, err)
+	}
+	return nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package monitoringstacksnapshot
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+// newTestManager returns a resourceManager backed by a fake client and a fake
+// Prometheus server answering the snapshot requests with the given handler.
+// The fake client doesn't support server-side apply so apply patches are
+// recorded and jobs are created (or read back if they already exist).
+func newTestManager(t *testing.T, handler http.HandlerFunc, objs ...client.Object) (*resourceManager, *[]client.Object) {
+	t.Helper()
+
+	var applied []client.Object
+	c := testutil.NewClientBuilder(objs...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.Patch(ctx, obj, patch, opts...)
+				}
+				applied = append(applied, obj.DeepCopyObject().(client.Object))
+				if _, ok := obj.(*batchv1.Job); ok {
+					if err := c.Create(ctx, obj); !apierrors.IsAlreadyExists(err) {
+						return err
+					}
+					return c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+				}
+				return nil
+			},
+		}).
+		Build()
+
+	url := testutil.NewPrometheusServer(t, handler)
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		scheme:    c.Scheme(),
+		logger:    logr.Discard(),
+		thanos:    ThanosConfiguration{Image: "thanos"},
+		newClient: func(_ context.Context, _ client.Reader, _ *stack.MonitoringStack, _ string) (snapshotter, error) {
+			return prometheus.NewClient(url, nil)
+		},
+	}, &applied
+}
+
+func newTestObjects() (*stack.MonitoringStack, *stack.MonitoringStackSnapshot, []client.Object) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{},
+			},
+		},
+	}
+	snap := &stack.MonitoringStackSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snap", Namespace: "ns", UID: "1234"},
+		Spec: stack.MonitoringStackSnapshotSpec{
+			MonitoringStack: "ms",
+			Destination: stack.SnapshotDestination{
+				PersistentVolumeClaim: &stack.SnapshotPersistentVolumeClaim{ClaimName: "backup"},
+			},
+		},
+	}
+
+	return ms, snap, []client.Object{
+		ms,
+		snap,
+		&monv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "prometheus-ms-db-prometheus-ms-0", Namespace: "ns"}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "ns"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "prometheus-ms-0", Namespace: "ns"},
+			Status: corev1.PodStatus{
+				PodIP:      "10.0.0.1",
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		},
+	}
+}
+
+func reconcileSnapshot(t *testing.T, rm *resourceManager, snap *stack.MonitoringStackSnapshot) *stack.MonitoringStackSnapshot {
+	t.Helper()
+
+	key := client.ObjectKeyFromObject(snap)
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	assert.NilError(t, err)
+
+	got := &stack.MonitoringStackSnapshot{}
+	assert.NilError(t, rm.Get(context.Background(), key, got))
+	return got
+}
+
+func TestReconcileSnapshot(t *testing.T) {
+	adminAPIEnabled := false
+	_, snap, objs := newTestObjects()
+	rm, applied := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if !adminAPIEnabled {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"admin APIs disabled"}`))
+			return
+		}
+		testutil.WriteSuccess(w, map[string]string{"name": "20240101T000000Z-1234"})
+	}, objs...)
+
+	// The first reconciliation enables the admin API.
+	got := reconcileSnapshot(t, rm, snap)
+	assert.Equal(t, got.Status.Phase, stack.SnapshotSnapshotting)
+	assert.Equal(t, got.Status.Pod, "prometheus-ms-0")
+	assert.Assert(t, got.Status.StartTime != nil)
+	assert.Equal(t, len(*applied), 1)
+	enabled, _, _ := unstructuredBool((*applied)[0], "spec", "enableAdminAPI")
+	assert.Assert(t, enabled)
+
+	// Prometheus hasn't been restarted yet.
+	got = reconcileSnapshot(t, rm, snap)
+	assert.Equal(t, got.Status.Phase, stack.SnapshotSnapshotting)
+
+	// The snapshot is taken and the copy job created.
+	adminAPIEnabled = true
+	got = reconcileSnapshot(t, rm, snap)
+	assert.Equal(t, got.Status.Phase, stack.SnapshotCopying)
+	assert.Equal(t, got.Status.SnapshotName, "20240101T000000Z-1234")
+
+	job := &batchv1.Job{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "snapshot-snap"}, job))
+
+	// The copy job succeeds.
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.NilError(t, rm.Status().Update(context.Background(), job))
+	assert.NilError(t, rm.Create(context.Background(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapshot-snap-abcde", Namespace: "ns", Labels: job.Spec.Template.Labels},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "2048Ki\n"}},
+			}},
+		},
+	}))
+
+	got = reconcileSnapshot(t, rm, snap)
+	assert.Equal(t, got.Status.Phase, stack.SnapshotCompleted)
+	assert.Equal(t, got.Status.Location, "pvc://backup/snap")
+	assert.Equal(t, got.Status.Size.String(), "2Mi")
+	assert.Assert(t, got.Status.CompletionTime != nil)
+	assert.Equal(t, len(got.Finalizers), 0)
+
+	// The admin API has been released.
+	last := (*applied)[len(*applied)-1]
+	_, found, _ := unstructuredBool(last, "spec", "enableAdminAPI")
+	assert.Assert(t, !found)
+}
+
+func TestReconcileSnapshotFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		mutate  func(*stack.MonitoringStack, *stack.MonitoringStackSnapshot)
+		message string
+	}{
+		{
+			name: "missing stack",
+			mutate: func(_ *stack.MonitoringStack, snap *stack.MonitoringStackSnapshot) {
+				snap.Spec.MonitoringStack = "other"
+			},
+			message: `MonitoringStack "other" not found`,
+		},
+		{
+			name: "no persistent storage",
+			mutate: func(ms *stack.MonitoringStack, _ *stack.MonitoringStackSnapshot) {
+				ms.Spec.PrometheusConfig.PersistentVolumeClaim = nil
+			},
+			message: "the MonitoringStack has no persistent storage configured for Prometheus",
+		},
+		{
+			name: "missing destination",
+			mutate: func(_ *stack.MonitoringStack, snap *stack.MonitoringStackSnapshot) {
+				snap.Spec.Destination.PersistentVolumeClaim.ClaimName = "missing"
+			},
+			message: `destination PersistentVolumeClaim "missing" not found`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, snap, objs := newTestObjects()
+			tc.mutate(ms, snap)
+			rm, applied := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+				t.Fatal("unexpected request to Prometheus")
+			}, objs...)
+
+			got := reconcileSnapshot(t, rm, snap)
+			assert.Equal(t, got.Status.Phase, stack.SnapshotFailed)
+			assert.Equal(t, got.Status.Message, tc.message)
+			assert.Equal(t, len(*applied), 0)
+		})
+	}
+}
+
+func unstructuredBool(obj client.Object, fields ...string) (bool, bool, error) {
+	return unstructured.NestedBool(obj.(*unstructured.Unstructured).Object, fields...)
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitoringstackvalidation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/selection"
+)
+
+type resourceManager struct {
+	client.Client
+	logger logr.Logger
+}
+
+// RBAC for validating the resources selected by monitoring stacks
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusrules;servicemonitors;podmonitors;probes;scrapeconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client: mgr.GetClient(),
+		logger: ctrl.Log.WithName("monitoring-stack-validation"),
+	}
+
+	generationChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	// Label changes can add or remove the resource from the selection.
+	resourceChanged := builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))
+	enqueueStacks := handler.EnqueueRequestsFromMapFunc(rm.findStacksForResource)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("monitoring-stack-validation").
+		For(&stack.MonitoringStack{}, generationChanged).
+		Watches(&monv1.PrometheusRule{}, enqueueStacks, resourceChanged).
+		Watches(&monv1.ServiceMonitor{}, enqueueStacks, resourceChanged).
+		Watches(&monv1.PodMonitor{}, enqueueStacks, resourceChanged).
+		Watches(&monv1.Probe{}, enqueueStacks, resourceChanged).
+		Watches(&monv1alpha1.ScrapeConfig{}, enqueueStacks, resourceChanged).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(rm.findStacksForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("stack", req.NamespacedName)
+
+	ms := &stack.MonitoringStack{}
+	err := rm.Get(ctx, req.NamespacedName, ms)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !ms.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var validation *stack.ResourceValidation
+	if ms.Spec.ResourceSelector != nil {
+		validation, err = rm.validate(ctx, ms)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if reflect.DeepEqual(validation, ms.Status.Validation) {
+		return ctrl.Result{}, nil
+	}
+
+	if validation != nil {
+		logger.Info("Validated monitoring stack resources", "validated", validation.Validated, "rejected", len(validation.Rejected))
+	}
+
+	patch := client.MergeFrom(ms.DeepCopy())
+	ms.Status.Validation = validation
+	return ctrl.Result{}, rm.Status().Patch(ctx, ms, patch)
+}
+
+// validate lints the resources selected by the stack.
+func (rm resourceManager) validate(ctx context.Context, ms *stack.MonitoringStack) (*stack.ResourceValidation, error) {
+	selector, err := metav1.LabelSelectorAsSelector(ms.Spec.ResourceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource selector: %w", err)
+	}
+
+	namespaces, err := selection.StackNamespaces(ctx, rm, ms)
+	if err != nil {
+		return nil, err
+	}
+
+	validation := &stack.ResourceValidation{}
+	check := func(kind string, obj client.Object, problems []string) {
+		validation.Validated++
+		if len(problems) > 0 {
+			validation.Rejected = append(validation.Rejected, stack.RejectedResource{
+				Kind:      kind,
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Reasons:   problems,
+			})
+		}
+	}
+
+	for _, ns := range namespaces {
+		opts := []client.ListOption{client.InNamespace(ns), client.MatchingLabelsSelector{Selector: selector}}
+
+		rules := &monv1.PrometheusRuleList{}
+		if err := rm.List(ctx, rules, opts...); err != nil {
+			return nil, err
+		}
+		for _, r := range rules.Items {
+			check(monv1.PrometheusRuleKind, r, lintPrometheusRule(r))
+		}
+
+		sms := &monv1.ServiceMonitorList{}
+		if err := rm.List(ctx, sms, opts...); err != nil {
+			return nil, err
+		}
+		for _, sm := range sms.Items {
+			check(monv1.ServiceMonitorsKind, sm, lintServiceMonitor(sm))
+		}
+
+		pms := &monv1.PodMonitorList{}
+		if err := rm.List(ctx, pms, opts...); err != nil {
+			return nil, err
+		}
+		for _, pm := range pms.Items {
+			check(monv1.PodMonitorsKind, pm, lintPodMonitor(pm))
+		}
+
+		probes := &monv1.ProbeList{}
+		if err := rm.List(ctx, probes, opts...); err != nil {
+			return nil, err
+		}
+		for _, p := range probes.Items {
+			check(monv1.ProbesKind, p, lintProbe(p))
+		}
+
+		scs := &monv1alpha1.ScrapeConfigList{}
+		if err := rm.List(ctx, scs, opts...); err != nil {
+			return nil, err
+		}
+		for _, sc := range scs.Items {
+			check(monv1alpha1.ScrapeConfigsKind, sc, lintScrapeConfig(sc))
+		}
+	}
+
+	slices.SortFunc(validation.Rejected, func(a, b stack.RejectedResource) int {
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Namespace, b.Namespace); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return validation, nil
+}
+
+// resourceKind returns the kind under which the resource is reported in the
+// validation status or an empty string if the resource isn't validated.
+func resourceKind(obj client.Object) string {
+	switch obj.(type) {
+	case *monv1.PrometheusRule:
+		return monv1.PrometheusRuleKind
+	case *monv1.ServiceMonitor:
+		return monv1.ServiceMonitorsKind
+	case *monv1.PodMonitor:
+		return monv1.PodMonitorsKind
+	case *monv1.Probe:
+		return monv1.ProbesKind
+	case *monv1alpha1.ScrapeConfig:
+		return monv1alpha1.ScrapeConfigsKind
+	default:
+		return ""
+	}
+}
+
+// findStacksForResource returns the stacks selecting the resource. Stacks
+// which already reject the resource are also returned so that the resource
+// is removed from their status once it isn't selected anymore (or deleted).
+func (rm resourceManager) findStacksForResource(ctx context.Context, obj client.Object) []reconcile.Request {
+	stacks := &stack.MonitoringStackList{}
+	if err := rm.List(ctx, stacks); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stacks")
+		return nil
+	}
+
+	ns := selection.GetNamespace(ctx, rm, obj.GetNamespace())
+
+	var requests []reconcile.Request
+	for i := range stacks.Items {
+		ms := &stacks.Items[i]
+		if (resourceKind(obj) != "" && selection.StackSelects(ms, obj, ns)) || rejects(ms, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+		}
+	}
+	return requests
+}
+
+// rejects returns true if the resource is reported as rejected in the status
+// of the stack.
+func rejects(ms *stack.MonitoringStack, obj client.Object) bool {
+	kind := resourceKind(obj)
+	if ms.Status.Validation == nil || kind == "" {
+		return false
+	}
+	return slices.ContainsFunc(ms.Status.Validation.Rejected, func(r stack.RejectedResource) bool {
+		return r.Kind == kind && r.Namespace == obj.GetNamespace() && r.Name == obj.GetName()
+	})
+}
+
+// findStacksForNamespace returns the stacks with a namespace selector since
+// they may select (or stop selecting) the namespace.
+func (rm resourceManager) findStacksForNamespace(ctx context.Context, _ client.Object) []reconcile.Request {
+	stacks := &stack.MonitoringStackList{}
+	if err := rm.List(ctx, stacks); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stacks")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ms := range stacks.Items {
+		if ms.Spec.NamespaceSelector != nil {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ms)})
+		}
+	}
+	return requests
+}
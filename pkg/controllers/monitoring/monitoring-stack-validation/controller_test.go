@@ -0,0 +1,130 @@
+package monitoringstackvalidation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+)
+
+func newTestManager(objs ...client.Object) *resourceManager {
+	return &resourceManager{
+		Client: testutil.NewClientBuilder(objs...).Build(),
+		logger: logr.Discard(),
+	}
+}
+
+func newRule(ns, name string, lbls map[string]string, expr string) *monv1.PrometheusRule {
+	return &monv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: lbls},
+		Spec: monv1.PrometheusRuleSpec{
+			Groups: []monv1.RuleGroup{{Name: "g", Rules: []monv1.Rule{{Alert: "A", Expr: intstr.FromString(expr)}}}},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	selected := map[string]string{"app": "demo"}
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ms"},
+		Spec: stack.MonitoringStackSpec{
+			ResourceSelector:  &metav1.LabelSelector{MatchLabels: selected},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+		},
+	}
+
+	rm := newTestManager(
+		ms,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{"monitoring": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		newRule("ns", "good", selected, "up == 0"),
+		newRule("ns", "bad", selected, "up =="),
+		newRule("ns", "unselected", nil, "up =="),
+		newRule("other", "bad", selected, "up =="),
+		&monv1.ServiceMonitor{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "sm", Labels: selected},
+			Spec: monv1.ServiceMonitorSpec{
+				Endpoints: []monv1.Endpoint{{RelabelConfigs: []monv1.RelabelConfig{{Action: "replace", SourceLabels: []monv1.LabelName{"a"}}}}},
+			},
+		},
+	)
+
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+	assert.NilError(t, err)
+
+	got := &stack.MonitoringStack{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(ms), got))
+	assert.DeepEqual(t, got.Status.Validation, &stack.ResourceValidation{
+		Validated: 3,
+		Rejected: []stack.RejectedResource{
+			{
+				Kind:      "PrometheusRule",
+				Namespace: "ns",
+				Name:      "bad",
+				Reasons:   []string{`group "g", rule 1: could not parse expression: 1:6: parse error: unexpected end of input`},
+			},
+			{
+				Kind:      "ServiceMonitor",
+				Namespace: "ns",
+				Name:      "sm",
+				Reasons:   []string{"endpoints[0].relabelings[0]: relabel configuration for replace action requires 'target_label' value"},
+			},
+		},
+	})
+}
+
+func TestFindStacksForResource(t *testing.T) {
+	selected := map[string]string{"app": "demo"}
+	sameNamespace := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "same-namespace"},
+		Spec:       stack.MonitoringStackSpec{ResourceSelector: &metav1.LabelSelector{MatchLabels: selected}},
+	}
+	allNamespaces := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "all-namespaces"},
+		Spec: stack.MonitoringStackSpec{
+			ResourceSelector:  &metav1.LabelSelector{},
+			NamespaceSelector: &metav1.LabelSelector{},
+		},
+	}
+	disabled := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "disabled"},
+	}
+	rejecting := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rejecting"},
+		Spec:       stack.MonitoringStackSpec{ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}},
+		Status: stack.MonitoringStackStatus{
+			Validation: &stack.ResourceValidation{Rejected: []stack.RejectedResource{{Kind: "PrometheusRule", Namespace: "ns", Name: "rule"}}},
+		},
+	}
+
+	// A rejected resource of another kind with the same name doesn't match.
+	rejectingOtherKind := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rejecting-other-kind"},
+		Spec:       stack.MonitoringStackSpec{ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}},
+		Status: stack.MonitoringStackStatus{
+			Validation: &stack.ResourceValidation{Rejected: []stack.RejectedResource{{Kind: "ServiceMonitor", Namespace: "ns", Name: "rule"}}},
+		},
+	}
+
+	rm := newTestManager(
+		sameNamespace, allNamespaces, disabled, rejecting, rejectingOtherKind,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}},
+	)
+
+	requests := rm.findStacksForResource(context.Background(), newRule("ns", "rule", selected, "up"))
+	var names []string
+	for _, r := range requests {
+		names = append(names, r.Name)
+	}
+	assert.DeepEqual(t, names, []string{"all-namespaces", "rejecting", "same-namespace"})
+}
@@ -0,0 +1,152 @@
+package monitoringstackvalidation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/promql/parser"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// lintPrometheusRule returns the problems found in the rule groups: duplicate
+// group names, rules which are neither recording nor alerting rules, invalid
+// PromQL expressions and invalid metric or label names.
+func lintPrometheusRule(pr *monv1.PrometheusRule) []string {
+	var problems []string
+
+	groups := map[string]struct{}{}
+	for i, g := range pr.Spec.Groups {
+		if g.Name == "" {
+			problems = append(problems, fmt.Sprintf("group %d: empty group name", i+1))
+		} else if _, found := groups[g.Name]; found {
+			problems = append(problems, fmt.Sprintf("group %q: duplicate group name", g.Name))
+		}
+		groups[g.Name] = struct{}{}
+
+		for j, r := range g.Rules {
+			for _, p := range lintRule(r) {
+				problems = append(problems, fmt.Sprintf("group %q, rule %d: %s", g.Name, j+1, p))
+			}
+		}
+	}
+
+	return problems
+}
+
+func lintRule(r monv1.Rule) []string {
+	var problems []string
+
+	switch {
+	case r.Record != "" && r.Alert != "":
+		problems = append(problems, "only one of 'record' and 'alert' must be set")
+	case r.Record == "" && r.Alert == "":
+		problems = append(problems, "one of 'record' or 'alert' must be set")
+	case r.Record != "":
+		if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+			problems = append(problems, fmt.Sprintf("invalid recording rule name %q", r.Record))
+		}
+		if len(r.Annotations) > 0 {
+			problems = append(problems, "invalid field 'annotations' in recording rule")
+		}
+		if r.For != nil {
+			problems = append(problems, "invalid field 'for' in recording rule")
+		}
+	}
+
+	expr := r.Expr.String()
+	if expr == "" {
+		problems = append(problems, "'expr' must be set")
+	} else if _, err := parser.ParseExpr(expr); err != nil {
+		problems = append(problems, fmt.Sprintf("could not parse expression: %s", err))
+	}
+
+	for name := range r.Labels {
+		if !model.LabelName(name).IsValid() {
+			problems = append(problems, fmt.Sprintf("invalid label name %q", name))
+		}
+	}
+
+	return problems
+}
+
+// lintRelabelConfigs returns the problems found in the relabel configurations.
+// The field argument is used to locate the problems.
+func lintRelabelConfigs(field string, rcs []monv1.RelabelConfig) []string {
+	var problems []string
+	for i, rc := range rcs {
+		if err := validateRelabelConfig(rc); err != nil {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %s", field, i, err))
+		}
+	}
+	return problems
+}
+
+// validateRelabelConfig validates the relabel configuration with the same
+// rules as Prometheus.
+func validateRelabelConfig(rc monv1.RelabelConfig) error {
+	cfg := relabel.DefaultRelabelConfig
+
+	if rc.Action != "" {
+		cfg.Action = relabel.Action(strings.ToLower(rc.Action))
+	}
+	if rc.Separator != nil {
+		cfg.Separator = *rc.Separator
+	}
+	if rc.Replacement != nil {
+		cfg.Replacement = *rc.Replacement
+	}
+	if rc.Regex != "" {
+		re, err := relabel.NewRegexp(rc.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", rc.Regex, err)
+		}
+		cfg.Regex = re
+	}
+	cfg.Modulus = rc.Modulus
+	cfg.TargetLabel = rc.TargetLabel
+	for _, l := range rc.SourceLabels {
+		cfg.SourceLabels = append(cfg.SourceLabels, model.LabelName(l))
+	}
+
+	return cfg.Validate()
+}
+
+func lintServiceMonitor(sm *monv1.ServiceMonitor) []string {
+	var problems []string
+	for i, ep := range sm.Spec.Endpoints {
+		problems = append(problems, lintRelabelConfigs(fmt.Sprintf("endpoints[%d].relabelings", i), ep.RelabelConfigs)...)
+		problems = append(problems, lintRelabelConfigs(fmt.Sprintf("endpoints[%d].metricRelabelings", i), ep.MetricRelabelConfigs)...)
+	}
+	return problems
+}
+
+func lintPodMonitor(pm *monv1.PodMonitor) []string {
+	var problems []string
+	for i, ep := range pm.Spec.PodMetricsEndpoints {
+		problems = append(problems, lintRelabelConfigs(fmt.Sprintf("podMetricsEndpoints[%d].relabelings", i), ep.RelabelConfigs)...)
+		problems = append(problems, lintRelabelConfigs(fmt.Sprintf("podMetricsEndpoints[%d].metricRelabelings", i), ep.MetricRelabelConfigs)...)
+	}
+	return problems
+}
+
+func lintProbe(p *monv1.Probe) []string {
+	var problems []string
+	if sc := p.Spec.Targets.StaticConfig; sc != nil {
+		problems = append(problems, lintRelabelConfigs("targets.staticConfig.relabelingConfigs", sc.RelabelConfigs)...)
+	}
+	if ing := p.Spec.Targets.Ingress; ing != nil {
+		problems = append(problems, lintRelabelConfigs("targets.ingress.relabelingConfigs", ing.RelabelConfigs)...)
+	}
+	problems = append(problems, lintRelabelConfigs("metricRelabelings", p.Spec.MetricRelabelConfigs)...)
+	return problems
+}
+
+func lintScrapeConfig(sc *monv1alpha1.ScrapeConfig) []string {
+	var problems []string
+	problems = append(problems, lintRelabelConfigs("relabelings", sc.Spec.RelabelConfigs)...)
+	problems = append(problems, lintRelabelConfigs("metricRelabelings", sc.Spec.MetricRelabelConfigs)...)
+	return problems
+}
@@ -0,0 +1,146 @@
+package monitoringstackvalidation
+
+import (
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	monv1alpha1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestLintPrometheusRule(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		groups   []monv1.RuleGroup
+		expected []string
+	}{
+		{
+			name: "valid",
+			groups: []monv1.RuleGroup{{
+				Name: "app",
+				Rules: []monv1.Rule{
+					{Record: "job:up:sum", Expr: intstr.FromString("sum by (job) (up)")},
+					{Alert: "TargetDown", Expr: intstr.FromString("up == 0"), For: ptr.To(monv1.Duration("5m")), Labels: map[string]string{"severity": "warning"}},
+				},
+			}},
+		},
+		{
+			name: "duplicate groups",
+			groups: []monv1.RuleGroup{
+				{Name: "app", Rules: []monv1.Rule{{Alert: "A", Expr: intstr.FromString("up == 0")}}},
+				{Name: "app", Rules: []monv1.Rule{{Alert: "B", Expr: intstr.FromString("up == 0")}}},
+				{Rules: []monv1.Rule{{Alert: "C", Expr: intstr.FromString("up == 0")}}},
+			},
+			expected: []string{`group "app": duplicate group name`, "group 3: empty group name"},
+		},
+		{
+			name: "invalid rules",
+			groups: []monv1.RuleGroup{{
+				Name: "app",
+				Rules: []monv1.Rule{
+					{Alert: "A", Expr: intstr.FromString("sum(up")},
+					{Record: "invalid name", Expr: intstr.FromString("up"), Annotations: map[string]string{"summary": "x"}},
+					{Expr: intstr.FromString("up")},
+					{Alert: "B", Expr: intstr.FromInt(1), Labels: map[string]string{"0invalid": "x"}},
+				},
+			}},
+			expected: []string{
+				`group "app", rule 1: could not parse expression: 1:7: parse error: unclosed left parenthesis`,
+				`group "app", rule 2: invalid recording rule name "invalid name"`,
+				`group "app", rule 2: invalid field 'annotations' in recording rule`,
+				`group "app", rule 3: one of 'record' or 'alert' must be set`,
+				`group "app", rule 4: invalid label name "0invalid"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pr := &monv1.PrometheusRule{Spec: monv1.PrometheusRuleSpec{Groups: tc.groups}}
+			assert.DeepEqual(t, lintPrometheusRule(pr), tc.expected)
+		})
+	}
+}
+
+func TestValidateRelabelConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rc   monv1.RelabelConfig
+		err  string
+	}{
+		{
+			name: "valid replace",
+			rc:   monv1.RelabelConfig{SourceLabels: []monv1.LabelName{"__meta_kubernetes_pod_name"}, TargetLabel: "pod"},
+		},
+		{
+			name: "valid labeldrop",
+			rc:   monv1.RelabelConfig{Action: "LabelDrop", Regex: "tmp_.*"},
+		},
+		{
+			name: "invalid regex",
+			rc:   monv1.RelabelConfig{Action: "keep", Regex: "(unclosed"},
+			err:  `invalid regex "(unclosed"`,
+		},
+		{
+			name: "missing target label",
+			rc:   monv1.RelabelConfig{Action: "replace", SourceLabels: []monv1.LabelName{"a"}},
+			err:  "relabel configuration for replace action requires 'target_label' value",
+		},
+		{
+			name: "hashmod without modulus",
+			rc:   monv1.RelabelConfig{Action: "hashmod", TargetLabel: "shard"},
+			err:  "relabel configuration for hashmod requires non-zero modulus",
+		},
+		{
+			name: "labeldrop with target label",
+			rc:   monv1.RelabelConfig{Action: "labeldrop", Regex: "tmp_.*", TargetLabel: "x"},
+			err:  "labeldrop action requires only 'regex', and no other fields",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRelabelConfig(tc.rc)
+			if tc.err == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestLintScrapeResources(t *testing.T) {
+	invalid := []monv1.RelabelConfig{{Action: "keep", Regex: "(unclosed"}}
+
+	for _, tc := range []struct {
+		name     string
+		problems []string
+		expected string
+	}{
+		{
+			name:     "ServiceMonitor",
+			problems: lintServiceMonitor(&monv1.ServiceMonitor{Spec: monv1.ServiceMonitorSpec{Endpoints: []monv1.Endpoint{{}, {MetricRelabelConfigs: invalid}}}}),
+			expected: "endpoints[1].metricRelabelings[0]: invalid regex",
+		},
+		{
+			name:     "PodMonitor",
+			problems: lintPodMonitor(&monv1.PodMonitor{Spec: monv1.PodMonitorSpec{PodMetricsEndpoints: []monv1.PodMetricsEndpoint{{RelabelConfigs: invalid}}}}),
+			expected: "podMetricsEndpoints[0].relabelings[0]: invalid regex",
+		},
+		{
+			name:     "Probe",
+			problems: lintProbe(&monv1.Probe{Spec: monv1.ProbeSpec{Targets: monv1.ProbeTargets{StaticConfig: &monv1.ProbeTargetStaticConfig{RelabelConfigs: invalid}}}}),
+			expected: "targets.staticConfig.relabelingConfigs[0]: invalid regex",
+		},
+		{
+			name:     "ScrapeConfig",
+			problems: lintScrapeConfig(&monv1alpha1.ScrapeConfig{Spec: monv1alpha1.ScrapeConfigSpec{RelabelConfigs: invalid}}),
+			expected: "relabelings[0]: invalid regex",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, len(tc.problems), 1)
+			assert.Assert(t, strings.HasPrefix(tc.problems[0], tc.expected), tc.problems[0])
+		})
+	}
+}
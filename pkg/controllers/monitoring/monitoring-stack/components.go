@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strings"
 
+	"github.com/prometheus/common/model"
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -14,6 +16,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
 	"github.com/rhobs/observability-operator/pkg/reconciler"
 )
 
@@ -23,8 +26,24 @@ const (
 	AlertmanagerUserFSGroupID            = int64(65535)
 
 	prometheusSecretsMountPoint = "/etc/prometheus/secrets"
+
+	// defaultScrapeInterval is the scrape interval of Prometheus when the
+	// stack doesn't set it.
+	defaultScrapeInterval = monv1.Duration("30s")
 )
 
+// PrometheusPodName returns the name of the pod created by prometheus-operator
+// for the given Prometheus replica of the stack.
+func PrometheusPodName(ms *stack.MonitoringStack, replica int) string {
+	return fmt.Sprintf("prometheus-%s-%d", ms.Name, replica)
+}
+
+// PrometheusPVCName returns the name of the PersistentVolumeClaim created by
+// prometheus-operator for the given Prometheus pod of the stack.
+func PrometheusPVCName(ms *stack.MonitoringStack, pod string) string {
+	return fmt.Sprintf("prometheus-%s-db-%s", ms.Name, pod)
+}
+
 var (
 	rbacVerbs = []string{"get", "list", "watch"}
 )
@@ -49,22 +68,42 @@ func stackComponentReconcilers(
 	thanos ThanosConfiguration,
 	prometheus PrometheusConfiguration,
 	alertmanager AlertmanagerConfiguration,
-) []reconciler.Reconciler {
+	blackboxExporter BlackboxExporterConfiguration,
+	federationSources []federationSource,
+	sidecarGRPCTLSSecret *corev1.Secret,
+) ([]reconciler.Reconciler, error) {
 	prometheusName := ms.Name + "-prometheus"
 	alertmanagerName := ms.Name + "-alertmanager"
 	additionalScrapeConfigsSecretName := ms.Name + "-prometheus-additional-scrape-configs"
 	hasNsSelector := ms.Spec.NamespaceSelector != nil
 	deployAlertmanager := !ms.Spec.AlertmanagerConfig.Disabled
+	deployBlackboxExporter := probingEnabled(ms)
+	deployFederationCA := hasFederationCA(federationSources)
 
-	return []reconciler.Reconciler{
+	if err := validateScrapeTimeout(ms.Spec.PrometheusConfig); err != nil {
+		return nil, err
+	}
+
+	var blackboxExporterConfig string
+	if deployBlackboxExporter {
+		var err error
+		blackboxExporterConfig, err = newBlackboxExporterConfig(ms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reconcilers := sidecarGRPCTLSReconcilers(ms, sidecarGRPCTLSSecret)
+	return append(reconcilers,
 		// Prometheus Deployment
 		reconciler.NewUpdater(newServiceAccount(prometheusName, ms.Namespace), ms),
 		reconciler.NewUpdater(newPrometheusClusterRole(prometheusName, rbacVerbs), ms),
-		reconciler.NewUpdater(newAdditionalScrapeConfigsSecret(ms, additionalScrapeConfigsSecretName), ms),
+		reconciler.NewUpdater(newAdditionalScrapeConfigsSecret(ms, additionalScrapeConfigsSecretName, federationSources, instanceSelectorKey, instanceSelectorValue), ms),
+		reconciler.NewOptionalUpdater(newFederationCASecret(ms, federationSources, instanceSelectorKey, instanceSelectorValue), ms, deployFederationCA),
 		reconciler.NewUpdater(newPrometheus(ms, prometheusName,
 			additionalScrapeConfigsSecretName,
 			instanceSelectorKey, instanceSelectorValue,
-			thanos, prometheus), ms),
+			thanos, prometheus, deployFederationCA), ms),
 		reconciler.NewUpdater(newPrometheusService(ms, instanceSelectorKey, instanceSelectorValue), ms),
 		reconciler.NewUpdater(newThanosSidecarService(ms, instanceSelectorKey, instanceSelectorValue), ms),
 		reconciler.NewOptionalUpdater(newPrometheusPDB(ms, instanceSelectorKey, instanceSelectorValue), ms,
@@ -85,7 +124,12 @@ func stackComponentReconcilers(
 		reconciler.NewOptionalUpdater(newAlertmanager(ms, alertmanagerName, instanceSelectorKey, instanceSelectorValue, alertmanager), ms, deployAlertmanager),
 		reconciler.NewOptionalUpdater(newAlertmanagerService(ms, instanceSelectorKey, instanceSelectorValue), ms, deployAlertmanager),
 		reconciler.NewOptionalUpdater(newAlertmanagerPDB(ms, instanceSelectorKey, instanceSelectorValue), ms, deployAlertmanager),
-	}
+
+		// Blackbox exporter Deployment
+		reconciler.NewOptionalUpdater(newBlackboxExporterConfigMap(ms, blackboxExporterConfig, instanceSelectorKey, instanceSelectorValue), ms, deployBlackboxExporter),
+		reconciler.NewOptionalUpdater(newBlackboxExporterDeployment(ms, blackboxExporterConfig, instanceSelectorKey, instanceSelectorValue, blackboxExporter), ms, deployBlackboxExporter),
+		reconciler.NewOptionalUpdater(newBlackboxExporterService(ms, instanceSelectorKey, instanceSelectorValue), ms, deployBlackboxExporter),
+	), nil
 }
 
 func newPrometheusClusterRole(rbacResourceName string, rbacVerbs []string) *rbacv1.ClusterRole {
@@ -127,6 +171,35 @@ func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	}
 }
 
+// validateScrapeTimeout checks that the scrape timeout isn't greater than the
+// scrape interval, which Prometheus would refuse to load. The durations can't
+// be compared by the API server since they may be expressed in days, weeks or
+// years.
+func validateScrapeTimeout(config *stack.PrometheusConfig) error {
+	if config == nil || config.ScrapeTimeout == nil {
+		return nil
+	}
+
+	interval := defaultScrapeInterval
+	if config.ScrapeInterval != nil {
+		interval = *config.ScrapeInterval
+	}
+
+	i, err := model.ParseDuration(string(interval))
+	if err != nil {
+		return fmt.Errorf("invalid scrape interval: %w", err)
+	}
+	t, err := model.ParseDuration(string(*config.ScrapeTimeout))
+	if err != nil {
+		return fmt.Errorf("invalid scrape timeout: %w", err)
+	}
+	if t > i {
+		return fmt.Errorf("the scrape timeout (%s) must not be greater than the scrape interval (%s)", *config.ScrapeTimeout, interval)
+	}
+
+	return nil
+}
+
 func newPrometheus(
 	ms *stack.MonitoringStack,
 	rbacResourceName string,
@@ -135,6 +208,7 @@ func newPrometheus(
 	instanceSelectorValue string,
 	thanosCfg ThanosConfiguration,
 	prometheusCfg PrometheusConfiguration,
+	mountFederationCA bool,
 ) *monv1.Prometheus {
 	prometheusSelector := ms.Spec.ResourceSelector
 
@@ -208,13 +282,23 @@ func newPrometheus(
 				ExternalLabels:            config.ExternalLabels,
 				EnableRemoteWriteReceiver: config.EnableRemoteWriteReceiver,
 				EnableFeatures: func() []monv1.EnableFeature {
+					features := []monv1.EnableFeature{}
 					if config.EnableOtlpHttpReceiver != nil && *config.EnableOtlpHttpReceiver {
-						return []monv1.EnableFeature{"otlp-write-receiver"}
+						features = append(features, "otlp-write-receiver")
 					}
-					return []monv1.EnableFeature{}
+					if config.Exemplars != nil {
+						features = append(features, "exemplar-storage")
+					}
+					if config.EnableNativeHistograms != nil && *config.EnableNativeHistograms {
+						features = append(features, "native-histograms")
+					}
+					return features
 				}(),
+				TracingConfig:   config.TracingConfig,
+				ScrapeProtocols: config.ScrapeProtocols,
 			},
 			Retention:             ms.Spec.Retention,
+			Exemplars:             config.Exemplars,
 			RuleSelector:          prometheusSelector,
 			RuleNamespaceSelector: ms.Spec.NamespaceSelector,
 			Thanos: &monv1.ThanosSpec{
@@ -250,6 +334,20 @@ func newPrometheus(
 		prometheus.Spec.Secrets = append(prometheus.Spec.Secrets, tlsConfig.CertificateAuthority.Name)
 	}
 
+	if mountFederationCA {
+		prometheus.Spec.Secrets = append(prometheus.Spec.Secrets, federationCASecretName(ms))
+	}
+
+	if config.ThanosSidecarGRPCTLS != nil {
+		secretName := sidecarGRPCTLSSecretName(ms)
+		prometheus.Spec.Secrets = append(prometheus.Spec.Secrets, secretName)
+		prometheus.Spec.Thanos.GRPCServerTLSConfig = &monv1.TLSConfig{
+			CAFile:   filepath.Join(prometheusSecretsMountPoint, secretName, certificates.CAKey),
+			CertFile: filepath.Join(prometheusSecretsMountPoint, secretName, certificates.CertificateKey),
+			KeyFile:  filepath.Join(prometheusSecretsMountPoint, secretName, certificates.PrivateKeyKey),
+		}
+	}
+
 	if prometheusCfg.Image != "" {
 		prometheus.Spec.CommonPrometheusFields.Image = ptr.To(prometheusCfg.Image)
 	}
@@ -285,6 +383,26 @@ func newPrometheus(
 		prometheus.Spec.ScrapeInterval = *ms.Spec.PrometheusConfig.ScrapeInterval
 	}
 
+	if config.ScrapeTimeout != nil {
+		prometheus.Spec.ScrapeTimeout = *config.ScrapeTimeout
+	}
+
+	if config.EvaluationInterval != nil {
+		prometheus.Spec.EvaluationInterval = *config.EvaluationInterval
+	}
+
+	if query := config.Query; query != nil {
+		prometheus.Spec.Query = &monv1.QuerySpec{
+			MaxConcurrency: query.MaxConcurrency,
+			MaxSamples:     query.MaxSamples,
+			Timeout:        query.Timeout,
+		}
+		if query.LookbackDelta != nil {
+			prometheus.Spec.Query.LookbackDelta = ptr.To(string(*query.LookbackDelta))
+		}
+		prometheus.Spec.QueryLogFile = query.QueryLogFile
+	}
+
 	return prometheus
 }
 
@@ -409,7 +527,7 @@ func newThanosSidecarService(ms *stack.MonitoringStack, instanceSelectorKey stri
 	}
 }
 
-func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *corev1.Secret {
+func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string, federationSources []federationSource, instanceSelectorKey string, instanceSelectorValue string) *corev1.Secret {
 	var (
 		prometheusScheme     = "http"
 		prometheusCAFile     string
@@ -433,18 +551,13 @@ func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *c
 		alertmanagerCAFile = filepath.Join(prometheusSecretsMountPoint, amCASecret.Name, amCASecret.Key)
 		alertmanagerServerName = fmt.Sprintf("%s-alertmanager", ms.Name)
 	}
-	return &corev1.Secret{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: corev1.SchemeGroupVersion.String(),
-			Kind:       "Secret",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: ms.Namespace,
-		},
-		StringData: map[string]string{
-			AdditionalScrapeConfigsSelfScrapeKey: fmt.Sprintf(`
-- job_name: prometheus-self
+
+	// The scrape interval and timeout of the jobs default to the global
+	// values of the Prometheus configuration.
+	jobOptions := scrapeJobOptions(ms.Spec.PrometheusConfig)
+
+	selfScrapeConfig := fmt.Sprintf(`
+- job_name: prometheus-self%s
   scheme: %s
   tls_config:
     ca_file: %q
@@ -477,9 +590,7 @@ func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *c
     namespaces:
       names:
       - %s
-- job_name: alertmanager-self
-  scrape_interval: 30s
-  scrape_timeout: 10s
+- job_name: alertmanager-self%s
   metrics_path: /metrics
   scheme: %s
   tls_config:
@@ -531,19 +642,91 @@ func newAdditionalScrapeConfigsSecret(ms *stack.MonitoringStack, name string) *c
     namespaces:
       names:
       - %s`,
-				prometheusScheme,
-				prometheusCAFile,
-				prometheusServerName,
-				fmt.Sprintf("%s-prometheus", ms.Name),
-				ms.Namespace,
-				alertmanagerScheme,
-				alertmanagerCAFile,
-				alertmanagerServerName,
-				fmt.Sprintf("%s-alertmanager", ms.Name),
-				ms.Namespace,
-			),
+		jobOptions,
+		prometheusScheme,
+		prometheusCAFile,
+		prometheusServerName,
+		fmt.Sprintf("%s-prometheus", ms.Name),
+		ms.Namespace,
+		jobOptions,
+		alertmanagerScheme,
+		alertmanagerCAFile,
+		alertmanagerServerName,
+		fmt.Sprintf("%s-alertmanager", ms.Name),
+		ms.Namespace,
+	)
+
+	if probingEnabled(ms) {
+		selfScrapeConfig += fmt.Sprintf(`
+- job_name: blackbox-exporter-self%s
+  metrics_path: /metrics
+  scheme: http
+  relabel_configs:
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_service_label_app_kubernetes_io_name
+    regex: %s
+  - action: keep
+    source_labels:
+    - __meta_kubernetes_endpoint_port_name
+    regex: http
+  - source_labels:
+    - __meta_kubernetes_namespace
+    target_label: namespace
+  - source_labels:
+    - __meta_kubernetes_service_name
+    target_label: service
+  - source_labels:
+    - __meta_kubernetes_pod_name
+    target_label: pod
+  - source_labels:
+    - __meta_kubernetes_pod_container_name
+    target_label: container
+  - target_label: endpoint
+    replacement: http
+  kubernetes_sd_configs:
+  - role: endpoints
+    namespaces:
+      names:
+      - %s`,
+			jobOptions,
+			blackboxExporterName(ms),
+			ms.Namespace,
+		)
+	}
+
+	selfScrapeConfig += federationScrapeConfigs(ms, federationSources, jobOptions)
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
 		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		StringData: map[string]string{
+			AdditionalScrapeConfigsSelfScrapeKey: selfScrapeConfig,
+		},
+	}
+}
+
+// scrapeJobOptions returns the options of the scrape jobs generated by the
+// operator which can't be set globally in the Prometheus configuration.
+func scrapeJobOptions(config *stack.PrometheusConfig) string {
+	var options strings.Builder
+	if config.ScrapeClassicHistograms != nil {
+		fmt.Fprintf(&options, "\n  scrape_classic_histograms: %t", *config.ScrapeClassicHistograms)
+	}
+	if config.NativeHistogramBucketLimit != nil {
+		fmt.Fprintf(&options, "\n  native_histogram_bucket_limit: %d", *config.NativeHistogramBucketLimit)
+	}
+	if config.NativeHistogramMinBucketFactor != nil {
+		fmt.Fprintf(&options, "\n  native_histogram_min_bucket_factor: %s", config.NativeHistogramMinBucketFactor.AsDec().String())
 	}
+	return options.String()
 }
 
 func newPrometheusPDB(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string) *policyv1.PodDisruptionBudget {
@@ -10,6 +10,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
 )
@@ -46,10 +47,18 @@ func TestStorageSpec(t *testing.T) {
 	}
 }
 
+func TestPrometheusPVCName(t *testing.T) {
+	ms := &stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"}}
+
+	assert.Equal(t, PrometheusPodName(ms, 1), "prometheus-ms-1")
+	assert.Equal(t, PrometheusPVCName(ms, PrometheusPodName(ms, 1)), "prometheus-ms-db-prometheus-ms-1")
+}
+
 func TestNewAdditionalScrapeConfigsSecret(t *testing.T) {
 	for _, tc := range []struct {
 		name       string
 		spec       stack.MonitoringStackSpec
+		federation []federationSource
 		goldenFile string
 	}{
 		{
@@ -98,6 +107,46 @@ func TestNewAdditionalScrapeConfigsSecret(t *testing.T) {
 			},
 			goldenFile: "tls",
 		},
+		{
+			name: "probing",
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig:   &stack.PrometheusConfig{},
+				AlertmanagerConfig: stack.AlertmanagerConfig{},
+				Probing:            &stack.ProbingConfig{Enabled: true},
+			},
+			goldenFile: "probing",
+		},
+		{
+			name: "native-histograms",
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig: &stack.PrometheusConfig{
+					ScrapeClassicHistograms:        ptr.To(true),
+					NativeHistogramBucketLimit:     ptr.To(uint64(160)),
+					NativeHistogramMinBucketFactor: ptr.To(resource.MustParse("1.1")),
+				},
+				AlertmanagerConfig: stack.AlertmanagerConfig{},
+			},
+			goldenFile: "native-histograms",
+		},
+		{
+			name: "federation",
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig:   &stack.PrometheusConfig{},
+				AlertmanagerConfig: stack.AlertmanagerConfig{},
+				Federation: &stack.FederationConfig{
+					Match:    []string{`{job="app"}`, `{__name__=~"slo:.+"}`},
+					Interval: ptr.To(monv1.Duration("1m")),
+				},
+			},
+			federation: []federationSource{
+				{ms: &stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: "team-a"}}},
+				{
+					ms: &stack.MonitoringStack{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Namespace: "team-b"}},
+					ca: []byte("ca"),
+				},
+			},
+			goldenFile: "federation",
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			ms := stack.MonitoringStack{
@@ -107,9 +156,140 @@ func TestNewAdditionalScrapeConfigsSecret(t *testing.T) {
 				},
 				Spec: tc.spec,
 			}
-			s := newAdditionalScrapeConfigsSecret(&ms, tc.name)
+			s := newAdditionalScrapeConfigsSecret(&ms, tc.name, tc.federation, "key", "value")
 			assert.Equal(t, s.Name, tc.name)
 			golden.Assert(t, s.StringData[AdditionalScrapeConfigsSelfScrapeKey], tc.goldenFile)
 		})
 	}
 }
+
+func TestNewPrometheusTracingAndExemplars(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   stack.PrometheusConfig
+		features []monv1.EnableFeature
+	}{
+		{
+			name:     "default",
+			features: []monv1.EnableFeature{},
+		},
+		{
+			name: "otlp and exemplars",
+			config: stack.PrometheusConfig{
+				EnableOtlpHttpReceiver: ptr.To(true),
+				Exemplars:              &monv1.Exemplars{MaxSize: ptr.To(int64(100000))},
+				TracingConfig: &monv1.PrometheusTracingConfig{
+					Endpoint:         "tempo.tracing.svc:4317",
+					SamplingFraction: ptr.To(resource.MustParse("0.1")),
+				},
+			},
+			features: []monv1.EnableFeature{"otlp-write-receiver", "exemplar-storage"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &stack.MonitoringStack{
+				ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+				Spec:       stack.MonitoringStackSpec{PrometheusConfig: &tc.config},
+			}
+
+			p := newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+			assert.DeepEqual(t, p.Spec.EnableFeatures, tc.features)
+			assert.DeepEqual(t, p.Spec.TracingConfig, tc.config.TracingConfig)
+			assert.DeepEqual(t, p.Spec.Exemplars, tc.config.Exemplars)
+		})
+	}
+}
+
+func TestNewPrometheusScrapeSettings(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{
+				ScrapeInterval:         ptr.To(monv1.Duration("1m")),
+				ScrapeTimeout:          ptr.To(monv1.Duration("20s")),
+				EvaluationInterval:     ptr.To(monv1.Duration("2m")),
+				ScrapeProtocols:        []monv1.ScrapeProtocol{"PrometheusProto", "OpenMetricsText1.0.0"},
+				EnableNativeHistograms: ptr.To(true),
+			},
+		},
+	}
+
+	p := newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+	assert.Equal(t, p.Spec.ScrapeInterval, monv1.Duration("1m"))
+	assert.Equal(t, p.Spec.ScrapeTimeout, monv1.Duration("20s"))
+	assert.Equal(t, p.Spec.EvaluationInterval, monv1.Duration("2m"))
+	assert.DeepEqual(t, p.Spec.ScrapeProtocols, []monv1.ScrapeProtocol{"PrometheusProto", "OpenMetricsText1.0.0"})
+	assert.DeepEqual(t, p.Spec.EnableFeatures, []monv1.EnableFeature{"native-histograms"})
+}
+
+func TestValidateScrapeTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval monv1.Duration
+		timeout  monv1.Duration
+		err      string
+	}{
+		{name: "no timeout", interval: "10s"},
+		{name: "timeout equal to the interval", interval: "1m", timeout: "60s"},
+		{name: "timeout lower than the default interval", timeout: "20s"},
+		{name: "durations in days", interval: "1d", timeout: "12h"},
+		{
+			name:     "timeout greater than the interval",
+			interval: "10s",
+			timeout:  "20s",
+			err:      "the scrape timeout (20s) must not be greater than the scrape interval (10s)",
+		},
+		{
+			name:    "timeout greater than the default interval",
+			timeout: "1m",
+			err:     "the scrape timeout (1m) must not be greater than the scrape interval (30s)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &stack.PrometheusConfig{}
+			if tc.interval != "" {
+				config.ScrapeInterval = ptr.To(tc.interval)
+			}
+			if tc.timeout != "" {
+				config.ScrapeTimeout = ptr.To(tc.timeout)
+			}
+
+			err := validateScrapeTimeout(config)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
+
+func TestNewPrometheusQuery(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+
+	p := newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+	assert.Assert(t, p.Spec.Query == nil)
+	assert.Equal(t, p.Spec.QueryLogFile, "")
+
+	ms.Spec.PrometheusConfig.Query = &stack.QueryConfig{
+		MaxConcurrency: ptr.To(int32(4)),
+		Timeout:        ptr.To(monv1.Duration("30s")),
+		MaxSamples:     ptr.To(int32(1000000)),
+		LookbackDelta:  ptr.To(monv1.Duration("10m")),
+		QueryLogFile:   "query.log",
+	}
+
+	p = newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+	assert.DeepEqual(t, p.Spec.Query, &monv1.QuerySpec{
+		MaxConcurrency: ptr.To(int32(4)),
+		Timeout:        ptr.To(monv1.Duration("30s")),
+		MaxSamples:     ptr.To(int32(1000000)),
+		LookbackDelta:  ptr.To("10m"),
+	})
+	assert.Equal(t, p.Spec.QueryLogFile, "query.log")
+}
@@ -23,14 +23,41 @@ const (
 	ResourceSelectorIsNilMessage   = "No resources will be discovered, ResourceSelector is nil"
 	ResourceDiscoveryOnMessage     = "Resource discovery is operational"
 	NoReason                       = "None"
+	SuspendedReason                = "MonitoringStackSuspended"
+	HibernatingReason              = "MonitoringStackHibernating"
+	NotSuspendedReason             = "MonitoringStackNotSuspended"
+	InvalidHibernationReason       = "InvalidHibernationConfig"
+	SuspendedMessage               = "Monitoring Stack is suspended"
+	NotSuspendedMessage            = "Monitoring Stack is not suspended"
 )
 
-func updateConditions(ms *v1alpha1.MonitoringStack, prom monv1.Prometheus, recError error) []v1alpha1.Condition {
-	return []v1alpha1.Condition{
+func updateConditions(ms *v1alpha1.MonitoringStack, prom monv1.Prometheus, s suspension, recError error) []v1alpha1.Condition {
+	available := updateAvailable(ms.Status.Conditions, prom, ms.Generation)
+	if s.suspended {
+		available = v1alpha1.Condition{
+			Type:               v1alpha1.AvailableCondition,
+			Status:             v1alpha1.ConditionFalse,
+			Reason:             s.reason,
+			Message:            s.message,
+			LastTransitionTime: metav1.Now(),
+			ObservedGeneration: ms.Generation,
+		}
+	}
+
+	conditions := []v1alpha1.Condition{
 		updateResourceDiscovery(ms),
-		updateAvailable(ms.Status.Conditions, prom, ms.Generation),
+		available,
 		updateReconciled(ms.Status.Conditions, prom, ms.Generation, recError),
+		updateSuspended(ms, s),
 	}
+
+	// The ConfigurationLoaded condition is managed by the
+	// monitoring-stack-reload controller.
+	if c, err := getMSCondition(ms.Status.Conditions, v1alpha1.ConfigurationLoadedCondition); err == nil {
+		conditions = append(conditions, c)
+	}
+
+	return conditions
 }
 
 func getMSCondition(conditions []v1alpha1.Condition, t v1alpha1.ConditionType) (v1alpha1.Condition, error) {
@@ -68,6 +95,25 @@ func updateResourceDiscovery(ms *v1alpha1.MonitoringStack) v1alpha1.Condition {
 
 }
 
+// updateSuspended returns the "Suspended" condition reflecting whether the
+// MonitoringStack is suspended, either explicitly or by its hibernation
+// schedule.
+func updateSuspended(ms *v1alpha1.MonitoringStack, s suspension) v1alpha1.Condition {
+	status := v1alpha1.ConditionFalse
+	if s.suspended {
+		status = v1alpha1.ConditionTrue
+	}
+
+	return v1alpha1.Condition{
+		Type:               v1alpha1.SuspendedCondition,
+		Status:             status,
+		Reason:             s.reason,
+		Message:            s.message,
+		LastTransitionTime: metav1.Now(),
+		ObservedGeneration: ms.Generation,
+	}
+}
+
 // updateAvailable gets existing "Available" condition and updates its parameters
 // based on the Prometheus "Available" condition
 func updateAvailable(conditions []v1alpha1.Condition, prom monv1.Prometheus, generation int64) v1alpha1.Condition {
@@ -323,3 +323,49 @@ func TestUpdateResourceDiscovery(t *testing.T) {
 	}
 
 }
+
+func TestUpdateConditionsSuspended(t *testing.T) {
+	ms := &v1alpha1.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       v1alpha1.MonitoringStackSpec{Suspend: true},
+	}
+	sus := suspension{suspended: true, reason: SuspendedReason, message: SuspendedMessage}
+
+	conditions := updateConditions(ms, monv1.Prometheus{}, sus, nil)
+
+	available, err := getMSCondition(conditions, v1alpha1.AvailableCondition)
+	assert.NilError(t, err)
+	assert.Check(t, v1alpha1.Condition{
+		Status:             v1alpha1.ConditionFalse,
+		Reason:             SuspendedReason,
+		Message:            SuspendedMessage,
+		ObservedGeneration: 2,
+	}.Equal(available), "got %v", available)
+
+	suspended, err := getMSCondition(conditions, v1alpha1.SuspendedCondition)
+	assert.NilError(t, err)
+	assert.Check(t, v1alpha1.Condition{
+		Status:             v1alpha1.ConditionTrue,
+		Reason:             SuspendedReason,
+		Message:            SuspendedMessage,
+		ObservedGeneration: 2,
+	}.Equal(suspended), "got %v", suspended)
+}
+
+func TestUpdateConditionsKeepsConfigurationLoaded(t *testing.T) {
+	loaded := v1alpha1.Condition{
+		Type:    v1alpha1.ConfigurationLoadedCondition,
+		Status:  v1alpha1.ConditionFalse,
+		Reason:  "ConfigurationReloadFailed",
+		Message: "invalid rule",
+	}
+	ms := &v1alpha1.MonitoringStack{
+		Status: v1alpha1.MonitoringStackStatus{Conditions: []v1alpha1.Condition{loaded}},
+	}
+
+	conditions := updateConditions(ms, monv1.Prometheus{}, suspension{reason: NotSuspendedReason, message: NotSuspendedMessage}, nil)
+
+	c, err := getMSCondition(conditions, v1alpha1.ConfigurationLoadedCondition)
+	assert.NilError(t, err)
+	assert.Check(t, loaded.Equal(c), "got %v", c)
+}
@@ -25,16 +25,21 @@ import (
 
 	"github.com/go-logr/logr"
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
 )
@@ -49,6 +54,13 @@ type resourceManager struct {
 	prometheus            PrometheusConfiguration
 	alertmanager          AlertmanagerConfiguration
 	thanos                ThanosConfiguration
+	blackboxExporter      BlackboxExporterConfiguration
+	// apiReader reads the Secrets provided by the users which aren't cached
+	// by the operator.
+	apiReader client.Reader
+	// Namespace of the operator where the certificate authority issuing the
+	// gRPC certificates is stored.
+	namespace string
 }
 
 type PrometheusConfiguration struct {
@@ -63,26 +75,43 @@ type ThanosConfiguration struct {
 	Image string
 }
 
+type BlackboxExporterConfiguration struct {
+	Image string
+}
+
 // Options allows for controller options to be set
 type Options struct {
 	InstanceSelector string
 	Prometheus       PrometheusConfiguration
 	Alertmanager     AlertmanagerConfiguration
 	Thanos           ThanosConfiguration
+	BlackboxExporter BlackboxExporterConfiguration
+	Namespace        string
 }
 
-const finalizerName = "monitoring.observability.openshift.io/finalizer"
+const (
+	finalizerName = "monitoring.observability.openshift.io/finalizer"
+
+	// templateIndexKey indexes MonitoringStacks by the name of their template.
+	templateIndexKey = ".spec.template"
+)
 
 // RBAC for managing monitoring stacks
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=list;watch;create;update
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks/status,verbs=get;update
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacktemplates,verbs=get;list;watch
 
 // RBAC for managing Prometheus Operator CRs
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=alertmanagers;prometheuses;servicemonitors,verbs=list;watch;create;update;delete;patch
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=list;watch;create;update;delete;patch
-//+kubebuilder:rbac:groups="",resources=serviceaccounts;services;secrets,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts;services;secrets,verbs=get;list;watch;create;update;delete;patch
 //+kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=list;watch;create;update;delete;patch
 
+// RBAC for managing the blackbox exporter
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=list;watch;create;update;delete;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
 // RBAC for delegating permissions to Prometheus
 //+kubebuilder:rbac:groups="",resources=pods;services;endpoints,verbs=get;list;watch
 //+kubebuilder:rbac:groups=extensions;networking.k8s.io,resources=ingresses,verbs=get;list;watch
@@ -99,6 +128,7 @@ func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 
 	rm := &resourceManager{
 		k8sClient:             mgr.GetClient(),
+		apiReader:             mgr.GetAPIReader(),
 		scheme:                mgr.GetScheme(),
 		logger:                ctrl.Log.WithName("observability-operator"),
 		instanceSelectorKey:   split[0],
@@ -106,13 +136,27 @@ func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 		thanos:                opts.Thanos,
 		prometheus:            opts.Prometheus,
 		alertmanager:          opts.Alertmanager,
+		blackboxExporter:      opts.BlackboxExporter,
+		namespace:             opts.Namespace,
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &stack.MonitoringStack{}, templateIndexKey, func(o client.Object) []string {
+		ms := o.(*stack.MonitoringStack)
+		if ms.Spec.Template == "" {
+			return nil
+		}
+		return []string{ms.Spec.Template}
+	}); err != nil {
+		return err
 	}
+
 	// We only want to trigger a reconciliation when the generation
 	// of a child changes. Until we need to update our the status for our own objects,
 	// we can save CPU cycles by avoiding reconciliations triggered by
 	// child status changes. The only exception is Prometheus resources, where we want to
-	// be notified about changes in their status.
+	// be notified about changes in their status. The ConfigMaps and Secrets
+	// have no generation: they are reconciled on any change.
 	generationChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	resourceVersionChanged := builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})
 
 	ctrl, err := ctrl.NewControllerManagedBy(mgr).
 		For(&stack.MonitoringStack{}).
@@ -124,6 +168,19 @@ func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 		Owns(&rbacv1.RoleBinding{}, generationChanged).
 		Owns(&monv1.ServiceMonitor{}, generationChanged).
 		Owns(&policyv1.PodDisruptionBudget{}, generationChanged).
+		Owns(&appsv1.Deployment{}, generationChanged).
+		Owns(&v1.ConfigMap{}, resourceVersionChanged).
+		Owns(&v1.Secret{}, resourceVersionChanged).
+		Watches(
+			&stack.MonitoringStack{},
+			handler.EnqueueRequestsFromMapFunc(rm.findFederatingStacks),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{})),
+		).
+		Watches(
+			&stack.MonitoringStackTemplate{},
+			handler.EnqueueRequestsFromMapFunc(rm.findStacksForTemplate),
+			generationChanged,
+		).
 		Build(rm)
 
 	if err != nil {
@@ -181,13 +238,87 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		}
 	}
 
-	reconcilers := stackComponentReconcilers(ms,
+	// The suspension doesn't depend on the template: it is computed first to
+	// be reported even when the template can't be resolved.
+	sus, susErr := getSuspension(ms, time.Now())
+	if susErr != nil {
+		sus = suspension{reason: InvalidHibernationReason, message: susErr.Error()}
+	}
+
+	tmpl, err := rm.getTemplate(ctx, ms)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	var spec stack.MonitoringStackSpec
+	if err != nil {
+		err = fmt.Errorf("MonitoringStackTemplate %q not found", ms.Spec.Template)
+	} else {
+		spec, err = effectiveSpec(ms, tmpl)
+	}
+	if err != nil {
+		// The template is missing or the stack doesn't comply with it: report
+		// the error and wait for either the stack or the template to be
+		// updated.
+		logger.Info("invalid monitoring stack template", "err", err)
+		ms.Status.EffectiveSpec = nil
+		// The suspension is enforced even though the stack can't be
+		// reconciled.
+		if sus.suspended {
+			if err := rm.scaleDown(ctx, ms); err != nil {
+				return rm.updateStatus(ctx, req, ms, sus, err), err
+			}
+		}
+		return withHibernationRequeue(rm.updateStatus(ctx, req, ms, sus, err), sus), nil
+	}
+	ms.Status.EffectiveSpec = &spec
+
+	// The components are built from the effective specification.
+	ems := ms.DeepCopy()
+	ems.Spec = spec
+
+	if susErr != nil {
+		// Retrying doesn't help with an invalid schedule: report the error
+		// and wait for the stack to be updated.
+		logger.Info("invalid hibernation configuration", "err", susErr)
+		return rm.updateStatus(ctx, req, ms, sus, susErr), nil
+	}
+
+	if sus.suspended {
+		// Changes to the stack aren't applied while it is suspended: only
+		// ensure that its workloads are scaled down.
+		if err := rm.scaleDown(ctx, ems); err != nil {
+			return rm.updateStatus(ctx, req, ms, sus, err), err
+		}
+		return withHibernationRequeue(rm.updateStatus(ctx, req, ms, sus, nil), sus), nil
+	}
+
+	federationSources, err := rm.federationSources(ctx, ems)
+	if err != nil {
+		return rm.updateStatus(ctx, req, ms, sus, err), err
+	}
+
+	sidecarGRPCTLSSecret, err := rm.sidecarGRPCTLSSecret(ctx, ems)
+	if err != nil {
+		return rm.updateStatus(ctx, req, ms, sus, err), err
+	}
+
+	reconcilers, err := stackComponentReconcilers(ems,
 		rm.instanceSelectorKey,
 		rm.instanceSelectorValue,
 		rm.thanos,
 		rm.prometheus,
 		rm.alertmanager,
+		rm.blackboxExporter,
+		federationSources,
+		sidecarGRPCTLSSecret,
 	)
+	if err != nil {
+		// The configuration is invalid: report the error and wait for the
+		// stack to be updated.
+		logger.Info("invalid monitoring stack configuration", "err", err)
+		return rm.updateStatus(ctx, req, ms, sus, err), nil
+	}
 	for _, reconciler := range reconcilers {
 		err := reconciler.Reconcile(ctx, rm.k8sClient, rm.scheme)
 		// handle create / update errors that can happen due to a stale cache by
@@ -197,14 +328,57 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
 		}
 		if err != nil {
-			return rm.updateStatus(ctx, req, ms, err), err
+			return rm.updateStatus(ctx, req, ms, sus, err), err
 		}
 	}
 
-	return rm.updateStatus(ctx, req, ms, nil), nil
+	return withHibernationRequeue(rm.updateStatus(ctx, req, ms, sus, nil), sus), nil
+}
+
+// withHibernationRequeue makes sure that the stack is reconciled again at the
+// next transition of its hibernation schedule.
+func withHibernationRequeue(res ctrl.Result, sus suspension) ctrl.Result {
+	if sus.next.IsZero() {
+		return res
+	}
+
+	// Add a small delay to be sure to be past the scheduled time.
+	next := time.Until(sus.next) + time.Second
+	if res.RequeueAfter == 0 || next < res.RequeueAfter {
+		res.RequeueAfter = next
+	}
+	return res
 }
 
-func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms *stack.MonitoringStack, recError error) ctrl.Result {
+// scaleDown scales the Prometheus, Alertmanager and blackbox exporter
+// instances of the stack to zero replicas. The rest of the specification is
+// left untouched so that the persistent volumes are kept and the stack can be
+// resumed as it was: the replicas are restored by the reconcilers on resume.
+func (rm resourceManager) scaleDown(ctx context.Context, ms *stack.MonitoringStack) error {
+	patch := client.RawPatch(types.MergePatchType, []byte(`{"spec":{"replicas":0}}`))
+
+	for _, obj := range []client.Object{
+		&monv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Name: ms.Name, Namespace: ms.Namespace}},
+		&monv1.Alertmanager{ObjectMeta: metav1.ObjectMeta{Name: ms.Name, Namespace: ms.Namespace}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: blackboxExporterName(ms), Namespace: ms.Namespace}},
+	} {
+		key := client.ObjectKeyFromObject(obj)
+		if err := rm.k8sClient.Get(ctx, key, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if err := rm.k8sClient.Patch(ctx, obj, patch, client.FieldOwner("observability-operator")); err != nil {
+			return fmt.Errorf("failed to scale down %s (%T): %w", key, obj, err)
+		}
+	}
+
+	return nil
+}
+
+func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms *stack.MonitoringStack, sus suspension, recError error) ctrl.Result {
 	var prom monv1.Prometheus
 	logger := rm.logger.WithValues("stack", req.NamespacedName)
 	key := client.ObjectKey{
@@ -212,11 +386,14 @@ func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms
 		Namespace: ms.Namespace,
 	}
 	err := rm.k8sClient.Get(ctx, key, &prom)
-	if err != nil {
+	if err != nil && !errors.IsNotFound(err) {
 		logger.Info("Failed to get prometheus object", "err", err)
 		return ctrl.Result{RequeueAfter: 2 * time.Second}
 	}
-	ms.Status.Conditions = updateConditions(ms, prom, recError)
+	// When the Prometheus object doesn't exist (yet), the conditions are
+	// reported as unknown. There's no need to requeue since the controller
+	// is notified when the Prometheus object is created.
+	ms.Status.Conditions = updateConditions(ms, prom, sus, recError)
 	err = rm.k8sClient.Status().Update(ctx, ms)
 	if err != nil {
 		logger.Info("Failed to update status", "err", err)
@@ -225,6 +402,37 @@ func (rm resourceManager) updateStatus(ctx context.Context, req ctrl.Request, ms
 	return ctrl.Result{}
 }
 
+// getTemplate returns the MonitoringStackTemplate referenced by the stack, if
+// any.
+func (rm resourceManager) getTemplate(ctx context.Context, ms *stack.MonitoringStack) (*stack.MonitoringStackTemplate, error) {
+	if ms.Spec.Template == "" {
+		return nil, nil
+	}
+
+	tmpl := &stack.MonitoringStackTemplate{}
+	if err := rm.k8sClient.Get(ctx, client.ObjectKey{Name: ms.Spec.Template}, tmpl); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// findStacksForTemplate returns the MonitoringStacks referencing the given
+// MonitoringStackTemplate.
+func (rm resourceManager) findStacksForTemplate(ctx context.Context, tmpl client.Object) []reconcile.Request {
+	stacks := &stack.MonitoringStackList{}
+	if err := rm.k8sClient.List(ctx, stacks, client.MatchingFields{templateIndexKey: tmpl.GetName()}); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stacks", "template", tmpl.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(stacks.Items))
+	for _, ms := range stacks.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&ms)})
+	}
+	return requests
+}
+
 func (rm resourceManager) getStack(ctx context.Context, req ctrl.Request) (*stack.MonitoringStack, error) {
 	logger := rm.logger.WithValues("stack", req.NamespacedName)
 
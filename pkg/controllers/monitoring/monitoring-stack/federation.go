@@ -0,0 +1,215 @@
+package monitoringstack
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/selection"
+)
+
+// federationSource is a MonitoringStack federated by another stack.
+type federationSource struct {
+	ms *stack.MonitoringStack
+	// Certificate authority of the web server of the federated stack, nil
+	// when it doesn't serve its API over TLS.
+	ca []byte
+}
+
+func federationCASecretName(ms *stack.MonitoringStack) string {
+	return ms.Name + "-federation-ca"
+}
+
+func federationCAKey(src *stack.MonitoringStack) string {
+	return fmt.Sprintf("%s_%s_ca.crt", src.Namespace, src.Name)
+}
+
+func hasFederationCA(sources []federationSource) bool {
+	for _, src := range sources {
+		if src.ca != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newFederationCASecret returns the secret holding the certificate
+// authorities of the federated stacks. The certificate authorities are copied
+// since Prometheus can only mount secrets from its own namespace.
+func newFederationCASecret(ms *stack.MonitoringStack, sources []federationSource, instanceSelectorKey string, instanceSelectorValue string) *corev1.Secret {
+	name := federationCASecretName(ms)
+	data := map[string][]byte{}
+	for _, src := range sources {
+		if src.ca != nil {
+			data[federationCAKey(src.ms)] = src.ca
+		}
+	}
+
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		Data: data,
+	}
+}
+
+// federationScrapeConfigs returns the scrape jobs collecting the series of
+// the federated stacks.
+func federationScrapeConfigs(ms *stack.MonitoringStack, sources []federationSource, jobOptions string) string {
+	fed := ms.Spec.Federation
+	if fed == nil {
+		return ""
+	}
+
+	honorLabels := fed.HonorLabels == nil || *fed.HonorLabels
+
+	var b strings.Builder
+	for _, src := range sources {
+		fmt.Fprintf(&b, "\n- job_name: federate/%s/%s%s", src.ms.Namespace, src.ms.Name, jobOptions)
+		if fed.Interval != nil {
+			fmt.Fprintf(&b, "\n  scrape_interval: %s", *fed.Interval)
+		}
+		fmt.Fprintf(&b, "\n  honor_labels: %t", honorLabels)
+		b.WriteString("\n  metrics_path: /federate")
+		b.WriteString("\n  params:\n    match[]:")
+		for _, m := range fed.Match {
+			fmt.Fprintf(&b, "\n    - %q", m)
+		}
+
+		if src.ca != nil {
+			b.WriteString("\n  scheme: https")
+			fmt.Fprintf(&b, "\n  tls_config:\n    ca_file: %q\n    server_name: %q",
+				filepath.Join(prometheusSecretsMountPoint, federationCASecretName(ms), federationCAKey(src.ms)),
+				src.ms.Name+"-prometheus",
+			)
+		} else {
+			b.WriteString("\n  scheme: http")
+		}
+
+		fmt.Fprintf(&b, "\n  static_configs:\n  - targets:\n    - %s-prometheus.%s.svc:9090", src.ms.Name, src.ms.Namespace)
+	}
+
+	return b.String()
+}
+
+// federationSources returns the stacks federated by the given stack, sorted
+// by namespace and name.
+func (rm resourceManager) federationSources(ctx context.Context, ms *stack.MonitoringStack) ([]federationSource, error) {
+	fed := ms.Spec.Federation
+	if fed == nil {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&fed.StackSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid federation stack selector: %w", err)
+	}
+
+	namespaces, err := selection.Namespaces(ctx, rm.k8sClient, ms.Namespace, fed.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []federationSource
+	for _, ns := range namespaces {
+		stacks := &stack.MonitoringStackList{}
+		if err := rm.k8sClient.List(ctx, stacks, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+
+		for i := range stacks.Items {
+			src := &stacks.Items[i]
+			if (src.Namespace == ms.Namespace && src.Name == ms.Name) || !src.DeletionTimestamp.IsZero() {
+				continue
+			}
+
+			ca, err := rm.federationCA(ctx, src)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, federationSource{ms: src, ca: ca})
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].ms.Namespace != sources[j].ms.Namespace {
+			return sources[i].ms.Namespace < sources[j].ms.Namespace
+		}
+		return sources[i].ms.Name < sources[j].ms.Name
+	})
+
+	return sources, nil
+}
+
+// federationCA returns the certificate authority of the web server of the
+// stack if it serves its API over TLS.
+func (rm resourceManager) federationCA(ctx context.Context, ms *stack.MonitoringStack) ([]byte, error) {
+	if ms.Spec.PrometheusConfig == nil || ms.Spec.PrometheusConfig.WebTLSConfig == nil {
+		return nil, nil
+	}
+
+	ref := ms.Spec.PrometheusConfig.WebTLSConfig.CertificateAuthority
+	secret := &corev1.Secret{}
+	if err := rm.apiReader.Get(ctx, client.ObjectKey{Namespace: ms.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the certificate authority of federated stack %s/%s: %w", ms.Namespace, ms.Name, err)
+	}
+
+	ca, found := secret.Data[ref.Key]
+	if !found {
+		return nil, fmt.Errorf("key %q not found in secret %s/%s", ref.Key, ms.Namespace, ref.Name)
+	}
+	return ca, nil
+}
+
+// findFederatingStacks returns the stacks federating the given stack.
+func (rm resourceManager) findFederatingStacks(ctx context.Context, obj client.Object) []reconcile.Request {
+	stacks := &stack.MonitoringStackList{}
+	if err := rm.k8sClient.List(ctx, stacks); err != nil {
+		rm.logger.Error(err, "failed to list monitoring stacks")
+		return nil
+	}
+
+	ns := selection.GetNamespace(ctx, rm.k8sClient, obj.GetNamespace())
+
+	var requests []reconcile.Request
+	for i := range stacks.Items {
+		ms := &stacks.Items[i]
+		if ms.Namespace == obj.GetNamespace() && ms.Name == obj.GetName() {
+			continue
+		}
+		if federates(ms, obj, ns) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+		}
+	}
+	return requests
+}
+
+// federates returns true if the stack federates the given stack.
+func federates(ms *stack.MonitoringStack, src client.Object, ns *corev1.Namespace) bool {
+	fed := ms.Spec.Federation
+	if fed == nil {
+		return false
+	}
+
+	if src.GetNamespace() != ns.Name || !selection.MatchesNamespace(ms.Namespace, fed.NamespaceSelector, ns) {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&fed.StackSelector)
+	return err == nil && selector.Matches(labels.Set(src.GetLabels()))
+}
@@ -0,0 +1,121 @@
+package monitoringstack
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newFederatedStack(name string, namespace string, lbls map[string]string) *stack.MonitoringStack {
+	return &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: lbls},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{},
+		},
+	}
+}
+
+func newFederatingStack(nsSelector *metav1.LabelSelector) *stack.MonitoringStack {
+	ms := newFederatedStack("global", "ns", map[string]string{"federated": "true"})
+	ms.Spec.Federation = &stack.FederationConfig{
+		StackSelector:     metav1.LabelSelector{MatchLabels: map[string]string{"federated": "true"}},
+		NamespaceSelector: nsSelector,
+		Match:             []string{`{job="app"}`},
+	}
+	return ms
+}
+
+func TestFederationSources(t *testing.T) {
+	federated := map[string]string{"federated": "true"}
+
+	withTLS := newFederatedStack("tls", "other", federated)
+	withTLS.Spec.PrometheusConfig.WebTLSConfig = &stack.WebTLSConfig{
+		CertificateAuthority: stack.SecretKeySelector{Name: "certs", Key: "ca.crt"},
+	}
+
+	objs := []client.Object{
+		newFederatedStack("local", "ns", federated),
+		newFederatedStack("not-selected", "ns", nil),
+		newFederatedStack("remote", "other", federated),
+		newFederatedStack("unlabeled-namespace", "unlabeled", federated),
+		withTLS,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{"monitoring": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"monitoring": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	}
+	certs := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "certs", Namespace: "other"},
+		Data:       map[string][]byte{"ca.crt": []byte("ca")},
+	}
+
+	sourceNames := func(sources []federationSource) []string {
+		var names []string
+		for _, src := range sources {
+			names = append(names, src.ms.Namespace+"/"+src.ms.Name)
+		}
+		return names
+	}
+
+	// Without a namespace selector, only the stacks from the same namespace
+	// are federated and the stack never federates itself.
+	ms := newFederatingStack(nil)
+	rm := newTestManager(append(objs, certs, ms)...)
+	sources, err := rm.federationSources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sourceNames(sources), []string{"ns/local"})
+	assert.Assert(t, !hasFederationCA(sources))
+
+	ms = newFederatingStack(&metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}})
+	rm = newTestManager(append(objs, certs, ms)...)
+	sources, err = rm.federationSources(context.Background(), ms)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sourceNames(sources), []string{"ns/local", "other/remote", "other/tls"})
+	assert.Assert(t, hasFederationCA(sources))
+
+	secret := newFederationCASecret(ms, sources, "key", "value")
+	assert.Equal(t, secret.Name, "global-federation-ca")
+	assert.Equal(t, secret.Namespace, "ns")
+	assert.DeepEqual(t, secret.Data, map[string][]byte{"other_tls_ca.crt": []byte("ca")})
+
+	// The certificate authority of the federated stack is required.
+	rm = newTestManager(append(objs, ms)...)
+	_, err = rm.federationSources(context.Background(), ms)
+	assert.ErrorContains(t, err, "failed to get the certificate authority of federated stack other/tls")
+}
+
+func TestFindFederatingStacks(t *testing.T) {
+	local := newFederatingStack(nil)
+	local.Name = "local"
+	clusterWide := newFederatingStack(&metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}})
+	clusterWide.Name = "cluster-wide"
+
+	rm := newTestManager(
+		local, clusterWide,
+		newFederatedStack("no-federation", "ns", nil),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{"monitoring": "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"monitoring": "true"}}},
+	)
+
+	assert.DeepEqual(t, rm.findFederatingStacks(context.Background(), newFederatedStack("src", "ns", map[string]string{"federated": "true"})), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "cluster-wide"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "local"}},
+	})
+
+	assert.DeepEqual(t, rm.findFederatingStacks(context.Background(), newFederatedStack("src", "other", map[string]string{"federated": "true"})), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "cluster-wide"}},
+	})
+
+	// A stack doesn't federate itself.
+	assert.DeepEqual(t, rm.findFederatingStacks(context.Background(), local), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "cluster-wide"}},
+	})
+
+	assert.Equal(t, len(rm.findFederatingStacks(context.Background(), newFederatedStack("src", "ns", nil))), 0)
+}
@@ -0,0 +1,88 @@
+package monitoringstack
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+func managedSidecarGRPCTLSSecretName(ms *stack.MonitoringStack) string {
+	return ms.Name + "-grpc-tls"
+}
+
+// sidecarGRPCTLSSecretName returns the name of the secret mounted by the
+// Thanos sidecar to serve gRPC over mutual TLS.
+func sidecarGRPCTLSSecretName(ms *stack.MonitoringStack) string {
+	if tls := ms.Spec.PrometheusConfig.ThanosSidecarGRPCTLS; tls != nil && tls.SecretName != "" {
+		return tls.SecretName
+	}
+	return managedSidecarGRPCTLSSecretName(ms)
+}
+
+// sidecarGRPCTLSSecret returns the secret holding the server certificate of
+// the Thanos sidecar issued by the operator. It returns nil when mutual TLS
+// is disabled or when the secret is provided by the user.
+func (rm resourceManager) sidecarGRPCTLSSecret(ctx context.Context, ms *stack.MonitoringStack) (*corev1.Secret, error) {
+	tls := ms.Spec.PrometheusConfig.ThanosSidecarGRPCTLS
+	if tls == nil || tls.SecretName != "" {
+		return nil, nil
+	}
+
+	ca, err := certificates.GetCA(ctx, rm.k8sClient, rm.apiReader, rm.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the certificate authority: %w", err)
+	}
+	clientCA, err := certificates.GetClientCA(ctx, rm.k8sClient, rm.apiReader, rm.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the client certificate authority: %w", err)
+	}
+
+	// The certificate is only valid for the names of the sidecar Service so
+	// that the sidecars of the other stacks can't impersonate it. The
+	// sidecar only trusts the client certificates issued to the queriers.
+	serviceName := ms.Name + "-thanos-sidecar"
+	secret, err := ca.NewSecret(ctx, rm.k8sClient, managedSidecarGRPCTLSSecretName(ms), ms.Namespace, certificates.Request{
+		CommonName: serviceName,
+		DNSNames: []string{
+			serviceName,
+			fmt.Sprintf("%s.%s.svc", serviceName, ms.Namespace),
+		},
+		Usage:  x509.ExtKeyUsageServerAuth,
+		PeerCA: clientCA,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue the certificate of the Thanos sidecar: %w", err)
+	}
+
+	secret.Labels = objectLabels(secret.Name, ms.Name, rm.instanceSelectorKey, rm.instanceSelectorValue)
+	return secret, nil
+}
+
+// sidecarGRPCTLSReconcilers apply the secret issued by the operator or
+// delete it when it isn't needed anymore. A secret provided by the user is
+// left untouched, even if it has the name of the generated secret.
+func sidecarGRPCTLSReconcilers(ms *stack.MonitoringStack, secret *corev1.Secret) []reconciler.Reconciler {
+	if secret != nil {
+		return []reconciler.Reconciler{reconciler.NewUpdater(secret, ms)}
+	}
+
+	if tls := ms.Spec.PrometheusConfig.ThanosSidecarGRPCTLS; tls != nil && tls.SecretName == managedSidecarGRPCTLSSecretName(ms) {
+		return nil
+	}
+
+	return []reconciler.Reconciler{
+		reconciler.NewDeleter(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      managedSidecarGRPCTLSSecretName(ms),
+				Namespace: ms.Namespace,
+			},
+		}),
+	}
+}
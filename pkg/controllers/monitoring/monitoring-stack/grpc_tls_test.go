@@ -0,0 +1,96 @@
+package monitoringstack
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+func newGRPCTLSStack(tls *stack.GRPCTLSConfig) *stack.MonitoringStack {
+	return &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			PrometheusConfig: &stack.PrometheusConfig{ThanosSidecarGRPCTLS: tls},
+		},
+	}
+}
+
+func TestSidecarGRPCTLSSecret(t *testing.T) {
+	rm := newTestManager()
+	rm.namespace = "operator"
+
+	secret, err := rm.sidecarGRPCTLSSecret(context.Background(), newGRPCTLSStack(nil))
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+
+	secret, err = rm.sidecarGRPCTLSSecret(context.Background(), newGRPCTLSStack(&stack.GRPCTLSConfig{}))
+	assert.NilError(t, err)
+	assert.Equal(t, secret.Name, "ms-grpc-tls")
+	assert.Equal(t, secret.Namespace, "ns")
+	assert.Assert(t, len(secret.Data[certificates.CAKey]) > 0)
+
+	block, _ := pem.Decode(secret.Data[certificates.CertificateKey])
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cert.DNSNames, []string{"ms-thanos-sidecar", "ms-thanos-sidecar.ns.svc"})
+	assert.DeepEqual(t, cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+
+	// The certificate authorities are stored in the namespace of the
+	// operator and the sidecar only trusts the client one.
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "operator", Name: "observability-operator-grpc-ca"}, &corev1.Secret{}))
+	clientCA := &corev1.Secret{}
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "operator", Name: "observability-operator-grpc-client-ca"}, clientCA))
+	assert.DeepEqual(t, secret.Data[certificates.CAKey], clientCA.Data[certificates.CertificateKey])
+
+	// Secrets provided by the user aren't generated.
+	secret, err = rm.sidecarGRPCTLSSecret(context.Background(), newGRPCTLSStack(&stack.GRPCTLSConfig{SecretName: "custom"}))
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+}
+
+func TestSidecarGRPCTLSReconcilers(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ms-grpc-tls", Namespace: "ns"}}
+	ms := newGRPCTLSStack(&stack.GRPCTLSConfig{})
+	reconcilers := sidecarGRPCTLSReconcilers(ms, secret)
+	assert.Equal(t, len(reconcilers), 1)
+	assert.Assert(t, is[reconciler.Updater](reconcilers[0]))
+
+	// The generated secret is deleted when it isn't needed anymore...
+	for _, tls := range []*stack.GRPCTLSConfig{nil, {SecretName: "custom"}} {
+		reconcilers = sidecarGRPCTLSReconcilers(newGRPCTLSStack(tls), nil)
+		assert.Equal(t, len(reconcilers), 1)
+		assert.Assert(t, is[reconciler.Deleter](reconcilers[0]))
+	}
+
+	// ... unless it is provided by the user.
+	reconcilers = sidecarGRPCTLSReconcilers(newGRPCTLSStack(&stack.GRPCTLSConfig{SecretName: "ms-grpc-tls"}), nil)
+	assert.Equal(t, len(reconcilers), 0)
+}
+
+func is[T any](v any) bool {
+	_, ok := v.(T)
+	return ok
+}
+
+func TestNewPrometheusSidecarGRPCTLS(t *testing.T) {
+	ms := newGRPCTLSStack(nil)
+	p := newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+	assert.Assert(t, p.Spec.Thanos.GRPCServerTLSConfig == nil)
+
+	ms = newGRPCTLSStack(&stack.GRPCTLSConfig{SecretName: "custom"})
+	p = newPrometheus(ms, "ms-prometheus", "ms-prometheus-additional-scrape-configs", "key", "value", ThanosConfiguration{}, PrometheusConfiguration{}, false)
+	assert.DeepEqual(t, p.Spec.Secrets, []string{"custom"})
+	assert.Equal(t, p.Spec.Thanos.GRPCServerTLSConfig.CAFile, "/etc/prometheus/secrets/custom/ca.crt")
+	assert.Equal(t, p.Spec.Thanos.GRPCServerTLSConfig.CertFile, "/etc/prometheus/secrets/custom/tls.crt")
+	assert.Equal(t, p.Spec.Thanos.GRPCServerTLSConfig.KeyFile, "/etc/prometheus/secrets/custom/tls.key")
+}
@@ -0,0 +1,81 @@
+package monitoringstack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// suspension describes whether a MonitoringStack should be suspended at a
+// given point in time.
+type suspension struct {
+	suspended bool
+	reason    string
+	message   string
+
+	// next is the time of the next scheduled transition. It is zero when no
+	// hibernation schedule is configured.
+	next time.Time
+}
+
+// getSuspension returns the suspension state of the MonitoringStack at the
+// given time. The stack is suspended either explicitly with spec.suspend or
+// when the next wake-up of the hibernation schedule comes before the next
+// sleep.
+func getSuspension(ms *stack.MonitoringStack, now time.Time) (suspension, error) {
+	if ms.Spec.Suspend {
+		return suspension{
+			suspended: true,
+			reason:    SuspendedReason,
+			message:   SuspendedMessage,
+		}, nil
+	}
+
+	h := ms.Spec.Hibernation
+	if h == nil {
+		return suspension{reason: NotSuspendedReason, message: NotSuspendedMessage}, nil
+	}
+
+	loc := time.UTC
+	if h.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(h.TimeZone)
+		if err != nil {
+			return suspension{}, fmt.Errorf("invalid hibernation time zone %q: %w", h.TimeZone, err)
+		}
+	}
+
+	sleep, err := cron.ParseStandard(h.SleepSchedule)
+	if err != nil {
+		return suspension{}, fmt.Errorf("invalid hibernation sleep schedule %q: %w", h.SleepSchedule, err)
+	}
+
+	wake, err := cron.ParseStandard(h.WakeSchedule)
+	if err != nil {
+		return suspension{}, fmt.Errorf("invalid hibernation wake schedule %q: %w", h.WakeSchedule, err)
+	}
+
+	now = now.In(loc)
+	nextSleep, nextWake := sleep.Next(now), wake.Next(now)
+	if nextSleep.IsZero() || nextWake.IsZero() {
+		return suspension{}, fmt.Errorf("hibernation schedules %q and %q never fire", h.SleepSchedule, h.WakeSchedule)
+	}
+
+	if nextWake.Before(nextSleep) {
+		return suspension{
+			suspended: true,
+			reason:    HibernatingReason,
+			message:   fmt.Sprintf("Monitoring Stack is hibernating until %s", nextWake.UTC().Format(time.RFC3339)),
+			next:      nextWake,
+		}, nil
+	}
+
+	return suspension{
+		reason:  NotSuspendedReason,
+		message: fmt.Sprintf("Monitoring Stack will hibernate at %s", nextSleep.UTC().Format(time.RFC3339)),
+		next:    nextSleep,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package monitoringstack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestGetSuspension(t *testing.T) {
+	workingHours := &stack.HibernationConfig{
+		SleepSchedule: "0 19 * * 1-5",
+		WakeSchedule:  "0 8 * * 1-5",
+	}
+	// Wednesday.
+	day := time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name      string
+		spec      stack.MonitoringStackSpec
+		now       time.Time
+		suspended bool
+		reason    string
+		next      time.Time
+		err       bool
+	}{
+		{
+			name:   "not suspended",
+			now:    day.Add(12 * time.Hour),
+			reason: NotSuspendedReason,
+		},
+		{
+			name:      "suspended",
+			spec:      stack.MonitoringStackSpec{Suspend: true, Hibernation: workingHours},
+			now:       day.Add(12 * time.Hour),
+			suspended: true,
+			reason:    SuspendedReason,
+		},
+		{
+			name:   "during working hours",
+			spec:   stack.MonitoringStackSpec{Hibernation: workingHours},
+			now:    day.Add(12 * time.Hour),
+			reason: NotSuspendedReason,
+			next:   day.Add(19 * time.Hour),
+		},
+		{
+			name:      "during the night",
+			spec:      stack.MonitoringStackSpec{Hibernation: workingHours},
+			now:       day.Add(22 * time.Hour),
+			suspended: true,
+			reason:    HibernatingReason,
+			next:      day.Add(32 * time.Hour),
+		},
+		{
+			name:      "during the weekend",
+			spec:      stack.MonitoringStackSpec{Hibernation: workingHours},
+			now:       day.Add(3*24*time.Hour + 12*time.Hour),
+			suspended: true,
+			reason:    HibernatingReason,
+			next:      day.Add(5*24*time.Hour + 8*time.Hour),
+		},
+		{
+			name: "time zone",
+			spec: stack.MonitoringStackSpec{Hibernation: &stack.HibernationConfig{
+				SleepSchedule: workingHours.SleepSchedule,
+				WakeSchedule:  workingHours.WakeSchedule,
+				TimeZone:      "Asia/Tokyo",
+			}},
+			// 21:00 in Tokyo.
+			now:       day.Add(12 * time.Hour),
+			suspended: true,
+			reason:    HibernatingReason,
+			// 08:00 in Tokyo.
+			next: day.Add(23 * time.Hour),
+		},
+		{
+			name: "invalid schedule",
+			spec: stack.MonitoringStackSpec{Hibernation: &stack.HibernationConfig{
+				SleepSchedule: "every day",
+				WakeSchedule:  workingHours.WakeSchedule,
+			}},
+			now: day,
+			err: true,
+		},
+		{
+			name: "invalid time zone",
+			spec: stack.MonitoringStackSpec{Hibernation: &stack.HibernationConfig{
+				SleepSchedule: workingHours.SleepSchedule,
+				WakeSchedule:  workingHours.WakeSchedule,
+				TimeZone:      "Mars/Olympus_Mons",
+			}},
+			now: day,
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := getSuspension(&stack.MonitoringStack{Spec: tc.spec}, tc.now)
+			if tc.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, s.suspended, tc.suspended)
+			assert.Equal(t, s.reason, tc.reason)
+			assert.Assert(t, s.next.Equal(tc.next), "expected next transition at %s, got %s", tc.next, s.next)
+		})
+	}
+}
+
+func TestScaleDown(t *testing.T) {
+	ms := newProbingStack(&stack.ProbingConfig{Enabled: true})
+	prom := &monv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: ms.Name, Namespace: ms.Namespace},
+		Spec: monv1.PrometheusSpec{
+			CommonPrometheusFields: monv1.CommonPrometheusFields{Replicas: ptr.To(int32(2))},
+		},
+	}
+	blackbox := newBlackboxExporterDeployment(ms, "", "key", "value", BlackboxExporterConfiguration{})
+
+	// The Alertmanager is disabled and doesn't exist.
+	rm := newTestManager(ms, prom, blackbox)
+	assert.NilError(t, rm.scaleDown(context.Background(), ms))
+
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(prom), prom))
+	assert.Equal(t, *prom.Spec.Replicas, int32(0))
+
+	got := &appsv1.Deployment{}
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(blackbox), got))
+	assert.Equal(t, *got.Spec.Replicas, int32(0))
+}
@@ -0,0 +1,234 @@
+package monitoringstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	blackboxExporterPort             = 9115
+	blackboxExporterConfigKey        = "config.yml"
+	blackboxExporterConfigMountPoint = "/etc/blackbox-exporter"
+
+	// blackboxExporterConfigHashAnnotation rolls the blackbox exporter pods
+	// out when the configuration changes.
+	blackboxExporterConfigHashAnnotation = "monitoring.rhobs/config-hash"
+)
+
+// defaultProbeModules are the modules of the blackbox exporter available to
+// all the stacks.
+var defaultProbeModules = map[string]stack.ProbeModule{
+	"http_2xx":      {Prober: "http"},
+	"http_post_2xx": {Prober: "http", Config: &apiextensionsv1.JSON{Raw: []byte(`{"method":"POST"}`)}},
+	"tcp_connect":   {Prober: "tcp"},
+}
+
+func probingEnabled(ms *stack.MonitoringStack) bool {
+	return ms.Spec.Probing != nil && ms.Spec.Probing.Enabled
+}
+
+func blackboxExporterName(ms *stack.MonitoringStack) string {
+	return ms.Name + "-blackbox-exporter"
+}
+
+// newBlackboxExporterConfig returns the configuration file of the blackbox
+// exporter with the default modules and the modules of the stack.
+func newBlackboxExporterConfig(ms *stack.MonitoringStack) (string, error) {
+	modules := maps.Clone(defaultProbeModules)
+	if ms.Spec.Probing != nil {
+		maps.Copy(modules, ms.Spec.Probing.Modules)
+	}
+
+	cfg := make(map[string]map[string]any, len(modules))
+	for name, m := range modules {
+		module := map[string]any{"prober": m.Prober}
+		if m.Timeout != "" {
+			module["timeout"] = string(m.Timeout)
+		}
+		if m.Config != nil && len(m.Config.Raw) > 0 {
+			var proberCfg map[string]any
+			if err := json.Unmarshal(m.Config.Raw, &proberCfg); err != nil {
+				return "", fmt.Errorf("invalid configuration of probing module %q: %w", name, err)
+			}
+			module[m.Prober] = proberCfg
+		}
+		cfg[name] = module
+	}
+
+	b, err := yaml.Marshal(map[string]any{"modules": cfg})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate the blackbox exporter configuration: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func newBlackboxExporterConfigMap(ms *stack.MonitoringStack, config string, instanceSelectorKey string, instanceSelectorValue string) *corev1.ConfigMap {
+	name := blackboxExporterName(ms)
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		Data: map[string]string{
+			blackboxExporterConfigKey: config,
+		},
+	}
+}
+
+func newBlackboxExporterDeployment(
+	ms *stack.MonitoringStack,
+	config string,
+	instanceSelectorKey string,
+	instanceSelectorValue string,
+	blackboxExporterCfg BlackboxExporterConfiguration,
+) *appsv1.Deployment {
+	name := blackboxExporterName(ms)
+	probing := ms.Spec.Probing
+
+	replicas := probing.Replicas
+	if replicas == nil {
+		replicas = ptr.To(int32(1))
+	}
+
+	resources := probing.Resources
+	if isEmptyResources(resources) {
+		resources = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+		}
+	}
+
+	args := []string{
+		"--config.file=" + filepath.Join(blackboxExporterConfigMountPoint, blackboxExporterConfigKey),
+		fmt.Sprintf("--web.listen-address=:%d", blackboxExporterPort),
+	}
+	if ms.Spec.LogLevel != "" {
+		args = append(args, "--log.level="+string(ms.Spec.LogLevel))
+	}
+
+	hash := sha256.Sum256([]byte(config))
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: podLabels("blackbox-exporter", ms.Name),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: podLabels("blackbox-exporter", ms.Name),
+					Annotations: map[string]string{
+						blackboxExporterConfigHashAnnotation: hex.EncodeToString(hash[:8]),
+					},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: ptr.To(false),
+					NodeSelector:                 ms.Spec.NodeSelector,
+					Tolerations:                  ms.Spec.Tolerations,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: ptr.To(true),
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+					Containers: []corev1.Container{{
+						Name:      "blackbox-exporter",
+						Image:     blackboxExporterCfg.Image,
+						Args:      args,
+						Resources: resources,
+						Ports: []corev1.ContainerPort{{
+							Name:          "http",
+							ContainerPort: blackboxExporterPort,
+						}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/-/healthy",
+									Port: intstr.FromString("http"),
+								},
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "config",
+							MountPath: blackboxExporterConfigMountPoint,
+							ReadOnly:  true,
+						}},
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: ptr.To(false),
+							ReadOnlyRootFilesystem:   ptr.To(true),
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{"ALL"},
+							},
+						},
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "config",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: name},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func newBlackboxExporterService(ms *stack.MonitoringStack, instanceSelectorKey string, instanceSelectorValue string) *corev1.Service {
+	name := blackboxExporterName(ms)
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    objectLabels(name, ms.Name, instanceSelectorKey, instanceSelectorValue),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: podLabels("blackbox-exporter", ms.Name),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       blackboxExporterPort,
+					TargetPort: intstr.FromString("http"),
+				},
+			},
+		},
+	}
+}
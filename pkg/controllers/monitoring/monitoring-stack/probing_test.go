@@ -0,0 +1,103 @@
+package monitoringstack
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+)
+
+func newProbingStack(probing *stack.ProbingConfig) *stack.MonitoringStack {
+	return &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			Probing:          probing,
+		},
+	}
+}
+
+func TestNewBlackboxExporterConfig(t *testing.T) {
+	ms := newProbingStack(&stack.ProbingConfig{
+		Enabled: true,
+		Modules: map[string]stack.ProbeModule{
+			"http_2xx": {
+				Prober:  "http",
+				Timeout: "5s",
+				Config:  &apiextensionsv1.JSON{Raw: []byte(`{"valid_status_codes":[200,204],"preferred_ip_protocol":"ip4"}`)},
+			},
+			"icmp": {Prober: "icmp"},
+		},
+	})
+
+	cfg, err := newBlackboxExporterConfig(ms)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg, `modules:
+    http_2xx:
+        http:
+            preferred_ip_protocol: ip4
+            valid_status_codes:
+                - 200
+                - 204
+        prober: http
+        timeout: 5s
+    http_post_2xx:
+        http:
+            method: POST
+        prober: http
+    icmp:
+        prober: icmp
+    tcp_connect:
+        prober: tcp
+`)
+
+	ms.Spec.Probing.Modules["invalid"] = stack.ProbeModule{Prober: "http", Config: &apiextensionsv1.JSON{Raw: []byte(`[]`)}}
+	_, err = newBlackboxExporterConfig(ms)
+	assert.ErrorContains(t, err, `invalid configuration of probing module "invalid"`)
+}
+
+func TestNewBlackboxExporterDeployment(t *testing.T) {
+	ms := newProbingStack(&stack.ProbingConfig{Enabled: true})
+	ms.Spec.LogLevel = stack.Debug
+
+	d := newBlackboxExporterDeployment(ms, "modules: {}", "key", "value", BlackboxExporterConfiguration{Image: "blackbox"})
+	assert.Equal(t, d.Name, "ms-blackbox-exporter")
+	assert.Equal(t, *d.Spec.Replicas, int32(1))
+
+	c := d.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, c.Image, "blackbox")
+	assert.DeepEqual(t, c.Args, []string{
+		"--config.file=/etc/blackbox-exporter/config.yml",
+		"--web.listen-address=:9115",
+		"--log.level=debug",
+	})
+	assert.Equal(t, c.Resources.Requests.Cpu().String(), "10m")
+
+	// The pods are rolled out when the configuration changes.
+	hash := d.Spec.Template.Annotations[blackboxExporterConfigHashAnnotation]
+	assert.Assert(t, hash != "")
+	d = newBlackboxExporterDeployment(ms, "modules: {foo: {prober: http}}", "key", "value", BlackboxExporterConfiguration{})
+	assert.Assert(t, d.Spec.Template.Annotations[blackboxExporterConfigHashAnnotation] != hash)
+
+	ms.Spec.Probing.Replicas = ptr.To(int32(3))
+	d = newBlackboxExporterDeployment(ms, "", "key", "value", BlackboxExporterConfiguration{})
+	assert.Equal(t, *d.Spec.Replicas, int32(3))
+}
+
+// newTestManager returns a resourceManager backed by a fake client.
+func newTestManager(objs ...client.Object) *resourceManager {
+	c := testutil.NewClientBuilder(objs...).Build()
+	return &resourceManager{
+		k8sClient: c,
+		apiReader: c,
+		scheme:    c.Scheme(),
+		logger:    logr.Discard(),
+	}
+}
@@ -0,0 +1,187 @@
+package monitoringstack
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	defaultLogLevel  = stack.Info
+	defaultRetention = monv1.Duration("120h")
+)
+
+// presetResources returns the resources associated to a sizing preset.
+func presetResources(preset stack.MonitoringStackPreset) corev1.ResourceRequirements {
+	resources := func(reqCPU, reqMem, limCPU, limMem string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(reqCPU),
+				corev1.ResourceMemory: resource.MustParse(reqMem),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(limCPU),
+				corev1.ResourceMemory: resource.MustParse(limMem),
+			},
+		}
+	}
+
+	switch preset {
+	case stack.MediumPreset:
+		return resources("500m", "1Gi", "1", "2Gi")
+	case stack.LargePreset:
+		return resources("1", "4Gi", "2", "8Gi")
+	default:
+		return resources("100m", "256Mi", "500m", "512Mi")
+	}
+}
+
+func isEmptyResources(r corev1.ResourceRequirements) bool {
+	return len(r.Limits) == 0 && len(r.Requests) == 0 && len(r.Claims) == 0
+}
+
+// effectiveSpec returns the specification of the MonitoringStack merged with
+// the given template (which may be nil) and the default values. Fields set in
+// the MonitoringStack always take precedence over the template.
+func effectiveSpec(ms *stack.MonitoringStack, tmpl *stack.MonitoringStackTemplate) (stack.MonitoringStackSpec, error) {
+	spec := *ms.Spec.DeepCopy()
+
+	if spec.PrometheusConfig == nil {
+		spec.PrometheusConfig = &stack.PrometheusConfig{}
+	}
+	if spec.PrometheusConfig.Replicas == nil {
+		spec.PrometheusConfig.Replicas = ptr.To(int32(2))
+	}
+
+	var t stack.MonitoringStackTemplateSpec
+	if tmpl != nil {
+		t = *tmpl.Spec.DeepCopy()
+		clearLegacyDefaults(&spec)
+	}
+
+	if spec.LogLevel == "" {
+		spec.LogLevel = t.LogLevel
+	}
+	if spec.LogLevel == "" {
+		spec.LogLevel = defaultLogLevel
+	}
+
+	if spec.Retention == "" {
+		spec.Retention = t.Retention
+	}
+	if spec.Retention == "" {
+		spec.Retention = defaultRetention
+	}
+
+	if isEmptyResources(spec.Resources) {
+		if t.Resources != nil {
+			spec.Resources = *t.Resources
+		} else {
+			spec.Resources = presetResources(t.Preset)
+		}
+	}
+
+	// Resources applied by the autoscaler take precedence over the
+	// configured ones.
+	if applied := autoscaledResources(ms); applied != nil {
+		spec.Resources = *applied
+	}
+
+	if len(spec.Tolerations) == 0 {
+		spec.Tolerations = t.Tolerations
+	}
+
+	if len(spec.NodeSelector) == 0 {
+		spec.NodeSelector = t.NodeSelector
+	}
+
+	spec.PrometheusConfig.RemoteWrite = mergeRemoteWrite(t.RemoteWrite, spec.PrometheusConfig.RemoteWrite)
+
+	if len(t.ExternalLabels) > 0 {
+		labels := maps.Clone(t.ExternalLabels)
+		maps.Copy(labels, spec.PrometheusConfig.ExternalLabels)
+		spec.PrometheusConfig.ExternalLabels = labels
+	}
+
+	if t.TLSMode == stack.TLSModeRequired {
+		if err := validateTLS(spec); err != nil {
+			return spec, fmt.Errorf("template %q requires TLS: %w", tmpl.Name, err)
+		}
+	}
+
+	return spec, nil
+}
+
+// clearLegacyDefaults unsets the fields of the stack equal to the values
+// defaulted by the CRD before the introduction of the templates. The API
+// server persisted them in the existing MonitoringStacks which would
+// otherwise never inherit the log level, the retention and the resources of
+// their template. The cleared fields get the same values back from the
+// defaults when the template doesn't set them.
+func clearLegacyDefaults(spec *stack.MonitoringStackSpec) {
+	if spec.LogLevel == defaultLogLevel {
+		spec.LogLevel = ""
+	}
+	if spec.Retention == defaultRetention {
+		spec.Retention = ""
+	}
+	if equality.Semantic.DeepEqual(spec.Resources, presetResources(stack.SmallPreset)) {
+		spec.Resources = corev1.ResourceRequirements{}
+	}
+}
+
+// autoscaledResources returns the resources applied by the autoscaler if
+// autoscaling is enabled for the stack.
+func autoscaledResources(ms *stack.MonitoringStack) *corev1.ResourceRequirements {
+	recs := ms.Spec.Recommendations
+	if recs == nil || !recs.Enabled || recs.Autoscale == nil || !recs.Autoscale.Enabled {
+		return nil
+	}
+	if ms.Status.Recommendations == nil || ms.Status.Recommendations.AppliedResources == nil {
+		return nil
+	}
+	return ms.Status.Recommendations.AppliedResources.DeepCopy()
+}
+
+// mergeRemoteWrite returns the remote write endpoints of the template followed
+// by the ones of the stack. Stack endpoints replace template endpoints with the
+// same URL.
+func mergeRemoteWrite(tmpl, ms []monv1.RemoteWriteSpec) []monv1.RemoteWriteSpec {
+	if len(tmpl) == 0 {
+		return ms
+	}
+
+	urls := make(map[string]struct{}, len(ms))
+	for _, rw := range ms {
+		urls[rw.URL] = struct{}{}
+	}
+
+	merged := make([]monv1.RemoteWriteSpec, 0, len(tmpl)+len(ms))
+	for _, rw := range tmpl {
+		if _, found := urls[rw.URL]; !found {
+			merged = append(merged, rw)
+		}
+	}
+
+	return append(merged, ms...)
+}
+
+func validateTLS(spec stack.MonitoringStackSpec) error {
+	var errs []error
+	if spec.PrometheusConfig.WebTLSConfig == nil {
+		errs = append(errs, errors.New("prometheusConfig.webTLSConfig must be set"))
+	}
+	if !spec.AlertmanagerConfig.Disabled && spec.AlertmanagerConfig.WebTLSConfig == nil {
+		errs = append(errs, errors.New("alertmanagerConfig.webTLSConfig must be set"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,272 @@
+package monitoringstack
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestEffectiveSpec(t *testing.T) {
+	tmpl := &stack.MonitoringStackTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: stack.MonitoringStackTemplateSpec{
+			Preset:    stack.MediumPreset,
+			LogLevel:  stack.Warn,
+			Retention: "30d",
+			RemoteWrite: []monv1.RemoteWriteSpec{
+				{URL: "https://central.example.com/api/v1/write"},
+				{URL: "https://backup.example.com/api/v1/write"},
+			},
+			ExternalLabels: map[string]string{"cluster": "prod", "team": "platform"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		spec     stack.MonitoringStackSpec
+		tmpl     *stack.MonitoringStackTemplate
+		expected func(*stack.MonitoringStackSpec)
+		err      string
+	}{
+		{
+			name: "defaults without template",
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.LogLevel = stack.Info
+				s.Retention = "120h"
+				s.Resources = presetResources(stack.SmallPreset)
+				s.PrometheusConfig = &stack.PrometheusConfig{Replicas: ptr.To(int32(2))}
+			},
+		},
+		{
+			name: "inherit from template",
+			tmpl: tmpl,
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.LogLevel = stack.Warn
+				s.Retention = "30d"
+				s.Resources = presetResources(stack.MediumPreset)
+				s.PrometheusConfig = &stack.PrometheusConfig{
+					Replicas:       ptr.To(int32(2)),
+					RemoteWrite:    tmpl.Spec.RemoteWrite,
+					ExternalLabels: map[string]string{"cluster": "prod", "team": "platform"},
+				}
+			},
+		},
+		{
+			name: "stack overrides template",
+			tmpl: tmpl,
+			spec: stack.MonitoringStackSpec{
+				LogLevel:  stack.Debug,
+				Retention: "1d",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+				},
+				PrometheusConfig: &stack.PrometheusConfig{
+					Replicas: ptr.To(int32(1)),
+					RemoteWrite: []monv1.RemoteWriteSpec{
+						{URL: "https://backup.example.com/api/v1/write", Name: "backup"},
+						{URL: "https://team.example.com/api/v1/write"},
+					},
+					ExternalLabels: map[string]string{"team": "payments"},
+				},
+			},
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.PrometheusConfig.RemoteWrite = []monv1.RemoteWriteSpec{
+					{URL: "https://central.example.com/api/v1/write"},
+					{URL: "https://backup.example.com/api/v1/write", Name: "backup"},
+					{URL: "https://team.example.com/api/v1/write"},
+				}
+				s.PrometheusConfig.ExternalLabels = map[string]string{"cluster": "prod", "team": "payments"}
+			},
+		},
+		{
+			// Stacks created before the templates store the former CRD
+			// defaults.
+			name: "legacy defaults are replaced by the template",
+			tmpl: tmpl,
+			spec: stack.MonitoringStackSpec{
+				LogLevel:  stack.Info,
+				Retention: "120h",
+				Resources: presetResources(stack.SmallPreset),
+			},
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.LogLevel = stack.Warn
+				s.Retention = "30d"
+				s.Resources = presetResources(stack.MediumPreset)
+				s.PrometheusConfig = &stack.PrometheusConfig{
+					Replicas:       ptr.To(int32(2)),
+					RemoteWrite:    tmpl.Spec.RemoteWrite,
+					ExternalLabels: map[string]string{"cluster": "prod", "team": "platform"},
+				}
+			},
+		},
+		{
+			name: "legacy defaults are kept when the template doesn't set them",
+			tmpl: &stack.MonitoringStackTemplate{
+				Spec: stack.MonitoringStackTemplateSpec{
+					ExternalLabels: map[string]string{"cluster": "prod"},
+				},
+			},
+			spec: stack.MonitoringStackSpec{
+				LogLevel:  stack.Info,
+				Retention: "120h",
+				Resources: presetResources(stack.SmallPreset),
+			},
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.PrometheusConfig = &stack.PrometheusConfig{
+					Replicas:       ptr.To(int32(2)),
+					ExternalLabels: map[string]string{"cluster": "prod"},
+				}
+			},
+		},
+		{
+			name: "explicit template resources take precedence over the preset",
+			tmpl: &stack.MonitoringStackTemplate{
+				Spec: stack.MonitoringStackTemplateSpec{
+					Preset: stack.LargePreset,
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3Gi")},
+					},
+				},
+			},
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.LogLevel = stack.Info
+				s.Retention = "120h"
+				s.Resources = corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3Gi")},
+				}
+				s.PrometheusConfig = &stack.PrometheusConfig{Replicas: ptr.To(int32(2))}
+			},
+		},
+		{
+			name: "TLS required",
+			tmpl: &stack.MonitoringStackTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "secure"},
+				Spec:       stack.MonitoringStackTemplateSpec{TLSMode: stack.TLSModeRequired},
+			},
+			err: "template \"secure\" requires TLS: prometheusConfig.webTLSConfig must be set\nalertmanagerConfig.webTLSConfig must be set",
+		},
+		{
+			name: "TLS required without Alertmanager",
+			tmpl: &stack.MonitoringStackTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "secure"},
+				Spec:       stack.MonitoringStackTemplateSpec{TLSMode: stack.TLSModeRequired},
+			},
+			spec: stack.MonitoringStackSpec{
+				PrometheusConfig:   &stack.PrometheusConfig{WebTLSConfig: &stack.WebTLSConfig{}},
+				AlertmanagerConfig: stack.AlertmanagerConfig{Disabled: true},
+			},
+			expected: func(s *stack.MonitoringStackSpec) {
+				s.LogLevel = stack.Info
+				s.Retention = "120h"
+				s.Resources = presetResources(stack.SmallPreset)
+				s.PrometheusConfig.Replicas = ptr.To(int32(2))
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &stack.MonitoringStack{Spec: tc.spec}
+
+			actual, err := effectiveSpec(ms, tc.tmpl)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+
+			expected := tc.spec.DeepCopy()
+			tc.expected(expected)
+			assert.DeepEqual(t, *expected, actual)
+
+			// The stack itself must not be modified.
+			assert.DeepEqual(t, tc.spec, ms.Spec)
+		})
+	}
+}
+
+func TestEffectiveSpecAutoscaled(t *testing.T) {
+	applied := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3Gi")},
+	}
+	ms := &stack.MonitoringStack{
+		Spec: stack.MonitoringStackSpec{
+			Resources: presetResources(stack.LargePreset),
+			Recommendations: &stack.RecommendationsConfig{
+				Enabled:   true,
+				Autoscale: &stack.AutoscalePolicy{Enabled: true},
+			},
+		},
+		Status: stack.MonitoringStackStatus{
+			Recommendations: &stack.ResourceRecommendations{AppliedResources: &applied},
+		},
+	}
+
+	spec, err := effectiveSpec(ms, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, spec.Resources, applied)
+
+	// The applied resources are ignored once autoscaling is disabled.
+	ms.Spec.Recommendations.Autoscale.Enabled = false
+	spec, err = effectiveSpec(ms, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, spec.Resources, presetResources(stack.LargePreset))
+}
+
+func TestReconcileMissingTemplate(t *testing.T) {
+	ms := &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec:       stack.MonitoringStackSpec{Template: "missing"},
+		Status: stack.MonitoringStackStatus{
+			EffectiveSpec: &stack.MonitoringStackSpec{Retention: "30d"},
+		},
+	}
+	prom := &monv1.Prometheus{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: monv1.PrometheusSpec{
+			CommonPrometheusFields: monv1.CommonPrometheusFields{Replicas: ptr.To(int32(2))},
+		},
+	}
+	rm := newTestManager(ms, prom)
+
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+	assert.NilError(t, err)
+
+	// The effective specification of the previous template isn't reported
+	// anymore.
+	got := &stack.MonitoringStack{}
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(ms), got))
+	assert.Assert(t, got.Status.EffectiveSpec == nil)
+	i := slices.IndexFunc(got.Status.Conditions, func(c stack.Condition) bool {
+		return c.Type == stack.ReconciledCondition
+	})
+	assert.Assert(t, i >= 0)
+	assert.Equal(t, got.Status.Conditions[i].Status, stack.ConditionFalse)
+	assert.Equal(t, got.Status.Conditions[i].Message, `MonitoringStackTemplate "missing" not found`)
+
+	// The suspension is still reported and enforced.
+	got.Spec.Suspend = true
+	assert.NilError(t, rm.k8sClient.Update(context.Background(), got))
+	_, err = rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ms)})
+	assert.NilError(t, err)
+
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(ms), got))
+	i = slices.IndexFunc(got.Status.Conditions, func(c stack.Condition) bool {
+		return c.Type == stack.SuspendedCondition
+	})
+	assert.Assert(t, i >= 0)
+	assert.Equal(t, got.Status.Conditions[i].Status, stack.ConditionTrue)
+	assert.Equal(t, got.Status.Conditions[i].Reason, SuspendedReason)
+
+	assert.NilError(t, rm.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(prom), prom))
+	assert.Equal(t, *prom.Spec.Replicas, int32(0))
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheusruletest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	TestsPassedReason            = "TestsPassed"
+	TestsFailedReason            = "TestsFailed"
+	PrometheusRuleNotFoundReason = "PrometheusRuleNotFound"
+	EvaluationFailedReason       = "EvaluationFailed"
+)
+
+type resourceManager struct {
+	client.Client
+	logger logr.Logger
+}
+
+// RBAC for evaluating prometheus rule tests
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusruletests,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusruletests/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusrules,verbs=get;list;watch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client: mgr.GetClient(),
+		logger: ctrl.Log.WithName("prometheus-rule-test"),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&stack.PrometheusRuleTest{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&monv1.PrometheusRule{},
+			handler.EnqueueRequestsFromMapFunc(rm.findTestsForRule),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		// The evaluation of the tests is CPU and memory bound: evaluating them
+		// one at a time bounds the resources used by the operator.
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("test", req.NamespacedName)
+	logger.Info("Reconciling prometheus rule test")
+
+	test := &stack.PrometheusRuleTest{}
+	err := rm.Get(ctx, req.NamespacedName, test)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !test.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	pr := &monv1.PrometheusRule{}
+	err = rm.Get(ctx, client.ObjectKey{Namespace: test.Namespace, Name: test.Spec.RuleRef.Name}, pr)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if apierrors.IsNotFound(err) {
+		test.Status.RuleResourceVersion = ""
+		setStatus(test, nil, PrometheusRuleNotFoundReason, fmt.Errorf("PrometheusRule %q not found", test.Spec.RuleRef.Name))
+	} else {
+		// Nothing to do if the test and the rule haven't changed since the
+		// last evaluation.
+		if test.Status.ObservedGeneration == test.Generation && test.Status.RuleResourceVersion == pr.ResourceVersion {
+			return ctrl.Result{}, nil
+		}
+
+		result, err := evaluate(ctx, test, pr)
+		test.Status.RuleResourceVersion = pr.ResourceVersion
+		setStatus(test, result, EvaluationFailedReason, err)
+	}
+
+	if err := rm.Status().Update(ctx, test); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// setStatus updates the status of the test (without persisting it) from the
+// result of the evaluation. When err isn't nil, the test couldn't be
+// evaluated for the given reason.
+func setStatus(test *stack.PrometheusRuleTest, result *testResult, reason string, err error) {
+	now := metav1.Now()
+	test.Status.ObservedGeneration = test.Generation
+	test.Status.LastEvaluationTime = &now
+	test.Status.Passed = 0
+	test.Status.Failed = 0
+	test.Status.Failures = nil
+
+	c := stack.Condition{
+		Type:               stack.TestsPassedCondition,
+		Status:             stack.ConditionTrue,
+		Reason:             TestsPassedReason,
+		ObservedGeneration: test.Generation,
+		LastTransitionTime: now,
+	}
+
+	switch {
+	case err != nil:
+		c.Status = stack.ConditionFalse
+		c.Reason = reason
+		c.Message = err.Error()
+	case len(result.failures) > 0:
+		c.Status = stack.ConditionFalse
+		c.Reason = TestsFailedReason
+		c.Message = fmt.Sprintf("%d test(s) failed", len(result.failures))
+	default:
+		c.Message = fmt.Sprintf("%d test(s) passed", result.passed)
+	}
+
+	if result != nil {
+		test.Status.Passed = int32(result.passed)
+		test.Status.Failed = int32(len(result.failures))
+		test.Status.Failures = result.failures
+	}
+
+	// Keep the transition time when the condition doesn't change.
+	for _, prev := range test.Status.Conditions {
+		if prev.Type == c.Type && prev.Status == c.Status {
+			c.LastTransitionTime = prev.LastTransitionTime
+		}
+	}
+	test.Status.Conditions = []stack.Condition{c}
+}
+
+// findTestsForRule returns the tests referencing the PrometheusRule.
+func (rm resourceManager) findTestsForRule(ctx context.Context, obj client.Object) []reconcile.Request {
+	tests := &stack.PrometheusRuleTestList{}
+	if err := rm.List(ctx, tests, client.InNamespace(obj.GetNamespace())); err != nil {
+		rm.logger.Error(err, "failed to list prometheus rule tests")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, test := range tests.Items {
+		if test.Spec.RuleRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&test)})
+		}
+	}
+	return requests
+}
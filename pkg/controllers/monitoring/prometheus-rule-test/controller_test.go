@@ -0,0 +1,125 @@
+package prometheusruletest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+)
+
+func newTestManager(objs ...client.Object) *resourceManager {
+	c := testutil.NewClientBuilder(objs...).Build()
+
+	return &resourceManager{Client: c, logger: logr.Discard()}
+}
+
+func newTest(name string, expAlerts []stack.ExpectedAlert) *stack.PrometheusRuleTest {
+	return &stack.PrometheusRuleTest{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Generation: 1},
+		Spec: stack.PrometheusRuleTestSpec{
+			RuleRef:     stack.RuleReference{Name: "rules"},
+			InputSeries: []stack.InputSeries{{Series: `up{job="app", instance="a"}`, Values: "0x10"}},
+			AlertRuleTests: []stack.AlertRuleTest{
+				{EvalTime: "10m", Alertname: "InstanceDown", ExpAlerts: expAlerts},
+			},
+		},
+	}
+}
+
+func reconcileTest(t *testing.T, rm *resourceManager, test *stack.PrometheusRuleTest) *stack.PrometheusRuleTest {
+	t.Helper()
+
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(test)})
+	assert.NilError(t, err)
+
+	got := &stack.PrometheusRuleTest{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(test), got))
+	return got
+}
+
+func TestReconcile(t *testing.T) {
+	pr := newPrometheusRule()
+	pr.Name = "rules"
+	pr.Namespace = "ns"
+
+	passing := newTest("passing", []stack.ExpectedAlert{{
+		ExpLabels:      map[string]string{"severity": "page", "instance": "a", "job": "app"},
+		ExpAnnotations: map[string]string{"summary": "Instance a down"},
+	}})
+	failing := newTest("failing", nil)
+	missing := newTest("missing", nil)
+	missing.Spec.RuleRef.Name = "missing"
+
+	rm := newTestManager(pr, passing, failing, missing)
+
+	got := reconcileTest(t, rm, passing)
+	assert.Equal(t, got.Status.Passed, int32(1))
+	assert.Equal(t, got.Status.Failed, int32(0))
+	assert.Equal(t, got.Status.ObservedGeneration, int64(1))
+	assert.Equal(t, got.Status.RuleResourceVersion, pr.ResourceVersion)
+	assert.Equal(t, len(got.Status.Conditions), 1)
+	assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionTrue)
+	assert.Equal(t, got.Status.Conditions[0].Reason, TestsPassedReason)
+
+	got = reconcileTest(t, rm, failing)
+	assert.Equal(t, got.Status.Passed, int32(0))
+	assert.Equal(t, got.Status.Failed, int32(1))
+	assert.Equal(t, len(got.Status.Failures), 1)
+	assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionFalse)
+	assert.Equal(t, got.Status.Conditions[0].Reason, TestsFailedReason)
+
+	got = reconcileTest(t, rm, missing)
+	assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionFalse)
+	assert.Equal(t, got.Status.Conditions[0].Reason, PrometheusRuleNotFoundReason)
+	assert.Equal(t, got.Status.Conditions[0].Message, `PrometheusRule "missing" not found`)
+}
+
+func TestReconcileSkipsUnchanged(t *testing.T) {
+	pr := newPrometheusRule()
+	pr.Name = "rules"
+	pr.Namespace = "ns"
+	test := newTest("test", nil)
+
+	rm := newTestManager(pr, test)
+	got := reconcileTest(t, rm, test)
+	evaluated := got.Status.LastEvaluationTime
+	assert.Assert(t, evaluated != nil)
+	assert.Equal(t, got.Status.Conditions[0].Reason, TestsFailedReason)
+
+	// The test isn't evaluated again until the rule changes.
+	got = reconcileTest(t, rm, test)
+	assert.Assert(t, got.Status.LastEvaluationTime.Equal(evaluated))
+
+	// Removing the alerting rule fixes the test.
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(pr), pr))
+	pr.Spec.Groups[0].Rules = pr.Spec.Groups[0].Rules[:1]
+	assert.NilError(t, rm.Update(context.Background(), pr))
+
+	got = reconcileTest(t, rm, test)
+	assert.Equal(t, got.Status.RuleResourceVersion, pr.ResourceVersion)
+	assert.Equal(t, got.Status.Conditions[0].Reason, TestsPassedReason)
+	assert.Equal(t, len(got.Status.Failures), 0)
+}
+
+func TestFindTestsForRule(t *testing.T) {
+	other := newTest("other", nil)
+	other.Spec.RuleRef.Name = "other"
+	otherNamespace := newTest("test", nil)
+	otherNamespace.Namespace = "other"
+
+	rm := newTestManager(newTest("test", nil), other, otherNamespace)
+
+	pr := &monv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: "rules", Namespace: "ns"}}
+	assert.DeepEqual(t, rm.findTestsForRule(context.Background(), pr), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "test"}},
+	})
+}
@@ -0,0 +1,333 @@
+package prometheusruletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	defaultEvaluationInterval = time.Minute
+
+	// maxEvaluationSteps bounds the number of rule evaluations of a test to
+	// protect the operator against tests spanning a very long time range.
+	maxEvaluationSteps = 10000
+
+	// maxInputSamples bounds the number of samples of the input series once
+	// expanded (e.g. "0+1x100" is expanded into 101 samples).
+	maxInputSamples = 1000000
+
+	// maxQuerySamples bounds the number of samples loaded in memory by a
+	// single query of the rules and the expression tests.
+	maxQuerySamples = 1000000
+
+	// evaluationTimeout bounds the whole evaluation of a test.
+	evaluationTimeout = 30 * time.Second
+)
+
+// testResult is the outcome of the alert and PromQL expression tests.
+type testResult struct {
+	passed   int
+	failures []string
+}
+
+func (r *testResult) fail(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+type inputSeries struct {
+	labels  labels.Labels
+	samples []memSample
+}
+
+// evaluate runs the tests against the rules of the PrometheusRule the same
+// way as `promtool test rules`: starting from time 0, the input series are
+// loaded and the rule groups are evaluated at every evaluation interval.
+// Firing alerts are checked at the requested evaluation times and PromQL
+// expressions are evaluated once all the rules have been evaluated.
+//
+// An error is returned when the test or the rules can't be evaluated.
+func evaluate(ctx context.Context, test *stack.PrometheusRuleTest, pr *monv1.PrometheusRule) (*testResult, error) {
+	interval := defaultEvaluationInterval
+	if test.Spec.EvaluationInterval != "" {
+		d, err := model.ParseDuration(string(test.Spec.EvaluationInterval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid evaluation interval: %w", err)
+		}
+		interval = time.Duration(d)
+	}
+	if interval <= 0 {
+		return nil, errors.New("evaluation interval must be greater than 0")
+	}
+
+	series, err := parseInputSeries(test.Spec.InputSeries, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	alertEvalTimes, exprEvalTimes, err := evalTimes(test)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxt time.Duration
+	for _, t := range append(alertEvalTimes, exprEvalTimes...) {
+		maxt = max(maxt, t)
+	}
+	if steps := int64(maxt / interval); steps > maxEvaluationSteps {
+		return nil, fmt.Errorf("the test requires %d rule evaluations (maximum %d): increase the evaluation interval or reduce the evaluation times", steps, maxEvaluationSteps)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, evaluationTimeout)
+	defer cancel()
+
+	db := newMemStorage()
+	engine := promql.NewEngine(promql.EngineOpts{
+		MaxSamples:               maxQuerySamples,
+		Timeout:                  evaluationTimeout,
+		NoStepSubqueryIntervalFn: func(int64) int64 { return interval.Milliseconds() },
+		EnableAtModifier:         true,
+		EnableNegativeOffset:     true,
+	})
+	query := rules.EngineQueryFunc(engine, db)
+
+	groups, err := ruleGroups(pr, test.Spec.ExternalLabels, interval, engine, db)
+	if err != nil {
+		return nil, err
+	}
+
+	alertTests := map[time.Duration][]stack.AlertRuleTest{}
+	for i, at := range test.Spec.AlertRuleTests {
+		alertTests[alertEvalTimes[i]] = append(alertTests[alertEvalTimes[i]], at)
+	}
+	slices.Sort(alertEvalTimes)
+	alertEvalTimes = slices.Compact(alertEvalTimes)
+
+	result := &testResult{}
+	mint := time.Unix(0, 0).UTC()
+	curr := 0
+	for ts := mint; !ts.After(mint.Add(maxt)); ts = ts.Add(interval) {
+		appendTill(db, series, ts.Sub(mint).Milliseconds())
+
+		for _, g := range groups {
+			if err := evalGroup(ctx, g, ts); err != nil {
+				return nil, err
+			}
+		}
+
+		// Alerts are checked against the evaluation which precedes the
+		// requested time.
+		for curr < len(alertEvalTimes) && alertEvalTimes[curr] < ts.Add(interval).Sub(mint) {
+			for _, at := range alertTests[alertEvalTimes[curr]] {
+				checkAlerts(result, at, firingAlerts(groups, at.Alertname))
+			}
+			curr++
+		}
+	}
+
+	for i, et := range test.Spec.PromQLExprTests {
+		checkExpr(ctx, result, et, query, mint.Add(exprEvalTimes[i]))
+	}
+
+	return result, nil
+}
+
+// expansionRe matches the expanding notations of the input series values
+// ("axN", "a+bxN", "_xN", ...).
+var expansionRe = regexp.MustCompile(`x(\d+)`)
+
+// expandedSamples returns an upper bound of the number of samples described
+// by the values of an input series without expanding them.
+func expandedSamples(values string) uint64 {
+	n := uint64(len(strings.Fields(values)))
+	for _, m := range expansionRe.FindAllStringSubmatch(values, -1) {
+		count, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil || count >= math.MaxUint32 {
+			return math.MaxUint64
+		}
+		n += count
+	}
+	return n
+}
+
+func parseInputSeries(input []stack.InputSeries, interval time.Duration) ([]*inputSeries, error) {
+	var total uint64
+	for _, in := range input {
+		total += min(expandedSamples(in.Values), math.MaxUint64-total)
+	}
+	if total > maxInputSamples {
+		return nil, fmt.Errorf("the input series expand to more than %d samples: reduce the number of samples of the input series", maxInputSamples)
+	}
+
+	series := make([]*inputSeries, 0, len(input))
+	for i, in := range input {
+		lset, values, err := parser.ParseSeriesDesc(in.Series + " " + in.Values)
+		if err != nil {
+			return nil, fmt.Errorf("input series %d: %w", i+1, err)
+		}
+
+		s := &inputSeries{labels: lset}
+		for j, v := range values {
+			if v.Omitted {
+				continue
+			}
+			s.samples = append(s.samples, memSample{t: int64(j) * interval.Milliseconds(), f: v.Value, fh: v.Histogram})
+		}
+		series = append(series, s)
+	}
+
+	return series, nil
+}
+
+// evalTimes returns the evaluation times of the alert tests and of the
+// PromQL expression tests, in the order of the tests.
+func evalTimes(test *stack.PrometheusRuleTest) ([]time.Duration, []time.Duration, error) {
+	parse := func(kind string, i int, d monv1.Duration) (time.Duration, error) {
+		t, err := model.ParseDuration(string(d))
+		if err != nil {
+			return 0, fmt.Errorf("%s test %d: invalid evaluation time: %w", kind, i+1, err)
+		}
+		return time.Duration(t), nil
+	}
+
+	alertTimes := make([]time.Duration, 0, len(test.Spec.AlertRuleTests))
+	for i, at := range test.Spec.AlertRuleTests {
+		t, err := parse("alert rule", i, at.EvalTime)
+		if err != nil {
+			return nil, nil, err
+		}
+		alertTimes = append(alertTimes, t)
+	}
+
+	exprTimes := make([]time.Duration, 0, len(test.Spec.PromQLExprTests))
+	for i, et := range test.Spec.PromQLExprTests {
+		t, err := parse("PromQL expression", i, et.EvalTime)
+		if err != nil {
+			return nil, nil, err
+		}
+		exprTimes = append(exprTimes, t)
+	}
+
+	return alertTimes, exprTimes, nil
+}
+
+// appendTill appends the samples of the input series up to the given
+// timestamp and removes them from the input series.
+func appendTill(db *memStorage, series []*inputSeries, ts int64) {
+	for _, s := range series {
+		i := 0
+		for ; i < len(s.samples) && s.samples[i].t <= ts; i++ {
+			db.append(s.labels, s.samples[i])
+		}
+		s.samples = s.samples[i:]
+	}
+}
+
+func sortAlerts(as alerts) {
+	sort.Slice(as, func(i, j int) bool {
+		if c := labels.Compare(as[i].labels, as[j].labels); c != 0 {
+			return c < 0
+		}
+		return labels.Compare(as[i].annotations, as[j].annotations) < 0
+	})
+}
+
+func checkAlerts(result *testResult, at stack.AlertRuleTest, got alerts) {
+	exp := make(alerts, 0, len(at.ExpAlerts))
+	for _, a := range at.ExpAlerts {
+		lb := labels.NewBuilder(labels.FromMap(a.ExpLabels))
+		lb.Set(labels.AlertName, at.Alertname)
+		exp = append(exp, alert{labels: lb.Labels(), annotations: labels.FromMap(a.ExpAnnotations)})
+	}
+
+	sortAlerts(exp)
+	sortAlerts(got)
+	if !slices.EqualFunc(exp, got, func(a, b alert) bool {
+		return labels.Equal(a.labels, b.labels) && labels.Equal(a.annotations, b.annotations)
+	}) {
+		result.fail("alertname: %s, time: %s, expected: %s, got: %s", at.Alertname, at.EvalTime, exp, got)
+		return
+	}
+
+	result.passed++
+}
+
+type exprSample struct {
+	labels labels.Labels
+	value  float64
+}
+
+func sortSamples(s []exprSample) {
+	sort.Slice(s, func(i, j int) bool { return labels.Compare(s[i].labels, s[j].labels) < 0 })
+}
+
+func samplesString(samples []exprSample) string {
+	s := make([]string, 0, len(samples))
+	for _, smpl := range samples {
+		s = append(s, fmt.Sprintf("%s %s", smpl.labels, strconv.FormatFloat(smpl.value, 'g', -1, 64)))
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+func checkExpr(ctx context.Context, result *testResult, et stack.PromQLExprTest, query rules.QueryFunc, ts time.Time) {
+	exp := make([]exprSample, 0, len(et.ExpSamples))
+	for _, s := range et.ExpSamples {
+		lbls := labels.EmptyLabels()
+		if s.Labels != "" {
+			var err error
+			if lbls, err = parser.ParseMetric(s.Labels); err != nil {
+				result.fail("expr: %q, time: %s, invalid labels %q: %s", et.Expr, et.EvalTime, s.Labels, err)
+				return
+			}
+		}
+		v, err := strconv.ParseFloat(s.Value, 64)
+		if err != nil {
+			result.fail("expr: %q, time: %s, invalid value %q: %s", et.Expr, et.EvalTime, s.Value, err)
+			return
+		}
+		exp = append(exp, exprSample{labels: lbls, value: v})
+	}
+
+	vector, err := query(ctx, et.Expr, ts)
+	if err != nil {
+		result.fail("expr: %q, time: %s, error: %s", et.Expr, et.EvalTime, err)
+		return
+	}
+
+	got := make([]exprSample, 0, len(vector))
+	for _, s := range vector {
+		if s.H != nil {
+			result.fail("expr: %q, time: %s, native histogram results aren't supported", et.Expr, et.EvalTime)
+			return
+		}
+		got = append(got, exprSample{labels: s.Metric, value: s.F})
+	}
+
+	sortSamples(exp)
+	sortSamples(got)
+	if !slices.EqualFunc(exp, got, func(a, b exprSample) bool {
+		return labels.Equal(a.labels, b.labels) &&
+			(a.value == b.value || math.IsNaN(a.value) && math.IsNaN(b.value))
+	}) {
+		result.fail("expr: %q, time: %s, expected: %s, got: %s", et.Expr, et.EvalTime, samplesString(exp), samplesString(got))
+		return
+	}
+
+	result.passed++
+}
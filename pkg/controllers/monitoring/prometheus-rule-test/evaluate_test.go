@@ -0,0 +1,219 @@
+package prometheusruletest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newPrometheusRule() *monv1.PrometheusRule {
+	return &monv1.PrometheusRule{
+		Spec: monv1.PrometheusRuleSpec{
+			Groups: []monv1.RuleGroup{{
+				Name: "example",
+				Rules: []monv1.Rule{
+					{
+						Record: "job:test:count_over_time1m",
+						Expr:   intstr.FromString("sum without(instance) (count_over_time(test[1m]))"),
+					},
+					{
+						Alert:  "InstanceDown",
+						Expr:   intstr.FromString(`up == 0`),
+						For:    ptr.To(monv1.Duration("5m")),
+						Labels: map[string]string{"severity": "page"},
+						Annotations: map[string]string{
+							"summary": "Instance {{ $labels.instance }} down",
+						},
+					},
+					{
+						Alert:         "Flapping",
+						Expr:          intstr.FromString(`flap == 1`),
+						KeepFiringFor: ptr.To(monv1.NonEmptyDuration("2m")),
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		spec     stack.PrometheusRuleTestSpec
+		passed   int
+		failures []string
+		err      string
+	}{
+		{
+			name: "alerts",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{
+					{Series: `up{job="prometheus", instance="localhost:9090"}`, Values: "0 0 0 0 0 0 0 0 0 0 0 0 0 0 0"},
+					{Series: `up{job="node_exporter", instance="localhost:9100"}`, Values: "1+0x6 0 0 0 0 0 0 0 0"},
+				},
+				AlertRuleTests: []stack.AlertRuleTest{
+					{EvalTime: "4m", Alertname: "InstanceDown"},
+					{
+						EvalTime:  "10m",
+						Alertname: "InstanceDown",
+						ExpAlerts: []stack.ExpectedAlert{{
+							ExpLabels:      map[string]string{"severity": "page", "instance": "localhost:9090", "job": "prometheus"},
+							ExpAnnotations: map[string]string{"summary": "Instance localhost:9090 down"},
+						}},
+					},
+					{
+						EvalTime:  "12m",
+						Alertname: "InstanceDown",
+						ExpAlerts: []stack.ExpectedAlert{
+							{
+								ExpLabels:      map[string]string{"severity": "page", "instance": "localhost:9090", "job": "prometheus"},
+								ExpAnnotations: map[string]string{"summary": "Instance localhost:9090 down"},
+							},
+							{
+								ExpLabels:      map[string]string{"severity": "page", "instance": "localhost:9100", "job": "node_exporter"},
+								ExpAnnotations: map[string]string{"summary": "Instance localhost:9100 down"},
+							},
+						},
+					},
+				},
+			},
+			passed: 3,
+		},
+		{
+			name: "keep firing for",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{
+					{Series: `flap`, Values: "1 0 0 0 0"},
+				},
+				AlertRuleTests: []stack.AlertRuleTest{
+					{EvalTime: "0m", Alertname: "Flapping", ExpAlerts: []stack.ExpectedAlert{{}}},
+					{EvalTime: "2m", Alertname: "Flapping", ExpAlerts: []stack.ExpectedAlert{{}}},
+					{EvalTime: "3m", Alertname: "Flapping"},
+				},
+			},
+			passed: 3,
+		},
+		{
+			name: "recording rules and expressions",
+			spec: stack.PrometheusRuleTestSpec{
+				EvaluationInterval: "30s",
+				InputSeries: []stack.InputSeries{
+					{Series: `test{job="app", instance="a"}`, Values: "0+1x10"},
+					{Series: `test{job="app", instance="b"}`, Values: "_x4 1x6"},
+				},
+				PromQLExprTests: []stack.PromQLExprTest{
+					{
+						Expr:       "job:test:count_over_time1m",
+						EvalTime:   "1m",
+						ExpSamples: []stack.ExpectedSample{{Labels: `job:test:count_over_time1m{job="app"}`, Value: "3"}},
+					},
+					{
+						Expr:       "job:test:count_over_time1m",
+						EvalTime:   "5m",
+						ExpSamples: []stack.ExpectedSample{{Labels: `job:test:count_over_time1m{job="app"}`, Value: "6"}},
+					},
+					{
+						Expr:       "scalar(sum(test))",
+						EvalTime:   "2m",
+						ExpSamples: []stack.ExpectedSample{{Value: "5"}},
+					},
+					{
+						Expr:       `test{instance="b"}`,
+						EvalTime:   "1m",
+						ExpSamples: []stack.ExpectedSample{{Labels: `test{job="app", instance="b"}`, Value: "1"}},
+					},
+				},
+			},
+			passed: 3,
+			failures: []string{
+				`expr: "test{instance=\"b\"}", time: 1m, expected: [{__name__="test", instance="b", job="app"} 1], got: []`,
+			},
+		},
+		{
+			name: "unexpected alert",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{
+					{Series: `up{job="app", instance="a"}`, Values: "0x10"},
+				},
+				AlertRuleTests: []stack.AlertRuleTest{{EvalTime: "5m", Alertname: "InstanceDown"}},
+			},
+			failures: []string{
+				`alertname: InstanceDown, time: 5m, expected: [], got: [{labels: {alertname="InstanceDown", instance="a", job="app", severity="page"}, annotations: {summary="Instance a down"}}]`,
+			},
+		},
+		{
+			name: "invalid input series",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{{Series: `up{`, Values: "1"}},
+			},
+			err: "input series 1: ",
+		},
+		{
+			name: "too many evaluations",
+			spec: stack.PrometheusRuleTestSpec{
+				EvaluationInterval: "1s",
+				PromQLExprTests:    []stack.PromQLExprTest{{Expr: "up", EvalTime: "1d"}},
+			},
+			err: "the test requires 86400 rule evaluations (maximum 10000)",
+		},
+		{
+			name: "too many input samples",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{{Series: `up{job="app", instance="a"}`, Values: "0+1x100000000"}},
+			},
+			err: "the input series expand to more than 1000000 samples",
+		},
+		{
+			name: "too many input samples across series",
+			spec: stack.PrometheusRuleTestSpec{
+				InputSeries: []stack.InputSeries{
+					{Series: `up{job="app", instance="a"}`, Values: "1x600000"},
+					{Series: `up{job="app", instance="b"}`, Values: "_x100 1x600000"},
+				},
+			},
+			err: "the input series expand to more than 1000000 samples",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			test := &stack.PrometheusRuleTest{Spec: tc.spec}
+			result, err := evaluate(context.Background(), test, newPrometheusRule())
+			if tc.err != "" {
+				assert.Assert(t, err != nil && strings.HasPrefix(err.Error(), tc.err), "got error %v", err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, result.passed, tc.passed)
+			assert.DeepEqual(t, result.failures, tc.failures)
+		})
+	}
+}
+
+func TestEvaluateInvalidRule(t *testing.T) {
+	pr := &monv1.PrometheusRule{
+		Spec: monv1.PrometheusRuleSpec{
+			Groups: []monv1.RuleGroup{{
+				Name: "example",
+				Rules: []monv1.Rule{
+					{Record: "duplicate", Expr: intstr.FromString("up"), Labels: map[string]string{"instance": "x"}},
+				},
+			}},
+		},
+	}
+	test := &stack.PrometheusRuleTest{
+		Spec: stack.PrometheusRuleTestSpec{
+			InputSeries:     []stack.InputSeries{{Series: `up{instance="a"}`, Values: "1"}, {Series: `up{instance="b"}`, Values: "1"}},
+			PromQLExprTests: []stack.PromQLExprTest{{Expr: "duplicate", EvalTime: "0m"}},
+		},
+	}
+
+	_, err := evaluate(context.Background(), test, pr)
+	assert.Error(t, err, `rule "duplicate" of group "example" failed at 0s: vector contains metrics with the same labelset after applying rule labels`)
+}
@@ -0,0 +1,135 @@
+package prometheusruletest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+// ruleGroups returns the rule groups of the PrometheusRule evaluated by the
+// Prometheus rule manager against the in-memory storage.
+func ruleGroups(pr *monv1.PrometheusRule, externalLabels map[string]string, interval time.Duration, engine *promql.Engine, db *memStorage) ([]*rules.Group, error) {
+	opts := &rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(engine, db),
+		Appendable: db,
+		Queryable:  db,
+		Context:    context.Background(),
+		// The alerts aren't sent anywhere.
+		NotifyFunc: func(context.Context, string, ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+		Metrics:    rules.NewGroupMetrics(nil),
+	}
+	extLabels := labels.FromMap(externalLabels)
+
+	groups := make([]*rules.Group, 0, len(pr.Spec.Groups))
+	for _, g := range pr.Spec.Groups {
+		groupRules := make([]rules.Rule, 0, len(g.Rules))
+		for i, r := range g.Rules {
+			expr, err := parser.ParseExpr(r.Expr.String())
+			if err != nil {
+				return nil, fmt.Errorf("group %q, rule %d: invalid expression: %w", g.Name, i+1, err)
+			}
+
+			if r.Record != "" {
+				groupRules = append(groupRules, rules.NewRecordingRule(r.Record, expr, labels.FromMap(r.Labels)))
+				continue
+			}
+
+			var hold, keepFiringFor model.Duration
+			if r.For != nil {
+				if hold, err = model.ParseDuration(string(*r.For)); err != nil {
+					return nil, fmt.Errorf("group %q, rule %d: invalid 'for' duration: %w", g.Name, i+1, err)
+				}
+			}
+			if r.KeepFiringFor != nil {
+				if keepFiringFor, err = model.ParseDuration(string(*r.KeepFiringFor)); err != nil {
+					return nil, fmt.Errorf("group %q, rule %d: invalid 'keep_firing_for' duration: %w", g.Name, i+1, err)
+				}
+			}
+
+			groupRules = append(groupRules, rules.NewAlertingRule(
+				r.Alert,
+				expr,
+				time.Duration(hold),
+				time.Duration(keepFiringFor),
+				labels.FromMap(r.Labels),
+				labels.FromMap(r.Annotations),
+				extLabels,
+				"",
+				true,
+				opts.Logger,
+			))
+		}
+
+		var limit int
+		if g.Limit != nil {
+			limit = *g.Limit
+		}
+		groups = append(groups, rules.NewGroup(rules.GroupOptions{
+			Name:     g.Name,
+			Interval: interval,
+			Limit:    limit,
+			Rules:    groupRules,
+			Opts:     opts,
+		}))
+	}
+
+	return groups, nil
+}
+
+// evalGroup evaluates the rules of the group at the given time. The rule
+// manager doesn't return the evaluation errors but records them in the rules.
+func evalGroup(ctx context.Context, g *rules.Group, ts time.Time) error {
+	g.Eval(ctx, ts)
+
+	for _, r := range g.Rules() {
+		if err := r.LastError(); err != nil {
+			return fmt.Errorf("rule %q of group %q failed at %s: %w", r.Name(), g.Name(), model.Duration(ts.Sub(time.Unix(0, 0))), err)
+		}
+	}
+
+	return nil
+}
+
+// firingAlerts returns the firing alerts of the alerting rules with the
+// given name across all groups.
+func firingAlerts(groups []*rules.Group, name string) alerts {
+	var firing alerts
+	for _, g := range groups {
+		for _, ar := range g.AlertingRules() {
+			if ar.Name() != name {
+				continue
+			}
+			for _, a := range ar.ActiveAlerts() {
+				if a.State == rules.StateFiring {
+					firing = append(firing, alert{labels: a.Labels.Copy(), annotations: a.Annotations.Copy()})
+				}
+			}
+		}
+	}
+	return firing
+}
+
+type alert struct {
+	labels      labels.Labels
+	annotations labels.Labels
+}
+
+type alerts []alert
+
+func (as alerts) String() string {
+	s := make([]string, 0, len(as))
+	for _, a := range as {
+		s = append(s, fmt.Sprintf("{labels: %s, annotations: %s}", a.labels, a.annotations))
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
@@ -0,0 +1,231 @@
+package prometheusruletest
+
+import (
+	"context"
+	"slices"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// memSample is a float or a native histogram sample.
+type memSample struct {
+	t  int64
+	f  float64
+	fh *histogram.FloatHistogram
+}
+
+var _ chunks.Sample = memSample{}
+
+func (s memSample) T() int64                      { return s.t }
+func (s memSample) F() float64                    { return s.f }
+func (s memSample) H() *histogram.Histogram       { return nil }
+func (s memSample) FH() *histogram.FloatHistogram { return s.fh }
+
+func (s memSample) Type() chunkenc.ValueType {
+	if s.fh != nil {
+		return chunkenc.ValFloatHistogram
+	}
+	return chunkenc.ValFloat
+}
+
+type memSeries struct {
+	labels  labels.Labels
+	samples []memSample
+}
+
+// memStorage is a minimal in-memory storage holding the input series and
+// the series produced by the rules during a test. Samples are expected to be
+// appended in time order, older samples are dropped.
+type memStorage struct {
+	series map[string]*memSeries
+}
+
+var (
+	_ storage.Queryable  = &memStorage{}
+	_ storage.Appendable = &memStorage{}
+)
+
+func newMemStorage() *memStorage {
+	return &memStorage{series: map[string]*memSeries{}}
+}
+
+func (s *memStorage) append(lset labels.Labels, smpl memSample) {
+	key := lset.String()
+	ms, found := s.series[key]
+	if !found {
+		ms = &memSeries{labels: lset}
+		s.series[key] = ms
+	}
+
+	if n := len(ms.samples); n > 0 && ms.samples[n-1].t >= smpl.t {
+		return
+	}
+	ms.samples = append(ms.samples, smpl)
+}
+
+// Appender implements the storage.Appendable interface. The samples are
+// stored when the appender is committed.
+func (s *memStorage) Appender(context.Context) storage.Appender {
+	return &memAppender{storage: s}
+}
+
+type memAppendedSample struct {
+	labels labels.Labels
+	sample memSample
+}
+
+// memAppender buffers the samples written by the rule evaluations.
+type memAppender struct {
+	storage *memStorage
+	pending []memAppendedSample
+}
+
+// Append implements the storage.Appender interface.
+func (a *memAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.pending = append(a.pending, memAppendedSample{labels: l, sample: memSample{t: t, f: v}})
+	return ref, nil
+}
+
+// AppendHistogram implements the storage.Appender interface.
+func (a *memAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	if h != nil {
+		fh = h.ToFloat(nil)
+	}
+	a.pending = append(a.pending, memAppendedSample{labels: l, sample: memSample{t: t, fh: fh}})
+	return ref, nil
+}
+
+// AppendExemplar implements the storage.Appender interface.
+func (a *memAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// UpdateMetadata implements the storage.Appender interface.
+func (a *memAppender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// AppendCTZeroSample implements the storage.Appender interface.
+func (a *memAppender) AppendCTZeroSample(ref storage.SeriesRef, _ labels.Labels, _, _ int64) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// Commit implements the storage.Appender interface.
+func (a *memAppender) Commit() error {
+	for _, p := range a.pending {
+		a.storage.append(p.labels, p.sample)
+	}
+	a.pending = nil
+	return nil
+}
+
+// Rollback implements the storage.Appender interface.
+func (a *memAppender) Rollback() error {
+	a.pending = nil
+	return nil
+}
+
+// Querier implements the storage.Queryable interface.
+func (s *memStorage) Querier(mint, maxt int64) (storage.Querier, error) {
+	return &memQuerier{storage: s, mint: mint, maxt: maxt}, nil
+}
+
+type memQuerier struct {
+	storage    *memStorage
+	mint, maxt int64
+}
+
+func (q *memQuerier) matching(matchers []*labels.Matcher) []*memSeries {
+	var series []*memSeries
+	for _, ms := range q.storage.series {
+		if matches(ms.labels, matchers) {
+			series = append(series, ms)
+		}
+	}
+	slices.SortFunc(series, func(a, b *memSeries) int { return labels.Compare(a.labels, b.labels) })
+	return series
+}
+
+func matches(lset labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Select implements the storage.Querier interface.
+func (q *memQuerier) Select(_ context.Context, _ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	mint, maxt := q.mint, q.maxt
+	if hints != nil {
+		mint, maxt = hints.Start, hints.End
+	}
+
+	var series []storage.Series
+	for _, ms := range q.matching(matchers) {
+		var samples []chunks.Sample
+		for _, smpl := range ms.samples {
+			if smpl.t >= mint && smpl.t <= maxt {
+				samples = append(samples, smpl)
+			}
+		}
+		if len(samples) > 0 {
+			series = append(series, storage.NewListSeries(ms.labels, samples))
+		}
+	}
+
+	return &memSeriesSet{series: series, cur: -1}
+}
+
+// LabelValues implements the storage.LabelQuerier interface.
+func (q *memQuerier) LabelValues(_ context.Context, name string, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	var values []string
+	for _, ms := range q.matching(matchers) {
+		if v := ms.labels.Get(name); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return values, nil, nil
+}
+
+// LabelNames implements the storage.LabelQuerier interface.
+func (q *memQuerier) LabelNames(_ context.Context, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	var names []string
+	for _, ms := range q.matching(matchers) {
+		ms.labels.Range(func(l labels.Label) {
+			if !slices.Contains(names, l.Name) {
+				names = append(names, l.Name)
+			}
+		})
+	}
+	slices.Sort(names)
+	return names, nil, nil
+}
+
+// Close implements the storage.LabelQuerier interface.
+func (q *memQuerier) Close() error {
+	return nil
+}
+
+type memSeriesSet struct {
+	series []storage.Series
+	cur    int
+}
+
+func (s *memSeriesSet) Next() bool {
+	s.cur++
+	return s.cur < len(s.series)
+}
+
+func (s *memSeriesSet) At() storage.Series                { return s.series[s.cur] }
+func (s *memSeriesSet) Err() error                        { return nil }
+func (s *memSeriesSet) Warnings() annotations.Annotations { return nil }
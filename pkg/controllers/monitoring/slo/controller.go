@@ -0,0 +1,311 @@
+/*
+Copyright 2024.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+	"github.com/rhobs/observability-operator/pkg/selection"
+)
+
+const (
+	ReconciledReason              = "SLOReconciled"
+	FailedToReconcileReason       = "FailedToReconcile"
+	MonitoringStackNotFoundReason = "MonitoringStackNotFound"
+	InvalidSpecReason             = "InvalidSpec"
+	RuleNotSelectedReason         = "RuleNotSelected"
+
+	// refreshInterval is the interval between two reads of the error
+	// budget.
+	refreshInterval = 5 * time.Minute
+
+	// requestTimeout bounds the duration of the requests to Prometheus.
+	requestTimeout = time.Minute
+)
+
+// querier evaluates instant queries against the Prometheus API.
+type querier interface {
+	Query(ctx context.Context, query string) ([]prometheus.Sample, error)
+}
+
+type resourceManager struct {
+	client.Client
+	// apiReader reads the certificate authority of the stacks which isn't
+	// cached by the operator.
+	apiReader client.Reader
+	scheme    *runtime.Scheme
+	logger    logr.Logger
+	now       func() time.Time
+	newClient func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (querier, error)
+}
+
+// RBAC for managing SLOs
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=slos,verbs=get;list;watch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=slos/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=slos/finalizers,verbs=update
+
+// RBAC for generating the rules of the SLOs
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.rhobs,resources=monitoringstacks,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// RegisterWithManager registers the controller with Manager
+func RegisterWithManager(mgr ctrl.Manager) error {
+	rm := &resourceManager{
+		Client:    mgr.GetClient(),
+		apiReader: mgr.GetAPIReader(),
+		scheme:    mgr.GetScheme(),
+		logger:    ctrl.Log.WithName("slo"),
+		now:       time.Now,
+		newClient: func(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (querier, error) {
+			return prometheus.NewClientForStack(ctx, c, ms, host)
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&stack.SLO{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&monv1.PrometheusRule{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&stack.MonitoringStack{},
+			handler.EnqueueRequestsFromMapFunc(rm.findSLOsForStack),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(rm)
+}
+
+func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := rm.logger.WithValues("slo", req.NamespacedName)
+	logger.Info("Reconciling SLO")
+
+	slo := &stack.SLO{}
+	err := rm.Get(ctx, req.NamespacedName, slo)
+	if apierrors.IsNotFound(err) {
+		// The generated PrometheusRule is garbage-collected.
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !slo.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	ms := &stack.MonitoringStack{}
+	err = rm.Get(ctx, client.ObjectKey{Namespace: slo.Namespace, Name: slo.Spec.MonitoringStack}, ms)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	var recErr error
+	if apierrors.IsNotFound(err) {
+		// The SLO is reconciled again when the stack gets created.
+		slo.Status.Conditions = []stack.Condition{
+			condition(slo, MonitoringStackNotFoundReason, fmt.Errorf("MonitoringStack %q not found", slo.Spec.MonitoringStack)),
+		}
+	} else {
+		reason, err := rm.reconcileRule(ctx, slo, ms)
+		slo.Status.Conditions = []stack.Condition{condition(slo, reason, err)}
+
+		// Only API errors are retried, other errors require a change of
+		// the SLO or of the stack.
+		if reason == FailedToReconcileReason {
+			recErr = err
+		}
+
+		if err == nil {
+			slo.Status.ErrorBudget = rm.readErrorBudget(ctx, slo, ms)
+		}
+	}
+
+	if err := rm.Status().Update(ctx, slo); err != nil {
+		logger.Info("Failed to update status", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	if recErr != nil {
+		return ctrl.Result{}, recErr
+	}
+
+	return ctrl.Result{RequeueAfter: refreshInterval}, nil
+}
+
+// reconcileRule generates the PrometheusRule of the SLO. It returns the
+// reason of the Reconciled condition.
+func (rm resourceManager) reconcileRule(ctx context.Context, slo *stack.SLO, ms *stack.MonitoringStack) (string, error) {
+	pr, err := newPrometheusRule(slo)
+	if err != nil {
+		return InvalidSpecReason, err
+	}
+
+	pr.Labels, err = ruleLabels(ms)
+	if err != nil {
+		return RuleNotSelectedReason, err
+	}
+
+	// Fail early if the labels can't match the selector (e.g. because of
+	// NotIn or DoesNotExist requirements).
+	sel, err := metav1.LabelSelectorAsSelector(ms.Spec.ResourceSelector)
+	if err != nil || !sel.Matches(labels.Set(pr.Labels)) {
+		return RuleNotSelectedReason, fmt.Errorf("failed to generate labels matching the resource selector of MonitoringStack %q", ms.Name)
+	}
+
+	selected, err := rm.namespaceSelected(ctx, ms)
+	if err != nil {
+		return FailedToReconcileReason, err
+	}
+	if !selected {
+		return RuleNotSelectedReason, fmt.Errorf("namespace %q isn't selected by the namespace selector of MonitoringStack %q", ms.Namespace, ms.Name)
+	}
+
+	if err := reconciler.NewUpdater(pr, slo).Reconcile(ctx, rm, rm.scheme); err != nil {
+		return FailedToReconcileReason, err
+	}
+	slo.Status.PrometheusRule = pr.Name
+
+	return ReconciledReason, nil
+}
+
+// namespaceSelected returns whether the namespace of the stack (where the
+// PrometheusRule is created) is selected by the stack.
+func (rm resourceManager) namespaceSelected(ctx context.Context, ms *stack.MonitoringStack) (bool, error) {
+	if ms.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := rm.Get(ctx, client.ObjectKey{Name: ms.Namespace}, ns); err != nil {
+		return false, err
+	}
+
+	return selection.MatchesNamespace(ms.Namespace, ms.Spec.NamespaceSelector, ns), nil
+}
+
+// readErrorBudget reads the error ratio of the SLO over its window from the
+// Prometheus instance of the stack.
+func (rm resourceManager) readErrorBudget(ctx context.Context, slo *stack.SLO, ms *stack.MonitoringStack) *stack.SLOErrorBudget {
+	eb := &stack.SLOErrorBudget{}
+	if slo.Status.ErrorBudget != nil {
+		eb = slo.Status.ErrorBudget.DeepCopy()
+	}
+
+	if ms.Suspended() {
+		return eb
+	}
+
+	eb.LastUpdateTime = &metav1.Time{Time: rm.now()}
+	eb.Error = ""
+	if err := rm.computeErrorBudget(ctx, slo, ms, eb); err != nil {
+		rm.logger.Info("Failed to read error budget", "slo", client.ObjectKeyFromObject(slo), "err", err)
+		eb.Error = err.Error()
+	}
+
+	return eb
+}
+
+func (rm resourceManager) computeErrorBudget(ctx context.Context, slo *stack.SLO, ms *stack.MonitoringStack, eb *stack.SLOErrorBudget) error {
+	budget, err := errorBudget(slo)
+	if err != nil {
+		return err
+	}
+	w, err := window(slo)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	host := fmt.Sprintf("%s-prometheus.%s.svc", ms.Name, ms.Namespace)
+	q, err := rm.newClient(ctx, rm.apiReader, ms, host)
+	if err != nil {
+		return err
+	}
+
+	samples, err := q.Query(ctx, fmt.Sprintf("max(%s)", selector(slo, errorRatioMetric(w))))
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 || math.IsNaN(samples[0].Value) {
+		return errors.New("no data for the service level indicator")
+	}
+
+	ratio := samples[0].Value
+	eb.SLI = strconv.FormatFloat((1-ratio)*100, 'g', 6, 64)
+	eb.Remaining = strconv.FormatFloat((1-ratio/budget)*100, 'g', 6, 64)
+
+	return nil
+}
+
+func condition(slo *stack.SLO, reason string, err error) stack.Condition {
+	c := stack.Condition{
+		Type:               stack.ReconciledCondition,
+		Status:             stack.ConditionTrue,
+		Reason:             reason,
+		Message:            fmt.Sprintf("PrometheusRule %q generated", prometheusRuleName(slo)),
+		ObservedGeneration: slo.Generation,
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if err != nil {
+		c.Status = stack.ConditionFalse
+		c.Message = err.Error()
+	}
+
+	for _, prev := range slo.Status.Conditions {
+		if prev.Type == c.Type && prev.Status == c.Status {
+			c.LastTransitionTime = prev.LastTransitionTime
+		}
+	}
+
+	return c
+}
+
+// findSLOsForStack returns the SLOs evaluated by the MonitoringStack.
+func (rm resourceManager) findSLOsForStack(ctx context.Context, obj client.Object) []reconcile.Request {
+	slos := &stack.SLOList{}
+	if err := rm.List(ctx, slos, client.InNamespace(obj.GetNamespace())); err != nil {
+		rm.logger.Error(err, "failed to list SLOs")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, slo := range slos.Items {
+		if slo.Spec.MonitoringStack == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&slo)})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,185 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+type fakeQuerier struct {
+	queries []string
+	samples []prometheus.Sample
+	err     error
+}
+
+func (q *fakeQuerier) Query(_ context.Context, query string) ([]prometheus.Sample, error) {
+	q.queries = append(q.queries, query)
+	return q.samples, q.err
+}
+
+// newTestManager returns a resourceManager backed by a fake client and the
+// given querier.
+func newTestManager(q *fakeQuerier, objs ...client.Object) *resourceManager {
+	c := testutil.NewClientBuilder(objs...).WithInterceptorFuncs(testutil.WithApplyPatch()).Build()
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		scheme:    c.Scheme(),
+		logger:    logr.Discard(),
+		now:       testutil.Clock,
+		newClient: func(_ context.Context, _ client.Reader, _ *stack.MonitoringStack, _ string) (querier, error) {
+			return q, nil
+		},
+	}
+}
+
+func newStack() *stack.MonitoringStack {
+	return &stack.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: "ms", Namespace: "ns"},
+		Spec: stack.MonitoringStackSpec{
+			ResourceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+		},
+	}
+}
+
+func reconcileSLO(t *testing.T, rm *resourceManager, slo *stack.SLO) *stack.SLO {
+	t.Helper()
+
+	_, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(slo)})
+	assert.NilError(t, err)
+
+	got := &stack.SLO{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(slo), got))
+	return got
+}
+
+func TestReconcile(t *testing.T) {
+	q := &fakeQuerier{samples: []prometheus.Sample{{Value: 0.0005}}}
+	slo := newSLO()
+	rm := newTestManager(q, newStack(), slo)
+
+	got := reconcileSLO(t, rm, slo)
+	assert.Equal(t, got.Status.PrometheusRule, "api-slo")
+	assert.Equal(t, len(got.Status.Conditions), 1)
+	assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionTrue)
+	assert.Equal(t, got.Status.Conditions[0].Reason, ReconciledReason)
+
+	assert.Assert(t, got.Status.ErrorBudget != nil)
+	assert.Equal(t, got.Status.ErrorBudget.Error, "")
+	assert.Equal(t, got.Status.ErrorBudget.SLI, "99.95")
+	assert.Equal(t, got.Status.ErrorBudget.Remaining, "50")
+	assert.DeepEqual(t, q.queries, []string{`max(slo:sli_error:ratio_rate4w{slo="api", slo_namespace="ns"})`})
+
+	pr := &monv1.PrometheusRule{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "api-slo"}, pr))
+	assert.DeepEqual(t, pr.Labels, map[string]string{"app": "demo"})
+	assert.Equal(t, len(pr.OwnerReferences), 1)
+	assert.Equal(t, pr.OwnerReferences[0].Name, "api")
+
+	// Query failures are reported in the status.
+	q.samples = nil
+	got = reconcileSLO(t, rm, got)
+	assert.Equal(t, got.Status.Conditions[0].Reason, ReconciledReason)
+	assert.Equal(t, got.Status.ErrorBudget.Error, "no data for the service level indicator")
+	assert.Equal(t, got.Status.ErrorBudget.SLI, "99.95")
+
+	q.err = errors.New("connection refused")
+	got = reconcileSLO(t, rm, got)
+	assert.Equal(t, got.Status.ErrorBudget.Error, "connection refused")
+}
+
+func TestReconcileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		slo    func(*stack.SLO)
+		stack  func(*stack.MonitoringStack)
+		objs   []client.Object
+		reason string
+	}{
+		{
+			name:   "stack not found",
+			slo:    func(slo *stack.SLO) { slo.Spec.MonitoringStack = "missing" },
+			reason: MonitoringStackNotFoundReason,
+		},
+		{
+			name:   "invalid query",
+			slo:    func(slo *stack.SLO) { slo.Spec.Indicator.Errors = "sum(" },
+			reason: InvalidSpecReason,
+		},
+		{
+			name:   "nil resource selector",
+			stack:  func(ms *stack.MonitoringStack) { ms.Spec.ResourceSelector = nil },
+			reason: RuleNotSelectedReason,
+		},
+		{
+			name: "unsatisfiable resource selector",
+			stack: func(ms *stack.MonitoringStack) {
+				ms.Spec.ResourceSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"demo"}},
+				}
+			},
+			reason: RuleNotSelectedReason,
+		},
+		{
+			name: "namespace not selected",
+			stack: func(ms *stack.MonitoringStack) {
+				ms.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}}
+			},
+			objs:   []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}},
+			reason: RuleNotSelectedReason,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			slo := newSLO()
+			if tc.slo != nil {
+				tc.slo(slo)
+			}
+			ms := newStack()
+			if tc.stack != nil {
+				tc.stack(ms)
+			}
+
+			q := &fakeQuerier{}
+			rm := newTestManager(q, append(tc.objs, ms, slo)...)
+
+			got := reconcileSLO(t, rm, slo)
+			assert.Equal(t, len(got.Status.Conditions), 1)
+			assert.Equal(t, got.Status.Conditions[0].Status, stack.ConditionFalse)
+			assert.Equal(t, got.Status.Conditions[0].Reason, tc.reason)
+			assert.Assert(t, got.Status.ErrorBudget == nil)
+			assert.Equal(t, len(q.queries), 0)
+
+			err := rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "api-slo"}, &monv1.PrometheusRule{})
+			assert.Assert(t, apierrors.IsNotFound(err))
+		})
+	}
+}
+
+func TestFindSLOsForStack(t *testing.T) {
+	other := newSLO()
+	other.Name = "other"
+	other.Spec.MonitoringStack = "other"
+	otherNamespace := newSLO()
+	otherNamespace.Namespace = "other"
+
+	rm := newTestManager(&fakeQuerier{}, newSLO(), other, otherNamespace)
+
+	assert.DeepEqual(t, rm.findSLOsForStack(context.Background(), newStack()), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "api"}},
+	})
+}
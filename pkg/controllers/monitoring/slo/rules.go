@@ -0,0 +1,291 @@
+package slo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	sloLabel          = "slo"
+	sloNamespaceLabel = "slo_namespace"
+
+	errorRatioMetricPrefix = "slo:sli_error:ratio_rate"
+	objectiveMetric        = "slo:objective:ratio"
+	budgetRemainingMetric  = "slo:error_budget:remaining"
+
+	defaultWindow    = "28d"
+	defaultAlertName = "SLOErrorBudgetBurn"
+)
+
+// sliWindows are the windows of the error ratios recorded from the SLI
+// queries, as required by the burn rate alerts.
+var sliWindows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// burnRate fires when the error ratio over both windows exceeds factor
+// times the error budget.
+type burnRate struct {
+	long, short string
+	factor      float64
+}
+
+// Multi-window, multi-burn-rate alerts as recommended by the Google SRE
+// workbook (https://sre.google/workbook/alerting-on-slos/).
+var (
+	pageBurnRates   = []burnRate{{long: "1h", short: "5m", factor: 14.4}, {long: "6h", short: "30m", factor: 6}}
+	ticketBurnRates = []burnRate{{long: "1d", short: "2h", factor: 3}, {long: "3d", short: "6h", factor: 1}}
+)
+
+// errorBudget returns the error budget of the SLO as a ratio (e.g. 0.001 for
+// an objective of 99.9).
+func errorBudget(slo *stack.SLO) (float64, error) {
+	objective, err := strconv.ParseFloat(slo.Spec.Objective, 64)
+	if err != nil || objective <= 0 || objective >= 100 {
+		return 0, fmt.Errorf("invalid objective %q: must be greater than 0 and less than 100", slo.Spec.Objective)
+	}
+
+	// Round to avoid floating point artifacts in the generated rules.
+	return math.Round((100-objective)*1e10) / 1e12, nil
+}
+
+// window returns the normalized window of the SLO.
+func window(slo *stack.SLO) (string, error) {
+	w := string(slo.Spec.Window)
+	if w == "" {
+		w = defaultWindow
+	}
+
+	d, err := model.ParseDuration(w)
+	if err != nil || d == 0 {
+		return "", fmt.Errorf("invalid window %q", w)
+	}
+	if time.Duration(d) < 24*time.Hour {
+		return "", fmt.Errorf("invalid window %q: must be at least 1d", w)
+	}
+
+	return d.String(), nil
+}
+
+func errorRatioMetric(window string) string {
+	return errorRatioMetricPrefix + window
+}
+
+// selector returns the PromQL selector of the series recorded for the SLO.
+func selector(slo *stack.SLO, metric string) string {
+	return fmt.Sprintf("%s{%s=%q, %s=%q}", metric, sloLabel, slo.Name, sloNamespaceLabel, slo.Namespace)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// renderQuery replaces the window placeholder of the SLI query and checks
+// that the result is a valid PromQL expression.
+func renderQuery(field string, query string, window string) (string, error) {
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s query: %w", field, err)
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, map[string]string{"window": window}); err != nil {
+		return "", fmt.Errorf("invalid %s query: %w", field, err)
+	}
+
+	rendered := b.String()
+	if rendered == query {
+		return "", fmt.Errorf("invalid %s query: missing {{.window}} placeholder", field)
+	}
+	if _, err := parser.ParseExpr(rendered); err != nil {
+		return "", fmt.Errorf("invalid %s query: %w", field, err)
+	}
+
+	return rendered, nil
+}
+
+func prometheusRuleName(slo *stack.SLO) string {
+	return slo.Name + "-slo"
+}
+
+// newPrometheusRule returns the PrometheusRule implementing the SLO. The
+// caller is responsible for setting labels matching the rule selector of the
+// stack.
+func newPrometheusRule(slo *stack.SLO) (*monv1.PrometheusRule, error) {
+	budget, err := errorBudget(slo)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := window(slo)
+	if err != nil {
+		return nil, err
+	}
+
+	sloLabels := map[string]string{
+		sloLabel:          slo.Name,
+		sloNamespaceLabel: slo.Namespace,
+	}
+
+	var recordingRules []monv1.Rule
+	for _, sliWindow := range sliWindows {
+		errQuery, err := renderQuery("errors", slo.Spec.Indicator.Errors, sliWindow)
+		if err != nil {
+			return nil, err
+		}
+		totalQuery, err := renderQuery("total", slo.Spec.Indicator.Total, sliWindow)
+		if err != nil {
+			return nil, err
+		}
+
+		recordingRules = append(recordingRules, monv1.Rule{
+			Record: errorRatioMetric(sliWindow),
+			Expr:   intstr.FromString(fmt.Sprintf("(%s)\n/\n(%s)", errQuery, totalQuery)),
+			Labels: sloLabels,
+		})
+	}
+
+	// The error ratio over the SLO window is averaged from the shortest
+	// window instead of evaluating the SLI queries over a long range.
+	if !slices.Contains(sliWindows, w) {
+		shortest := selector(slo, errorRatioMetric(sliWindows[0]))
+		recordingRules = append(recordingRules, monv1.Rule{
+			Record: errorRatioMetric(w),
+			Expr:   intstr.FromString(fmt.Sprintf("sum_over_time(%s[%s])\n/\ncount_over_time(%s[%s])", shortest, w, shortest, w)),
+			Labels: sloLabels,
+		})
+	}
+
+	recordingRules = append(recordingRules,
+		monv1.Rule{
+			Record: objectiveMetric,
+			Expr:   intstr.FromString(fmt.Sprintf("vector(%s)", formatFloat(1-budget))),
+			Labels: sloLabels,
+		},
+		monv1.Rule{
+			Record: budgetRemainingMetric,
+			Expr:   intstr.FromString(fmt.Sprintf("1 - (%s / %s)", selector(slo, errorRatioMetric(w)), formatFloat(budget))),
+			Labels: sloLabels,
+		},
+	)
+
+	groups := []monv1.RuleGroup{{
+		Name:  fmt.Sprintf("slo-%s-recordings", slo.Name),
+		Rules: recordingRules,
+	}}
+
+	if alerting := slo.Spec.Alerting; alerting == nil || !alerting.Disabled {
+		groups = append(groups, monv1.RuleGroup{
+			Name:  fmt.Sprintf("slo-%s-alerts", slo.Name),
+			Rules: alertingRules(slo, budget),
+		})
+	}
+
+	return &monv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: monv1.SchemeGroupVersion.String(),
+			Kind:       monv1.PrometheusRuleKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      prometheusRuleName(slo),
+			Namespace: slo.Namespace,
+		},
+		Spec: monv1.PrometheusRuleSpec{Groups: groups},
+	}, nil
+}
+
+func alertingRules(slo *stack.SLO, budget float64) []monv1.Rule {
+	name := defaultAlertName
+	pageLabels := map[string]string{"severity": "critical"}
+	ticketLabels := map[string]string{"severity": "warning"}
+	annotations := map[string]string{
+		"summary":     fmt.Sprintf("SLO %s is burning its error budget too fast.", slo.Name),
+		"description": fmt.Sprintf("The error budget of the SLO %s/%s (objective %s%% over %s) will be exhausted before the end of the window at the current error rate.", slo.Namespace, slo.Name, slo.Spec.Objective, windowOrDefault(slo)),
+	}
+
+	if a := slo.Spec.Alerting; a != nil {
+		if a.Name != "" {
+			name = a.Name
+		}
+		if len(a.PageLabels) > 0 {
+			pageLabels = a.PageLabels
+		}
+		if len(a.TicketLabels) > 0 {
+			ticketLabels = a.TicketLabels
+		}
+		if len(a.Annotations) > 0 {
+			annotations = a.Annotations
+		}
+	}
+
+	rule := func(labels map[string]string, rates []burnRate) monv1.Rule {
+		exprs := make([]string, 0, len(rates))
+		for _, r := range rates {
+			threshold := fmt.Sprintf("(%s * %s)", formatFloat(r.factor), formatFloat(budget))
+			exprs = append(exprs, fmt.Sprintf("(%s > %s\nand\n%s > %s)",
+				selector(slo, errorRatioMetric(r.long)), threshold,
+				selector(slo, errorRatioMetric(r.short)), threshold,
+			))
+		}
+
+		return monv1.Rule{
+			Alert:       name,
+			Expr:        intstr.FromString(strings.Join(exprs, "\nor\n")),
+			Labels:      maps.Clone(labels),
+			Annotations: maps.Clone(annotations),
+		}
+	}
+
+	return []monv1.Rule{
+		rule(pageLabels, pageBurnRates),
+		rule(ticketLabels, ticketBurnRates),
+	}
+}
+
+func windowOrDefault(slo *stack.SLO) string {
+	if slo.Spec.Window == "" {
+		return defaultWindow
+	}
+	return string(slo.Spec.Window)
+}
+
+// ruleLabels returns labels matching the resource selector of the stack so
+// that the generated PrometheusRule is selected by its Prometheus.
+func ruleLabels(ms *stack.MonitoringStack) (map[string]string, error) {
+	sel := ms.Spec.ResourceSelector
+	if sel == nil {
+		return nil, errors.New("the MonitoringStack doesn't select any PrometheusRule (resourceSelector is null)")
+	}
+
+	labels := map[string]string{}
+	maps.Copy(labels, sel.MatchLabels)
+	for _, req := range sel.MatchExpressions {
+		if _, found := labels[req.Key]; found {
+			continue
+		}
+		switch req.Operator {
+		case metav1.LabelSelectorOpIn:
+			if len(req.Values) > 0 {
+				labels[req.Key] = req.Values[0]
+			}
+		case metav1.LabelSelectorOpExists:
+			labels[req.Key] = "true"
+		}
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,232 @@
+package slo
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/promql/parser"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newSLO() *stack.SLO {
+	return &stack.SLO{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns", Generation: 1},
+		Spec: stack.SLOSpec{
+			MonitoringStack: "ms",
+			Objective:       "99.9",
+			Window:          "28d",
+			Indicator: stack.SLIRatio{
+				Errors: `sum(rate(http_requests_total{code=~"5.."}[{{.window}}]))`,
+				Total:  `sum(rate(http_requests_total[{{.window}}]))`,
+			},
+		},
+	}
+}
+
+func TestErrorBudget(t *testing.T) {
+	for _, tc := range []struct {
+		objective string
+		budget    float64
+		err       bool
+	}{
+		{objective: "99.9", budget: 0.001},
+		{objective: "99.95", budget: 0.0005},
+		{objective: "95", budget: 0.05},
+		{objective: "0", err: true},
+		{objective: "100", err: true},
+		{objective: "abc", err: true},
+	} {
+		t.Run(tc.objective, func(t *testing.T) {
+			slo := newSLO()
+			slo.Spec.Objective = tc.objective
+
+			budget, err := errorBudget(slo)
+			if tc.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, budget, tc.budget)
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	for _, tc := range []struct {
+		window string
+		exp    string
+		err    bool
+	}{
+		{window: "", exp: "4w"},
+		{window: "30d", exp: "30d"},
+		{window: "24h", exp: "1d"},
+		{window: "1h", err: true},
+		{window: "0d", err: true},
+		{window: "foo", err: true},
+	} {
+		t.Run(tc.window, func(t *testing.T) {
+			slo := newSLO()
+			slo.Spec.Window = monv1.Duration(tc.window)
+
+			w, err := window(slo)
+			if tc.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, w, tc.exp)
+		})
+	}
+}
+
+func TestRenderQuery(t *testing.T) {
+	q, err := renderQuery("errors", `sum(rate(errors_total[{{.window}}]))`, "5m")
+	assert.NilError(t, err)
+	assert.Equal(t, q, `sum(rate(errors_total[5m]))`)
+
+	for _, query := range []string{
+		`sum(rate(errors_total[5m]))`,
+		`sum(rate(errors_total[{{.range}}]))`,
+		`sum(rate(errors_total[{{.window}}])`,
+		`sum(rate(errors_total[{{.window]))`,
+	} {
+		_, err := renderQuery("errors", query, "5m")
+		assert.Assert(t, err != nil, query)
+	}
+}
+
+func TestNewPrometheusRule(t *testing.T) {
+	slo := newSLO()
+
+	pr, err := newPrometheusRule(slo)
+	assert.NilError(t, err)
+	assert.Equal(t, pr.Name, "api-slo")
+	assert.Equal(t, pr.Namespace, "ns")
+	assert.Equal(t, len(pr.Spec.Groups), 2)
+
+	recordings := pr.Spec.Groups[0]
+	assert.Equal(t, recordings.Name, "slo-api-recordings")
+	var records []string
+	for _, r := range recordings.Rules {
+		records = append(records, r.Record)
+		assert.DeepEqual(t, r.Labels, map[string]string{"slo": "api", "slo_namespace": "ns"})
+		_, err := parser.ParseExpr(r.Expr.String())
+		assert.NilError(t, err, r.Record)
+	}
+	assert.DeepEqual(t, records, []string{
+		"slo:sli_error:ratio_rate5m",
+		"slo:sli_error:ratio_rate30m",
+		"slo:sli_error:ratio_rate1h",
+		"slo:sli_error:ratio_rate2h",
+		"slo:sli_error:ratio_rate6h",
+		"slo:sli_error:ratio_rate1d",
+		"slo:sli_error:ratio_rate3d",
+		"slo:sli_error:ratio_rate4w",
+		"slo:objective:ratio",
+		"slo:error_budget:remaining",
+	})
+	assert.Equal(t, recordings.Rules[0].Expr.String(), "(sum(rate(http_requests_total{code=~\"5..\"}[5m])))\n/\n(sum(rate(http_requests_total[5m])))")
+	assert.Equal(t, recordings.Rules[8].Expr.String(), "vector(0.999)")
+	assert.Equal(t, recordings.Rules[9].Expr.String(), `1 - (slo:sli_error:ratio_rate4w{slo="api", slo_namespace="ns"} / 0.001)`)
+
+	alerts := pr.Spec.Groups[1]
+	assert.Equal(t, alerts.Name, "slo-api-alerts")
+	assert.Equal(t, len(alerts.Rules), 2)
+	for _, r := range alerts.Rules {
+		assert.Equal(t, r.Alert, "SLOErrorBudgetBurn")
+		_, err := parser.ParseExpr(r.Expr.String())
+		assert.NilError(t, err)
+	}
+	assert.DeepEqual(t, alerts.Rules[0].Labels, map[string]string{"severity": "critical"})
+	assert.DeepEqual(t, alerts.Rules[1].Labels, map[string]string{"severity": "warning"})
+	assert.Equal(t, alerts.Rules[0].Expr.String(), `(slo:sli_error:ratio_rate1h{slo="api", slo_namespace="ns"} > (14.4 * 0.001)
+and
+slo:sli_error:ratio_rate5m{slo="api", slo_namespace="ns"} > (14.4 * 0.001))
+or
+(slo:sli_error:ratio_rate6h{slo="api", slo_namespace="ns"} > (6 * 0.001)
+and
+slo:sli_error:ratio_rate30m{slo="api", slo_namespace="ns"} > (6 * 0.001))`)
+}
+
+func TestNewPrometheusRuleAlerting(t *testing.T) {
+	slo := newSLO()
+	slo.Spec.Window = "3d"
+	slo.Spec.Alerting = &stack.SLOAlerting{
+		Name:         "APIErrorBudgetBurn",
+		PageLabels:   map[string]string{"severity": "page"},
+		TicketLabels: map[string]string{"severity": "ticket"},
+		Annotations:  map[string]string{"runbook_url": "https://example.com"},
+	}
+
+	pr, err := newPrometheusRule(slo)
+	assert.NilError(t, err)
+
+	// The SLO window is already recorded.
+	assert.Equal(t, len(pr.Spec.Groups[0].Rules), len(sliWindows)+2)
+
+	alerts := pr.Spec.Groups[1].Rules
+	assert.Equal(t, alerts[0].Alert, "APIErrorBudgetBurn")
+	assert.DeepEqual(t, alerts[0].Labels, map[string]string{"severity": "page"})
+	assert.DeepEqual(t, alerts[1].Labels, map[string]string{"severity": "ticket"})
+	assert.DeepEqual(t, alerts[1].Annotations, map[string]string{"runbook_url": "https://example.com"})
+
+	slo.Spec.Alerting.Disabled = true
+	pr, err = newPrometheusRule(slo)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pr.Spec.Groups), 1)
+}
+
+func TestNewPrometheusRuleInvalid(t *testing.T) {
+	slo := newSLO()
+	slo.Spec.Indicator.Total = `sum(rate(http_requests_total[5m]))`
+
+	_, err := newPrometheusRule(slo)
+	assert.ErrorContains(t, err, "invalid total query: missing {{.window}} placeholder")
+}
+
+func TestRuleLabels(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		selector *metav1.LabelSelector
+		exp      map[string]string
+		err      bool
+	}{
+		{
+			name: "nil selector",
+			err:  true,
+		},
+		{
+			name:     "empty selector",
+			selector: &metav1.LabelSelector{},
+			exp:      map[string]string{},
+		},
+		{
+			name: "labels and expressions",
+			selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "demo"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"other"}},
+					{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+					{Key: "monitored", Operator: metav1.LabelSelectorOpExists},
+					{Key: "ignored", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			exp: map[string]string{"app": "demo", "team": "a", "monitored": "true"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &stack.MonitoringStack{Spec: stack.MonitoringStackSpec{ResourceSelector: tc.selector}}
+
+			labels, err := ruleLabels(ms)
+			if tc.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, labels, tc.exp)
+		})
+	}
+}
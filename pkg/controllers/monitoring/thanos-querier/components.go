@@ -2,40 +2,198 @@ package thanos_querier
 
 import (
 	"fmt"
+	"path/filepath"
 
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 
 	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/rhobs/observability-operator/pkg/reconciler"
 )
 
-func thanosComponentReconcilers(thanos *msoapi.ThanosQuerier, sidecarUrls []string, thanosCfg ThanosConfiguration) []reconciler.Reconciler {
+// httpPort is the port on which the Thanos querier serves its HTTP API.
+const httpPort = 10902
+
+func thanosComponentReconcilers(thanos *msoapi.ThanosQuerier, stacks *discoveredStacks, grpcTLSSecret *corev1.Secret, tenancyTLSSecret *corev1.Secret, thanosCfg ThanosConfiguration) ([]reconciler.Reconciler, error) {
 	name := "thanos-querier-" + thanos.Name
-	return []reconciler.Reconciler{
+	frontendName := "thanos-query-frontend-" + thanos.Name
+	// When the query frontend is deployed, the main Service targets the
+	// query frontend which reaches the querier through the downstream
+	// Service.
+	downstreamName := name + "-downstream"
+	deployFrontend := queryFrontendEnabled(thanos)
+	tenancy := tenancyEnabled(thanos)
+	if deployFrontend && tenancy {
+		// The query frontend would bypass the tenancy proxies.
+		return nil, fmt.Errorf("the query frontend can't be deployed in tenancy mode")
+	}
+
+	serviceTarget := name
+	var cacheConfig string
+	if deployFrontend {
+		serviceTarget = frontendName
+
+		var err error
+		cacheConfig, err = newResponseCacheConfig(thanos.Spec.QueryFrontend.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query frontend cache: %w", err)
+		}
+	}
+
+	if thanos.Spec.IncludeOpenShiftPlatform && thanos.Spec.OpenShiftPlatformTLSSecretName == "" {
+		return nil, fmt.Errorf("openShiftPlatformTLSSecretName is required to query the OpenShift platform")
+	}
+
+	if err := validateQueryConfig(thanos); err != nil {
+		return nil, fmt.Errorf("invalid query configuration: %w", err)
+	}
+
+	endpointConfig, err := newEndpointConfig(thanos, stacks.tlsEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint configuration: %w", err)
+	}
+
+	reconcilers := grpcTLSReconcilers(thanos, grpcTLSSecret)
+	reconcilers = append(reconcilers, tenancyReconcilers(thanos, name, tenancyTLSSecret)...)
+	return append(reconcilers,
 		reconciler.NewUpdater(newServiceAccount(name, thanos.Namespace), thanos),
-		reconciler.NewUpdater(newThanosQuerierDeployment(name, thanos, sidecarUrls, thanosCfg), thanos),
-		reconciler.NewUpdater(newService(name, thanos.Namespace), thanos),
-		reconciler.NewUpdater(newServiceMonitor(name, thanos.Namespace), thanos),
+		reconciler.NewUpdater(newThanosQuerierDeployment(name, thanos, stacks.endpoints, stacks.tlsEndpoints, endpointConfig, thanosCfg), thanos),
+		// In tenancy mode, the querier API is only served by the proxies.
+		reconciler.NewOptionalUpdater(newService(name, thanos.Namespace, serviceTarget), thanos, !tenancy),
+		reconciler.NewOptionalUpdater(newServiceMonitor(name, thanos.Namespace), thanos, !tenancy),
+		reconciler.NewOptionalUpdater(newPodDisruptionBudget(name, thanos.Namespace), thanos, replicas(thanos) > 1),
+
+		// Query frontend
+		reconciler.NewOptionalUpdater(newService(downstreamName, thanos.Namespace, name), thanos, deployFrontend),
+		// The main ServiceMonitor scrapes the query frontend, the querier is
+		// scraped through the downstream Service.
+		reconciler.NewOptionalUpdater(newServiceMonitor(downstreamName, thanos.Namespace), thanos, deployFrontend),
+		reconciler.NewOptionalUpdater(newQueryFrontendDeployment(frontendName, downstreamName, thanos, cacheConfig, thanosCfg), thanos, deployFrontend),
+		reconciler.NewOptionalUpdater(newPodDisruptionBudget(frontendName, thanos.Namespace), thanos, deployFrontend && frontendReplicas(thanos) > 1),
+	), nil
+}
+
+// thanosComponentCleanup returns the reconcilers deleting the cluster-scoped
+// resources of the querier when it's deleted.
+func thanosComponentCleanup(thanos *msoapi.ThanosQuerier) []reconciler.Reconciler {
+	name := "thanos-querier-" + thanos.Name
+	return []reconciler.Reconciler{
+		reconciler.NewDeleter(newTenancyClusterRoleBinding(thanos, name)),
 	}
 }
 
-func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecarUrls []string, thanosCfg ThanosConfiguration) *appsv1.Deployment {
+// replicas returns the number of replicas of the Thanos querier.
+func replicas(thanos *msoapi.ThanosQuerier) int32 {
+	if thanos.Spec.Replicas == nil {
+		return 1
+	}
+	return *thanos.Spec.Replicas
+}
+
+func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecarUrls []string, tlsEndpoints []endpointGroup, endpointConfig string, thanosCfg ThanosConfiguration) *appsv1.Deployment {
 	args := []string{
 		"query",
 		"--log.format=logfmt",
-		"--query.replica-label=prometheus_replica",
-		"--query.auto-downsampling",
 	}
+	for _, rl := range replicaLabels(spec) {
+		args = append(args, fmt.Sprintf("--query.replica-label=%s", rl))
+	}
+	args = append(args, "--query.auto-downsampling")
+	args = append(args, queryArgs(spec)...)
+
 	for _, endpoint := range sidecarUrls {
 		args = append(args, fmt.Sprintf("--endpoint=%s", endpoint))
 	}
 
-	for _, rl := range spec.Spec.ReplicaLabels {
-		args = append(args, fmt.Sprintf("--query.replica-label=%s", rl))
+	if spec.Spec.LogLevel != "" {
+		args = append(args, fmt.Sprintf("--log.level=%s", spec.Spec.LogLevel))
+	}
+
+	args = append(args, tenancyArgs(spec)...)
+
+	var (
+		volumes      []corev1.Volume
+		volumeMounts []corev1.VolumeMount
+	)
+
+	// The sidecars and the endpoints with their own TLS configuration
+	// are declared in the endpoint configuration.
+	if endpointConfig != "" {
+		args = append(args, fmt.Sprintf("--endpoint.sd-config=%s", endpointConfig))
+	}
+	for i, secret := range endpointTLSSecrets(spec, tlsEndpoints) {
+		volume := fmt.Sprintf("endpoint-tls-%d", i)
+		volumes = append(volumes, corev1.Volume{
+			Name: volume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secret,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      volume,
+			MountPath: filepath.Join(endpointTLSMountPath, secret),
+			ReadOnly:  true,
+		})
+	}
+
+	// In tenancy mode, the HTTP API of the querier is bound to the loopback
+	// interface and its metrics are served by kube-rbac-proxy.
+	var ports []corev1.ContainerPort
+	if !tenancyEnabled(spec) {
+		ports = []corev1.ContainerPort{
+			{
+				ContainerPort: httpPort,
+				Name:          "metrics",
+			},
+		}
+	}
+
+	containers := []corev1.Container{
+		{
+			Name:                     "thanos-querier",
+			Args:                     args,
+			Image:                    thanosCfg.Image,
+			Ports:                    ports,
+			Resources:                spec.Spec.Resources,
+			VolumeMounts:             volumeMounts,
+			TerminationMessagePolicy: "FallbackToLogsOnError",
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				Capabilities: &corev1.Capabilities{
+					Drop: []corev1.Capability{
+						"ALL",
+					},
+				},
+				RunAsNonRoot: ptr.To(true),
+				SeccompProfile: &corev1.SeccompProfile{
+					Type: corev1.SeccompProfileTypeRuntimeDefault,
+				},
+			},
+		},
+	}
+	if tenancyEnabled(spec) {
+		containers = append(containers, newTenancyContainers(spec, thanosCfg)...)
+		volumes = append(volumes, newTenancyVolumes(spec, name)...)
+	}
+
+	nodeSelector := spec.Spec.NodeSelector
+	if len(nodeSelector) == 0 {
+		nodeSelector = map[string]string{
+			"kubernetes.io/os": "linux",
+		}
+	}
+
+	affinity := spec.Spec.Affinity
+	if affinity == nil && replicas(spec) > 1 {
+		affinity = newAntiAffinity(name)
 	}
 
 	thanos := &appsv1.Deployment{
@@ -49,7 +207,7 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 			Labels:    componentLabels(name),
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: ptr.To(int32(1)),
+			Replicas: ptr.To(replicas(spec)),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app.kubernetes.io/instance": name,
@@ -62,35 +220,14 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 					Labels:    componentLabels(name),
 				},
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "thanos-querier",
-							Args:  args,
-							Image: thanosCfg.Image,
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 10902,
-									Name:          "metrics",
-								},
-							},
-							TerminationMessagePolicy: "FallbackToLogsOnError",
-							SecurityContext: &corev1.SecurityContext{
-								AllowPrivilegeEscalation: ptr.To(false),
-								Capabilities: &corev1.Capabilities{
-									Drop: []corev1.Capability{
-										"ALL",
-									},
-								},
-								RunAsNonRoot: ptr.To(true),
-								SeccompProfile: &corev1.SeccompProfile{
-									Type: corev1.SeccompProfileTypeRuntimeDefault,
-								},
-							},
-						},
-					},
-					NodeSelector: map[string]string{
-						"kubernetes.io/os": "linux",
-					},
+					ServiceAccountName:        name,
+					Containers:                containers,
+					Volumes:                   volumes,
+					NodeSelector:              nodeSelector,
+					Tolerations:               spec.Spec.Tolerations,
+					Affinity:                  affinity,
+					TopologySpreadConstraints: spec.Spec.TopologySpreadConstraints,
+					PriorityClassName:         spec.Spec.PriorityClassName,
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: ptr.To(true),
 						SeccompProfile: &corev1.SeccompProfile{
@@ -106,6 +243,64 @@ func newThanosQuerierDeployment(name string, spec *msoapi.ThanosQuerier, sidecar
 	return thanos
 }
 
+// newAntiAffinity spreads the replicas of the Thanos querier across nodes and
+// zones. Unlike for Prometheus and Alertmanager, the spreading across nodes is
+// only preferred since a required anti-affinity would block the rolling
+// updates of the deployment on clusters with as many nodes as replicas.
+func newAntiAffinity(name string) *corev1.Affinity {
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app.kubernetes.io/instance": name,
+		},
+	}
+
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: selector,
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				},
+				{
+					Weight: 50,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: selector,
+						TopologyKey:   "topology.kubernetes.io/zone",
+					},
+				},
+			},
+		},
+	}
+}
+
+func newPodDisruptionBudget(name string, namespace string) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: policyv1.SchemeGroupVersion.String(),
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &intstr.IntOrString{
+				Type:   intstr.Int,
+				IntVal: 1,
+			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+		},
+	}
+}
+
 func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
@@ -119,7 +314,8 @@ func newServiceAccount(name string, namespace string) *corev1.ServiceAccount {
 	}
 }
 
-func newService(name string, namespace string) *corev1.Service {
+// newService returns a Service targeting the pods of the given component.
+func newService(name string, namespace string, target string) *corev1.Service {
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: corev1.SchemeGroupVersion.String(),
@@ -128,16 +324,17 @@ func newService(name string, namespace string) *corev1.Service {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
+			Labels:    componentLabels(name),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
 				{
-					Port: 10902,
+					Port: httpPort,
 					Name: "http",
 				},
 			},
 			Selector: map[string]string{
-				"app.kubernetes.io/instance": name,
+				"app.kubernetes.io/instance": target,
 			},
 			Type: "ClusterIP",
 		},
@@ -171,10 +368,21 @@ func newServiceMonitor(name string, namespace string) *monv1.ServiceMonitor {
 	}
 }
 
+const (
+	partOfLabel = "app.kubernetes.io/part-of"
+	partOfValue = "ThanosQuerier"
+)
+
 func componentLabels(querierName string) map[string]string {
 	return map[string]string{
 		"app.kubernetes.io/instance":   querierName,
-		"app.kubernetes.io/part-of":    "ThanosQuerier",
+		partOfLabel:                    partOfValue,
 		"app.kubernetes.io/managed-by": "observability-operator",
 	}
 }
+
+// ClusterRoleBindingSelector selects the ClusterRoleBindings managed by the
+// controller. The operator only caches these ClusterRoleBindings.
+func ClusterRoleBindingSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{partOfLabel: partOfValue})
+}
@@ -0,0 +1,70 @@
+package thanos_querier
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestNewThanosQuerierDeployment(t *testing.T) {
+	tq := newQuerier()
+
+	d := newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", ThanosConfiguration{Image: "thanos"})
+	assert.Equal(t, *d.Spec.Replicas, int32(1))
+	pod := d.Spec.Template.Spec
+	assert.DeepEqual(t, pod.NodeSelector, map[string]string{"kubernetes.io/os": "linux"})
+	assert.Assert(t, pod.Affinity == nil)
+	// The service account is bound to the system:auth-delegator role in
+	// tenancy mode.
+	assert.Equal(t, pod.ServiceAccountName, "thanos-querier-tq")
+	assert.DeepEqual(t, pod.Containers[0].Args, []string{
+		"query",
+		"--log.format=logfmt",
+		"--query.replica-label=prometheus_replica",
+		"--query.auto-downsampling",
+	})
+
+	tq.Spec.Replicas = ptr.To(int32(3))
+	tq.Spec.LogLevel = msoapi.Debug
+	tq.Spec.NodeSelector = map[string]string{"node-role.kubernetes.io/infra": ""}
+	tq.Spec.Tolerations = []corev1.Toleration{{Key: "node-role.kubernetes.io/infra", Effect: corev1.TaintEffectNoSchedule}}
+	tq.Spec.PriorityClassName = "system-cluster-critical"
+	tq.Spec.Resources = corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}
+
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", ThanosConfiguration{Image: "thanos"})
+	assert.Equal(t, *d.Spec.Replicas, int32(3))
+	pod = d.Spec.Template.Spec
+	assert.DeepEqual(t, pod.NodeSelector, tq.Spec.NodeSelector)
+	assert.DeepEqual(t, pod.Tolerations, tq.Spec.Tolerations)
+	assert.Equal(t, pod.PriorityClassName, "system-cluster-critical")
+	assert.DeepEqual(t, pod.Containers[0].Resources, tq.Spec.Resources)
+	assert.Equal(t, pod.Containers[0].Args[len(pod.Containers[0].Args)-1], "--log.level=debug")
+
+	// Replicas are spread across nodes unless an affinity is configured.
+	terms := pod.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	assert.Equal(t, len(terms), 2)
+	assert.Equal(t, terms[0].PodAffinityTerm.TopologyKey, "kubernetes.io/hostname")
+	assert.DeepEqual(t, terms[0].PodAffinityTerm.LabelSelector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq"})
+
+	tq.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", ThanosConfiguration{Image: "thanos"})
+	assert.DeepEqual(t, d.Spec.Template.Spec.Affinity, tq.Spec.Affinity)
+}
+
+func TestNewPodDisruptionBudget(t *testing.T) {
+	pdb := newPodDisruptionBudget("thanos-querier-tq", "ns")
+	assert.Equal(t, pdb.Spec.MinAvailable.IntValue(), 1)
+	assert.DeepEqual(t, pdb.Spec.Selector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq"})
+
+	tq := newQuerier()
+	assert.Equal(t, replicas(tq), int32(1))
+	tq.Spec.Replicas = ptr.To(int32(2))
+	assert.Equal(t, replicas(tq), int32(2))
+}
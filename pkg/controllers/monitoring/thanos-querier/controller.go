@@ -14,12 +14,20 @@ package thanos_querier
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -37,18 +45,65 @@ import (
 
 type resourceManager struct {
 	client.Client
-	scheme *runtime.Scheme
-	logger logr.Logger
-	thanos ThanosConfiguration
+	// apiReader reads the Secrets provided by the users which aren't cached
+	// by the operator.
+	apiReader      client.Reader
+	scheme         *runtime.Scheme
+	logger         logr.Logger
+	thanos         ThanosConfiguration
+	newStoreLister func(baseURL string, tlsConfig *tls.Config, token string) (storeLister, error)
+	// Namespace of the operator where the certificate authority issuing the
+	// gRPC certificates is stored.
+	namespace string
 }
 
 type ThanosConfiguration struct {
 	Image string
+	// Images of the proxies deployed in tenancy mode.
+	PromLabelProxyImage string
+	KubeRBACProxyImage  string
+}
+
+const (
+	// finalizerName ensures that the cluster-scoped resources of a
+	// ThanosQuerier, which can't be garbage collected, are deleted. It's only
+	// set in tenancy mode so that the other queriers can be deleted while the
+	// operator isn't running.
+	finalizerName = "monitoring.observability.openshift.io/thanos-querier-finalizer"
+
+	// stacksIndexKey indexes the ThanosQueriers by the MonitoringStacks they
+	// query, as reported in their status (`<namespace>/<name>`).
+	stacksIndexKey = ".status.monitoringStacks"
+
+	// namespacesIndexKey indexes the ThanosQueriers by the namespaces they
+	// select by name. The queriers selecting the namespaces by labels can't
+	// be indexed by namespace and are indexed with anyNamespace.
+	namespacesIndexKey = ".spec.namespaceSelector"
+	anyNamespace       = "*"
+)
+
+func indexQuerierStacks(o client.Object) []string {
+	return o.(*msoapi.ThanosQuerier).Status.MonitoringStacks
+}
+
+func indexQuerierNamespaces(o client.Object) []string {
+	querier := o.(*msoapi.ThanosQuerier)
+	sel := querier.Spec.NamespaceSelector
+	switch {
+	case sel.Any:
+		return []string{anyNamespace}
+	case len(sel.MatchNames) > 0:
+		return sel.MatchNames
+	case len(sel.MatchLabels) > 0 || len(sel.MatchExpressions) > 0:
+		return []string{anyNamespace}
+	}
+	return []string{querier.Namespace}
 }
 
 // Options allows for controller options to be set
 type Options struct {
-	Thanos ThanosConfiguration
+	Thanos    ThanosConfiguration
+	Namespace string
 }
 
 // RBAC for watching monitoring stacks
@@ -59,12 +114,27 @@ type Options struct {
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=thanosqueriers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=thanosqueriers/finalizers,verbs=update
 
+// RBAC for watching the namespaces selected by the thanosqueriers
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
 // RBAC for managing deployments
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;watch;create;update;patch;delete
 
+// RBAC for managing pod disruption budgets
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=list;watch;create;update;patch;delete
+
 // RBAC for managing core resources
 //+kubebuilder:rbac:groups=core,resources=services;serviceaccounts,verbs=list;watch;create;update;patch;delete
 
+// RBAC for managing the gRPC certificates
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+
+// RBAC for the tenancy mode
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
 // RBAC for managing Prometheus Operator CRs
 //+kubebuilder:rbac:groups=monitoring.rhobs,resources=servicemonitors,verbs=list;watch;create;update;patch;delete
 
@@ -73,22 +143,53 @@ func RegisterWithManager(mgr ctrl.Manager, opts Options) error {
 	logger := ctrl.Log.WithName("thanos-querier")
 
 	rm := &resourceManager{
-		Client: mgr.GetClient(),
-		scheme: mgr.GetScheme(),
-		logger: logger,
-		thanos: opts.Thanos,
+		Client:         mgr.GetClient(),
+		apiReader:      mgr.GetAPIReader(),
+		scheme:         mgr.GetScheme(),
+		logger:         logger,
+		thanos:         opts.Thanos,
+		newStoreLister: newStoreLister,
+		namespace:      opts.Namespace,
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &msoapi.ThanosQuerier{}, stacksIndexKey, indexQuerierStacks); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &msoapi.ThanosQuerier{}, namespacesIndexKey, indexQuerierNamespaces); err != nil {
+		return err
 	}
 
-	p := predicate.GenerationChangedPredicate{}
+	// The predicates are set per watch since the label changes of the
+	// namespaces don't change their generation. The manual changes of the
+	// owned resources are reverted: the resources with a generation are
+	// reconciled when their spec or labels change, the others on any change.
+	generationChanged := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	specOrLabelsChanged := builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))
+	resourceVersionChanged := builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&msoapi.ThanosQuerier{}).
-		Owns(&appsv1.Deployment{}).WithEventFilter(p).
-		Owns(&corev1.ServiceAccount{}).WithEventFilter(p).
-		Owns(&corev1.Service{}).WithEventFilter(p).
+		For(&msoapi.ThanosQuerier{}, generationChanged).
+		Owns(&appsv1.Deployment{}, specOrLabelsChanged).
+		Owns(&policyv1.PodDisruptionBudget{}, specOrLabelsChanged).
+		Owns(&monv1.ServiceMonitor{}, specOrLabelsChanged).
+		Owns(&corev1.ServiceAccount{}, resourceVersionChanged).
+		Owns(&corev1.Service{}, resourceVersionChanged).
+		Owns(&corev1.Secret{}, resourceVersionChanged).
+		Owns(&corev1.ConfigMap{}, resourceVersionChanged).
+		Watches(
+			&rbacv1.ClusterRoleBinding{},
+			handler.EnqueueRequestsFromMapFunc(rm.findQuerierForClusterRoleBinding),
+			resourceVersionChanged,
+		).
 		Watches(
 			&msoapi.MonitoringStack{},
 			handler.EnqueueRequestsFromMapFunc(rm.findQueriersForMonitoringStack),
-			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+			// Only the labels of the stacks are used by the queriers.
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(rm.findQueriersForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
 		).
 		Complete(rm)
 }
@@ -106,14 +207,57 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
-	sidecarServices, err := rm.findSidecarServices(ctx, querier)
+	if !querier.DeletionTimestamp.IsZero() {
+		logger.V(6).Info("removing cluster scoped resources")
+		for _, reconciler := range thanosComponentCleanup(querier) {
+			if err := reconciler.Reconcile(ctx, rm, rm.scheme); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, rm.setFinalizer(ctx, querier, false)
+	}
+
+	// The finalizer is added before the cluster-scoped resources are
+	// created and only removed once they have been deleted.
+	if tenancyEnabled(querier) {
+		if err := rm.setFinalizer(ctx, querier, true); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	stacks, err := rm.findSidecarServices(ctx, querier)
 	if client.IgnoreNotFound(err) != nil {
 		// we encountered an error other then NotFound, don't try to delete
 		// resources for this querier and reschedule reconcile
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
 	}
 
-	reconcilers := thanosComponentReconcilers(querier, sidecarServices, rm.thanos)
+	if err := rm.findAdditionalEndpoints(ctx, querier, stacks); err != nil {
+		return rm.updateStatus(ctx, querier, stacks, err), err
+	}
+
+	grpcTLSSecret, err := rm.grpcTLSSecret(ctx, querier)
+	if errors.Is(err, errGRPCClientNotAllowed) {
+		// The querier is reconciled again when its namespace is labelled.
+		logger.Info("invalid thanos querier configuration", "err", err)
+		return rm.updateStatus(ctx, querier, stacks, err), nil
+	}
+	if err != nil {
+		return rm.updateStatus(ctx, querier, stacks, err), err
+	}
+
+	tenancyTLSSecret, err := rm.tenancyTLSSecret(ctx, querier)
+	if err != nil {
+		return rm.updateStatus(ctx, querier, stacks, err), err
+	}
+
+	reconcilers, err := thanosComponentReconcilers(querier, stacks, grpcTLSSecret, tenancyTLSSecret, rm.thanos)
+	if err != nil {
+		// The configuration is invalid: report the error and wait for the
+		// querier to be updated.
+		logger.Info("invalid thanos querier configuration", "err", err)
+		return rm.updateStatus(ctx, querier, stacks, err), nil
+	}
 	for _, reconciler := range reconcilers {
 		err := reconciler.Reconcile(ctx, rm, rm.scheme)
 		// handle creation / updation errors that can happen due to a stale cache by
@@ -123,15 +267,48 @@ func (rm resourceManager) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
 		}
 		if err != nil {
-			return ctrl.Result{}, err
+			return rm.updateStatus(ctx, querier, stacks, err), err
+		}
+	}
+
+	if !tenancyEnabled(querier) {
+		if err := rm.setFinalizer(ctx, querier, false); err != nil {
+			return rm.updateStatus(ctx, querier, stacks, err), err
 		}
 	}
-	return ctrl.Result{}, nil
+	return rm.updateStatus(ctx, querier, stacks, nil), nil
+}
+
+// setFinalizer adds or removes the finalizer of the querier.
+func (rm resourceManager) setFinalizer(ctx context.Context, querier *msoapi.ThanosQuerier, present bool) error {
+	if slices.Contains(querier.Finalizers, finalizerName) == present {
+		return nil
+	}
+
+	if present {
+		querier.Finalizers = append(querier.Finalizers, finalizerName)
+	} else {
+		querier.Finalizers = slices.DeleteFunc(querier.Finalizers, func(f string) bool {
+			return f == finalizerName
+		})
+	}
+	return rm.Update(ctx, querier)
+}
+
+// discoveredStacks holds the MonitoringStacks matching the selector of a
+// ThanosQuerier, as `<namespace>/<name>`, the endpoints of their sidecar
+// services and the additional endpoints of the querier.
+type discoveredStacks struct {
+	selected  []string
+	excluded  []string
+	endpoints []string
+	// Additional endpoints with their own TLS configuration.
+	tlsEndpoints []endpointGroup
 }
 
 // Given a ThanosQuerier object, find the matching MonitoringStacks, extract the
 // sidecar service and return a list of urls for those sidecar services.
-func (rm resourceManager) findSidecarServices(ctx context.Context, tQuerier *msoapi.ThanosQuerier) ([]string, error) {
+func (rm resourceManager) findSidecarServices(ctx context.Context, tQuerier *msoapi.ThanosQuerier) (*discoveredStacks, error) {
 	logger := rm.logger.WithValues("selector", tQuerier.Spec.Selector)
 
 	msList := &msoapi.MonitoringStackList{}
@@ -140,20 +317,51 @@ func (rm resourceManager) findSidecarServices(ctx context.Context, tQuerier *mso
 		client.MatchingLabelsSelector{Selector: selector},
 	}
 
-	var sidecarUrls []string
+	stacks := &discoveredStacks{}
 	if err := rm.List(ctx, msList, opts...); err != nil {
 		logger.Info("Couldn't find any MonitoringStack")
-		return sidecarUrls, err
+		return stacks, err
 	}
 	logger.Info("Found MonitoringStacks list", "length", len(msList.Items))
+	sort.Slice(msList.Items, func(i, j int) bool {
+		if msList.Items[i].Namespace != msList.Items[j].Namespace {
+			return msList.Items[i].Namespace < msList.Items[j].Namespace
+		}
+		return msList.Items[i].Name < msList.Items[j].Name
+	})
+	namespaces := map[string]*corev1.Namespace{}
 	for _, ms := range msList.Items {
-		if tQuerier.MatchesNamespace(ms.Namespace) {
-			serviceName := ms.Name + "-thanos-sidecar"
-			sidecarUrls = append(sidecarUrls, getEndpointUrl(serviceName, ms.Namespace))
+		ns, found := namespaces[ms.Namespace]
+		if !found {
+			ns = &corev1.Namespace{}
+			if err := rm.Get(ctx, client.ObjectKey{Name: ms.Namespace}, ns); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return stacks, err
+				}
+				// The namespace is being deleted: only its name is known.
+				ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ms.Namespace}}
+			}
+			namespaces[ms.Namespace] = ns
 		}
+
+		if !tQuerier.MatchesNamespaceObject(ns) {
+			stacks.excluded = append(stacks.excluded, ms.Namespace+"/"+ms.Name)
+			continue
+		}
+		serviceName := ms.Name + "-thanos-sidecar"
+		stacks.selected = append(stacks.selected, ms.Namespace+"/"+ms.Name)
+		if tQuerier.Spec.GRPCTLS == nil {
+			stacks.endpoints = append(stacks.endpoints, getEndpointUrl(serviceName, ms.Namespace))
+			continue
+		}
+		// With mutual TLS, the querier verifies the name of each sidecar.
+		stacks.tlsEndpoints = append(stacks.tlsEndpoints, endpointGroup{
+			addresses: []string{getEndpointUrl(serviceName, ms.Namespace)},
+			tls:       grpcTLSConfig(tQuerier, sidecarServerName(tQuerier, &ms)),
+		})
 	}
 
-	return sidecarUrls, nil
+	return stacks, nil
 }
 
 // Given a Service object, return a url to use as value for --store/--endpoint.
@@ -161,33 +369,108 @@ func getEndpointUrl(serviceName string, namespace string) string {
 	return fmt.Sprintf("dnssrv+_grpc._tcp.%s.%s.svc.cluster.local", serviceName, namespace)
 }
 
-// Find all ThanosQueriers, whose Selector fits the given MonitoringStack and
-// return a list of reconcile requests, one for each ThanosQuerier.
+// findQueriersForMonitoringStack returns the ThanosQueriers which query the
+// given MonitoringStack or which select it. The queriers querying the stack
+// are found with the stacks index so that they are also reconciled when the
+// stack stops matching their selector or is deleted. The selectors are only
+// evaluated for the queriers which may select the namespace of the stack,
+// found with the namespaces index.
 func (rm resourceManager) findQueriersForMonitoringStack(ctx context.Context, ms client.Object) []reconcile.Request {
 	logger := rm.logger.WithValues("Monitoring Stack", ms.GetNamespace()+"/"+ms.GetName())
-	logger.Info("watched MonitoringStack changed, checking for matching querier")
-	queriers := &msoapi.ThanosQuerierList{}
-	err := rm.List(ctx, queriers, &client.ListOptions{})
-	if err != nil {
-		logger.Error(err, "Failed to list Thanosqueriers")
-		return []reconcile.Request{}
+
+	keys := map[types.NamespacedName]struct{}{}
+	wired := &msoapi.ThanosQuerierList{}
+	if err := rm.List(ctx, wired, client.MatchingFields{stacksIndexKey: ms.GetNamespace() + "/" + ms.GetName()}); err != nil {
+		logger.Error(err, "Failed to list the Thanosqueriers querying the stack")
+		return nil
+	}
+	for _, item := range wired.Items {
+		keys[client.ObjectKeyFromObject(&item)] = struct{}{}
 	}
 
-	var requests []reconcile.Request
-	for _, item := range queriers.Items {
+	var candidates []msoapi.ThanosQuerier
+	for _, key := range []string{ms.GetNamespace(), anyNamespace} {
+		queriers := &msoapi.ThanosQuerierList{}
+		if err := rm.List(ctx, queriers, client.MatchingFields{namespacesIndexKey: key}); err != nil {
+			logger.Error(err, "Failed to list Thanosqueriers")
+			return nil
+		}
+		candidates = append(candidates, queriers.Items...)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := rm.Get(ctx, client.ObjectKey{Name: ms.GetNamespace()}, ns); err != nil {
+		ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ms.GetNamespace()}}
+	}
+	for _, item := range candidates {
 		sel, err := metav1.LabelSelectorAsSelector(&item.Spec.Selector)
-		if err != nil {
-			return []reconcile.Request{}
+		if err != nil || !sel.Matches(labels.Set(ms.GetLabels())) || !item.MatchesNamespaceObject(ns) {
+			continue
+		}
+		keys[client.ObjectKeyFromObject(&item)] = struct{}{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(keys))
+	for key := range keys {
+		requests = append(requests, reconcile.Request{NamespacedName: key})
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].String() < requests[j].String()
+	})
+	return requests
+}
+
+// findQueriersForNamespace returns the ThanosQueriers selecting namespaces by
+// labels: a label change of the namespace may add or remove MonitoringStacks.
+// The queriers selecting the namespaces by both names and labels are indexed
+// by their names. The queriers of the namespace requesting a client
+// certificate from the operator are also returned since the label allowing it
+// may have changed.
+func (rm resourceManager) findQueriersForNamespace(ctx context.Context, ns client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	local := &msoapi.ThanosQuerierList{}
+	if err := rm.List(ctx, local, client.InNamespace(ns.GetName())); err != nil {
+		rm.logger.Error(err, "Failed to list Thanosqueriers")
+		return nil
+	}
+	for _, item := range local.Items {
+		if tls := item.Spec.GRPCTLS; tls != nil && tls.SecretName == "" {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
 		}
-		if sel.Matches(labels.Set(ms.GetLabels())) {
-			logger.Info("Found querier, scheduling sync")
-			requests = append(requests, reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      item.GetName(),
-					Namespace: item.GetNamespace(),
-				},
-			})
+	}
+
+	for _, key := range []string{anyNamespace, ns.GetName()} {
+		queriers := &msoapi.ThanosQuerierList{}
+		if err := rm.List(ctx, queriers, client.MatchingFields{namespacesIndexKey: key}); err != nil {
+			rm.logger.Error(err, "Failed to list Thanosqueriers")
+			return nil
+		}
+
+		for _, item := range queriers.Items {
+			sel := item.Spec.NamespaceSelector
+			if sel.Any || (len(sel.MatchLabels) == 0 && len(sel.MatchExpressions) == 0) {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
 		}
 	}
 	return requests
 }
+
+// findQuerierForClusterRoleBinding returns the ThanosQuerier of a tenancy
+// ClusterRoleBinding. The binding is cluster-scoped and can't be owned by the
+// querier: the querier is identified by the service account bound.
+func (rm resourceManager) findQuerierForClusterRoleBinding(_ context.Context, o client.Object) []reconcile.Request {
+	crb := o.(*rbacv1.ClusterRoleBinding)
+	if crb.Labels[partOfLabel] != partOfValue || len(crb.Subjects) != 1 {
+		return nil
+	}
+
+	name, found := strings.CutPrefix(crb.Subjects[0].Name, "thanos-querier-")
+	if !found {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: crb.Subjects[0].Namespace, Name: name},
+	}}
+}
@@ -0,0 +1,150 @@
+package thanos_querier
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestFindQueriersForMonitoringStack(t *testing.T) {
+	// Selects the stacks labelled app=demo in its own namespace.
+	selecting := newQuerier()
+	// Queried the stack before its labels were removed.
+	wired := newQuerier()
+	wired.Name = "wired"
+	wired.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"app": "removed"}}
+	wired.Status.MonitoringStacks = []string{"ns/ms"}
+	// Selects the stack labels but not its namespace.
+	otherNamespace := newQuerier()
+	otherNamespace.Name = "other-namespace"
+	otherNamespace.Spec.NamespaceSelector = msoapi.NamespaceSelector{MatchNames: []string{"other"}}
+	// Doesn't select the stack labels.
+	unrelated := newQuerier()
+	unrelated.Name = "unrelated"
+	unrelated.Spec.Selector = metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}}
+	unrelated.Status.MonitoringStacks = []string{"ns/other"}
+
+	// Selects the stack namespace by labels.
+	byLabels := newQuerier()
+	byLabels.Name = "by-labels"
+	byLabels.Spec.NamespaceSelector = msoapi.NamespaceSelector{
+		LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+	}
+
+	rm := newTestManager(&fakeStoreLister{}, selecting, wired, otherNamespace, unrelated, byLabels,
+		newNamespace("ns", map[string]string{"monitoring": "true"}),
+	)
+
+	ms := newMonitoringStack("ms", "ns")
+	assert.DeepEqual(t, rm.findQueriersForMonitoringStack(context.Background(), ms), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "by-labels"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "tq"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "wired"}},
+	})
+
+	// Once its labels are removed, the stack is only removed from the
+	// queriers which queried it.
+	ms.Labels = nil
+	assert.DeepEqual(t, rm.findQueriersForMonitoringStack(context.Background(), ms), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "wired"}},
+	})
+}
+
+func TestReconcileDeletion(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+	rm := newTestManager(&fakeStoreLister{}, tq)
+	rm.namespace = "operator"
+
+	got := reconcileQuerier(t, rm)
+	assert.DeepEqual(t, got.Finalizers, []string{finalizerName})
+
+	crbKey := client.ObjectKey{Name: "ns-thanos-querier-tq-tenancy"}
+	assert.NilError(t, rm.Get(context.Background(), crbKey, &rbacv1.ClusterRoleBinding{}))
+
+	// Without cluster-scoped resources, the finalizer isn't needed.
+	got.Spec.Tenancy.Enabled = false
+	assert.NilError(t, rm.Update(context.Background(), got))
+	got = reconcileQuerier(t, rm)
+	assert.Equal(t, len(got.Finalizers), 0)
+	err := rm.Get(context.Background(), crbKey, &rbacv1.ClusterRoleBinding{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+
+	got.Spec.Tenancy.Enabled = true
+	assert.NilError(t, rm.Update(context.Background(), got))
+	got = reconcileQuerier(t, rm)
+	assert.DeepEqual(t, got.Finalizers, []string{finalizerName})
+
+	// The cluster-scoped ClusterRoleBinding isn't garbage collected and is
+	// deleted before the finalizer is removed.
+	assert.NilError(t, rm.Delete(context.Background(), got))
+	_, err = rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tq)})
+	assert.NilError(t, err)
+
+	err = rm.Get(context.Background(), crbKey, &rbacv1.ClusterRoleBinding{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	err = rm.Get(context.Background(), client.ObjectKeyFromObject(tq), &msoapi.ThanosQuerier{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+}
+
+func TestReconcileWithoutFinalizer(t *testing.T) {
+	rm := newTestManager(&fakeStoreLister{}, newQuerier())
+
+	got := reconcileQuerier(t, rm)
+	assert.Equal(t, len(got.Finalizers), 0)
+}
+
+func TestFindQuerierForClusterRoleBinding(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+	rm := newTestManager(&fakeStoreLister{})
+
+	crb := newTenancyClusterRoleBinding(tq, "thanos-querier-tq")
+	assert.DeepEqual(t, rm.findQuerierForClusterRoleBinding(context.Background(), crb), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "tq"}},
+	})
+
+	// The bindings which aren't managed by the operator are ignored.
+	crb.Labels = nil
+	assert.Equal(t, len(rm.findQuerierForClusterRoleBinding(context.Background(), crb)), 0)
+}
+
+func TestIndexQuerierNamespaces(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		selector msoapi.NamespaceSelector
+		keys     []string
+	}{
+		{name: "default", keys: []string{"ns"}},
+		{name: "any", selector: msoapi.NamespaceSelector{Any: true}, keys: []string{anyNamespace}},
+		{name: "names", selector: msoapi.NamespaceSelector{MatchNames: []string{"a", "b"}}, keys: []string{"a", "b"}},
+		{
+			name:     "labels",
+			selector: msoapi.NamespaceSelector{LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}}},
+			keys:     []string{anyNamespace},
+		},
+		{
+			name: "names and labels",
+			selector: msoapi.NamespaceSelector{
+				MatchNames:    []string{"a"},
+				LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+			},
+			keys: []string{"a"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tq := newQuerier()
+			tq.Spec.NamespaceSelector = tc.selector
+			assert.DeepEqual(t, indexQuerierNamespaces(tq), tc.keys)
+		})
+	}
+}
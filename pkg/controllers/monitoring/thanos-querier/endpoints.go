@@ -0,0 +1,248 @@
+package thanos_querier
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+)
+
+const (
+	// endpointTLSMountPath is the directory where the secrets referenced by
+	// the TLS configuration of the endpoints are mounted.
+	endpointTLSMountPath = "/etc/thanos/endpoints"
+
+	defaultServicePort = "grpc"
+
+	// The Thanos sidecars of the OpenShift platform monitoring stack serve
+	// gRPC over mutual TLS with the certificates generated by the cluster
+	// monitoring operator.
+	platformServerName      = "prometheus-grpc"
+	platformSidecarEndpoint = "dnssrv+_grpc._tcp.prometheus-operated.openshift-monitoring.svc.cluster.local"
+)
+
+// endpointGroup is a set of endpoints sharing the same TLS configuration.
+type endpointGroup struct {
+	addresses []string
+	tls       *msoapi.ThanosEndpointTLSConfig
+}
+
+// findAdditionalEndpoints resolves the additional endpoints of the querier.
+// The endpoints without TLS configuration are added to the endpoints of the
+// stacks, the others are grouped by TLS configuration.
+func (rm resourceManager) findAdditionalEndpoints(ctx context.Context, querier *msoapi.ThanosQuerier, stacks *discoveredStacks) error {
+	for _, ep := range querier.Spec.AdditionalEndpoints {
+		addresses, err := rm.resolveEndpoint(ctx, querier, ep)
+		if err != nil {
+			return err
+		}
+
+		if ep.TLS == nil {
+			stacks.endpoints = append(stacks.endpoints, addresses...)
+			continue
+		}
+		if len(addresses) > 0 {
+			stacks.tlsEndpoints = append(stacks.tlsEndpoints, endpointGroup{addresses: addresses, tls: ep.TLS})
+		}
+	}
+
+	// The client certificate is provided by the user: copying the one of the
+	// platform thanos-querier would grant access to the platform metrics to
+	// anyone allowed to create a ThanosQuerier.
+	if querier.Spec.IncludeOpenShiftPlatform && querier.Spec.OpenShiftPlatformTLSSecretName != "" {
+		secret := querier.Spec.OpenShiftPlatformTLSSecretName
+		stacks.tlsEndpoints = append(stacks.tlsEndpoints, endpointGroup{
+			addresses: []string{platformSidecarEndpoint},
+			tls: &msoapi.ThanosEndpointTLSConfig{
+				CA:         &msoapi.SecretKeySelector{Name: secret, Key: certificates.CAKey},
+				Cert:       &msoapi.SecretKeySelector{Name: secret, Key: certificates.CertificateKey},
+				Key:        &msoapi.SecretKeySelector{Name: secret, Key: certificates.PrivateKeyKey},
+				ServerName: platformServerName,
+			},
+		})
+	}
+
+	return nil
+}
+
+// resolveEndpoint returns the addresses of the endpoint, in the format of the
+// --endpoint flag. The Services exposing an endpoint are resolved at each
+// reconciliation, including the periodic refresh of the status.
+func (rm resourceManager) resolveEndpoint(ctx context.Context, querier *msoapi.ThanosQuerier, ep msoapi.ThanosEndpoint) ([]string, error) {
+	switch {
+	case ep.Address != "":
+		return []string{ep.Address}, nil
+	case ep.DNSSRV != "":
+		return []string{"dnssrv+" + ep.DNSSRV}, nil
+	case ep.ServiceSelector != nil:
+		return rm.findServiceEndpoints(ctx, querier, ep.ServiceSelector)
+	}
+	return nil, fmt.Errorf("invalid endpoint: exactly one of address, dnsSRV and serviceSelector must be set")
+}
+
+func (rm resourceManager) findServiceEndpoints(ctx context.Context, querier *msoapi.ThanosQuerier, sel *msoapi.ThanosServiceSelector) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&sel.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint service selector: %w", err)
+	}
+
+	namespace := sel.Namespace
+	if namespace == "" {
+		namespace = querier.Namespace
+	}
+	port := sel.Port
+	if port == "" {
+		port = defaultServicePort
+	}
+
+	services := &corev1.ServiceList{}
+	if err := rm.List(ctx, services, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	sort.Slice(services.Items, func(i, j int) bool {
+		return services.Items[i].Name < services.Items[j].Name
+	})
+
+	var addresses []string
+	for _, svc := range services.Items {
+		for _, p := range svc.Spec.Ports {
+			if p.Name == port {
+				addresses = append(addresses, fmt.Sprintf("dnssrv+_%s._tcp.%s.%s.svc.cluster.local", port, svc.Name, svc.Namespace))
+				break
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// endpointConfig is the content of the --endpoint.sd-config flag of the
+// Thanos querier, declaring the endpoints with their own TLS configuration.
+// The default client configuration applies to the endpoints passed with the
+// --endpoint flag.
+type endpointConfig struct {
+	DefaultClientConfig *endpointClientConfig `yaml:"default_client_config,omitempty"`
+	Endpoints           []endpointSettings    `yaml:"endpoints"`
+}
+
+type endpointSettings struct {
+	Address string `yaml:"address"`
+	// The querier ignores the client configuration of the endpoints
+	// resolved through DNS unless they are declared as a group.
+	Group        bool                 `yaml:"group,omitempty"`
+	ClientConfig endpointClientConfig `yaml:"client_config"`
+}
+
+type endpointClientConfig struct {
+	TLSConfig  endpointTLSConfig `yaml:"tls_config"`
+	ServerName string            `yaml:"server_name,omitempty"`
+}
+
+type endpointTLSConfig struct {
+	// TLS is enabled explicitly, otherwise the querier falls back to the
+	// default client configuration for the unset fields.
+	Enabled  bool   `yaml:"enabled"`
+	CAFile   string `yaml:"ca_file,omitempty"`
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+}
+
+// newEndpointConfig returns the content of the --endpoint.sd-config flag,
+// empty when no endpoint has its own TLS configuration and mutual TLS isn't
+// enabled.
+//
+// The addresses resolved through DNS (e.g. `dnssrv+...`) are declared as
+// endpoint groups: each query is sent to one of the resolved addresses
+// instead of all of them, which is only suitable for replicas serving the
+// same data.
+func newEndpointConfig(querier *msoapi.ThanosQuerier, groups []endpointGroup) (string, error) {
+	var cfg endpointConfig
+	if tls := querier.Spec.GRPCTLS; tls != nil {
+		cfg.DefaultClientConfig = ptr.To(newEndpointClientConfig(grpcTLSConfig(querier, tls.ServerName)))
+	}
+	if len(groups) == 0 && cfg.DefaultClientConfig == nil {
+		return "", nil
+	}
+
+	for _, g := range groups {
+		clientConfig := newEndpointClientConfig(g.tls)
+		for _, address := range g.addresses {
+			cfg.Endpoints = append(cfg.Endpoints, endpointSettings{
+				Address:      address,
+				Group:        isDynamicAddress(address),
+				ClientConfig: clientConfig,
+			})
+		}
+	}
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func newEndpointClientConfig(tls *msoapi.ThanosEndpointTLSConfig) endpointClientConfig {
+	return endpointClientConfig{
+		TLSConfig: endpointTLSConfig{
+			Enabled:  true,
+			CAFile:   endpointTLSFile(tls.CA),
+			CertFile: endpointTLSFile(tls.Cert),
+			KeyFile:  endpointTLSFile(tls.Key),
+		},
+		ServerName: tls.ServerName,
+	}
+}
+
+// isDynamicAddress returns whether the address is resolved through DNS by the
+// querier.
+func isDynamicAddress(address string) bool {
+	for _, prefix := range []string{"dns+", "dnssrv+", "dnssrvnoa+"} {
+		if strings.HasPrefix(address, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func endpointTLSFile(sel *msoapi.SecretKeySelector) string {
+	if sel == nil {
+		return ""
+	}
+	return filepath.Join(endpointTLSMountPath, sel.Name, sel.Key)
+}
+
+// endpointTLSSecrets returns the sorted names of the secrets referenced by the
+// TLS configuration of the endpoints, including the client certificate of the
+// querier.
+func endpointTLSSecrets(querier *msoapi.ThanosQuerier, groups []endpointGroup) []string {
+	configs := make([]*msoapi.ThanosEndpointTLSConfig, 0, len(groups)+1)
+	if querier.Spec.GRPCTLS != nil {
+		configs = append(configs, grpcTLSConfig(querier, ""))
+	}
+	for _, g := range groups {
+		configs = append(configs, g.tls)
+	}
+
+	seen := map[string]bool{}
+	var secrets []string
+	for _, tls := range configs {
+		for _, sel := range []*msoapi.SecretKeySelector{tls.CA, tls.Cert, tls.Key} {
+			if sel != nil && !seen[sel.Name] {
+				seen[sel.Name] = true
+				secrets = append(secrets, sel.Name)
+			}
+		}
+	}
+	sort.Strings(secrets)
+	return secrets
+}
@@ -0,0 +1,141 @@
+package thanos_querier
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newGRPCService(name string, namespace string, port string, lbls map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: lbls},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: port, Port: 10901}},
+		},
+	}
+}
+
+func TestFindAdditionalEndpoints(t *testing.T) {
+	legacy := map[string]string{"app": "legacy"}
+	rm := newTestManager(&fakeStoreLister{},
+		newGRPCService("b", "ns", "grpc", legacy),
+		newGRPCService("a", "ns", "grpc", legacy),
+		newGRPCService("no-grpc-port", "ns", "web", legacy),
+		newGRPCService("other-namespace", "other", "grpc", legacy),
+		newGRPCService("custom-port", "other", "store", legacy),
+	)
+
+	tls := &msoapi.ThanosEndpointTLSConfig{
+		CA:         &msoapi.SecretKeySelector{Name: "store-tls", Key: "ca.crt"},
+		ServerName: "store.example.com",
+	}
+	tq := newQuerier()
+	tq.Spec.AdditionalEndpoints = []msoapi.ThanosEndpoint{
+		{Address: "store.example.com:10901"},
+		{DNSSRV: "_grpc._tcp.prometheus-operated.monitoring.svc.cluster.local"},
+		{ServiceSelector: &msoapi.ThanosServiceSelector{Selector: metav1.LabelSelector{MatchLabels: legacy}}},
+		{ServiceSelector: &msoapi.ThanosServiceSelector{Selector: metav1.LabelSelector{MatchLabels: legacy}, Namespace: "other", Port: "store"}},
+		{Address: "secure.example.com:10901", TLS: tls},
+		// Endpoints without any address aren't configured.
+		{ServiceSelector: &msoapi.ThanosServiceSelector{Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "none"}}}, TLS: tls},
+	}
+
+	stacks := &discoveredStacks{endpoints: []string{"sidecar"}}
+	assert.NilError(t, rm.findAdditionalEndpoints(context.Background(), tq, stacks))
+	assert.DeepEqual(t, stacks.endpoints, []string{
+		"sidecar",
+		"store.example.com:10901",
+		"dnssrv+_grpc._tcp.prometheus-operated.monitoring.svc.cluster.local",
+		"dnssrv+_grpc._tcp.a.ns.svc.cluster.local",
+		"dnssrv+_grpc._tcp.b.ns.svc.cluster.local",
+		"dnssrv+_store._tcp.custom-port.other.svc.cluster.local",
+	})
+	assert.Equal(t, len(stacks.tlsEndpoints), 1)
+	assert.DeepEqual(t, stacks.tlsEndpoints[0].addresses, []string{"secure.example.com:10901"})
+	assert.Equal(t, stacks.tlsEndpoints[0].tls, tls)
+}
+
+func TestIncludeOpenShiftPlatform(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.IncludeOpenShiftPlatform = true
+	tq.Spec.OpenShiftPlatformTLSSecretName = "platform-grpc-tls"
+
+	// The client certificate of the platform thanos-querier isn't copied:
+	// the querier mounts the secret provided by the user.
+	rm := newTestManager(&fakeStoreLister{}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "grpc-tls", Namespace: "openshift-monitoring"},
+	})
+	stacks := &discoveredStacks{}
+	assert.NilError(t, rm.findAdditionalEndpoints(context.Background(), tq, stacks))
+	cfg, err := newEndpointConfig(tq, stacks.tlsEndpoints)
+	assert.NilError(t, err)
+	// The platform sidecars are resolved through DNS: they are declared as a
+	// group for their TLS configuration to apply.
+	assert.Equal(t, cfg, `endpoints:
+    - address: dnssrv+_grpc._tcp.prometheus-operated.openshift-monitoring.svc.cluster.local
+      group: true
+      client_config:
+        tls_config:
+            enabled: true
+            ca_file: /etc/thanos/endpoints/platform-grpc-tls/ca.crt
+            cert_file: /etc/thanos/endpoints/platform-grpc-tls/tls.crt
+            key_file: /etc/thanos/endpoints/platform-grpc-tls/tls.key
+        server_name: prometheus-grpc
+`)
+
+	_, err = thanosComponentReconcilers(tq, stacks, nil, nil, ThanosConfiguration{})
+	assert.NilError(t, err)
+
+	tq.Spec.OpenShiftPlatformTLSSecretName = ""
+	_, err = thanosComponentReconcilers(tq, stacks, nil, nil, ThanosConfiguration{})
+	assert.ErrorContains(t, err, "openShiftPlatformTLSSecretName is required to query the OpenShift platform")
+}
+
+func TestNewThanosQuerierDeploymentEndpointTLS(t *testing.T) {
+	tq := newQuerier()
+	groups := []endpointGroup{
+		{
+			addresses: []string{"a:10901"},
+			tls: &msoapi.ThanosEndpointTLSConfig{
+				CA:   &msoapi.SecretKeySelector{Name: "store-b", Key: "ca.crt"},
+				Cert: &msoapi.SecretKeySelector{Name: "store-a", Key: "tls.crt"},
+				Key:  &msoapi.SecretKeySelector{Name: "store-a", Key: "tls.key"},
+			},
+		},
+		{
+			addresses: []string{"b:10901"},
+			tls:       &msoapi.ThanosEndpointTLSConfig{CA: &msoapi.SecretKeySelector{Name: "store-b", Key: "ca.crt"}},
+		},
+	}
+	cfg, err := newEndpointConfig(tq, groups)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg, `endpoints:
+    - address: a:10901
+      client_config:
+        tls_config:
+            enabled: true
+            ca_file: /etc/thanos/endpoints/store-b/ca.crt
+            cert_file: /etc/thanos/endpoints/store-a/tls.crt
+            key_file: /etc/thanos/endpoints/store-a/tls.key
+    - address: b:10901
+      client_config:
+        tls_config:
+            enabled: true
+            ca_file: /etc/thanos/endpoints/store-b/ca.crt
+`)
+
+	d := newThanosQuerierDeployment("thanos-querier-tq", tq, nil, groups, cfg, ThanosConfiguration{})
+	pod := d.Spec.Template.Spec
+	args := pod.Containers[0].Args
+	assert.Equal(t, args[len(args)-1], "--endpoint.sd-config="+cfg)
+
+	assert.Equal(t, len(pod.Volumes), 2)
+	assert.Equal(t, pod.Volumes[0].Secret.SecretName, "store-a")
+	assert.Equal(t, pod.Volumes[1].Secret.SecretName, "store-b")
+	assert.Equal(t, pod.Containers[0].VolumeMounts[1].MountPath, "/etc/thanos/endpoints/store-b")
+}
@@ -0,0 +1,196 @@
+package thanos_querier
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	defaultSplitInterval = "24h"
+	defaultMaxRetries    = 5
+)
+
+func queryFrontendEnabled(thanos *msoapi.ThanosQuerier) bool {
+	return thanos.Spec.QueryFrontend != nil && thanos.Spec.QueryFrontend.Enabled
+}
+
+// frontendReplicas returns the number of replicas of the query frontend.
+func frontendReplicas(thanos *msoapi.ThanosQuerier) int32 {
+	if thanos.Spec.QueryFrontend == nil || thanos.Spec.QueryFrontend.Replicas == nil {
+		return 1
+	}
+	return *thanos.Spec.QueryFrontend.Replicas
+}
+
+// responseCacheConfig is the configuration of the results cache of the query
+// frontend.
+type responseCacheConfig struct {
+	Type   string         `yaml:"type"`
+	Config map[string]any `yaml:"config"`
+}
+
+// newResponseCacheConfig returns the configuration of the results cache of
+// the query frontend, in the format of the --query-range.response-cache-config
+// flag.
+func newResponseCacheConfig(cache *msoapi.ThanosQueryFrontendCache) (string, error) {
+	if cache == nil {
+		cache = &msoapi.ThanosQueryFrontendCache{}
+	}
+
+	cfg := responseCacheConfig{Config: map[string]any{}}
+	switch cache.Type {
+	case msoapi.InMemoryCache, "":
+		cfg.Type = "IN-MEMORY"
+		if cache.MaxSize != "" {
+			cfg.Config["max_size"] = cache.MaxSize
+		}
+		if cache.Validity != nil {
+			cfg.Config["validity"] = string(*cache.Validity)
+		}
+	case msoapi.MemcachedCache:
+		if len(cache.Addresses) == 0 {
+			return "", fmt.Errorf("addresses are required for the Memcached cache")
+		}
+		cfg.Type = "MEMCACHED"
+		cfg.Config["addresses"] = cache.Addresses
+		if cache.Validity != nil {
+			cfg.Config["expiration"] = string(*cache.Validity)
+		}
+	case msoapi.RedisCache:
+		if len(cache.Addresses) != 1 {
+			return "", fmt.Errorf("the Redis cache requires exactly one address")
+		}
+		cfg.Type = "REDIS"
+		cfg.Config["addr"] = cache.Addresses[0]
+		if cache.Validity != nil {
+			cfg.Config["expiration"] = string(*cache.Validity)
+		}
+	default:
+		return "", fmt.Errorf("unsupported cache type %q", cache.Type)
+	}
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func newQueryFrontendDeployment(name string, downstreamName string, spec *msoapi.ThanosQuerier, cacheConfig string, thanosCfg ThanosConfiguration) *appsv1.Deployment {
+	// The deployment is also built to be deleted when the query frontend is
+	// disabled.
+	frontend := spec.Spec.QueryFrontend
+	if frontend == nil {
+		frontend = &msoapi.ThanosQueryFrontendConfig{}
+	}
+
+	splitInterval := defaultSplitInterval
+	if frontend.SplitInterval != nil {
+		splitInterval = string(*frontend.SplitInterval)
+	}
+
+	maxRetries := int32(defaultMaxRetries)
+	if frontend.MaxRetries != nil {
+		maxRetries = *frontend.MaxRetries
+	}
+
+	args := []string{
+		"query-frontend",
+		"--log.format=logfmt",
+		fmt.Sprintf("--http-address=0.0.0.0:%d", httpPort),
+		fmt.Sprintf("--query-frontend.downstream-url=http://%s.%s.svc:%d", downstreamName, spec.Namespace, httpPort),
+		fmt.Sprintf("--query-range.split-interval=%s", splitInterval),
+		fmt.Sprintf("--query-range.max-retries-per-request=%d", maxRetries),
+		fmt.Sprintf("--query-range.response-cache-config=%s", cacheConfig),
+	}
+
+	if spec.Spec.LogLevel != "" {
+		args = append(args, fmt.Sprintf("--log.level=%s", spec.Spec.LogLevel))
+	}
+
+	nodeSelector := spec.Spec.NodeSelector
+	if len(nodeSelector) == 0 {
+		nodeSelector = map[string]string{
+			"kubernetes.io/os": "linux",
+		}
+	}
+
+	var affinity *corev1.Affinity
+	if frontendReplicas(spec) > 1 {
+		affinity = newAntiAffinity(name)
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: spec.Namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(frontendReplicas(spec)),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/instance": name,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: spec.Namespace,
+					Labels:    componentLabels(name),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "thanos-query-frontend",
+							Args:  args,
+							Image: thanosCfg.Image,
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: httpPort,
+									Name:          "metrics",
+								},
+							},
+							Resources:                frontend.Resources,
+							TerminationMessagePolicy: "FallbackToLogsOnError",
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: ptr.To(false),
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{
+										"ALL",
+									},
+								},
+								RunAsNonRoot: ptr.To(true),
+								SeccompProfile: &corev1.SeccompProfile{
+									Type: corev1.SeccompProfileTypeRuntimeDefault,
+								},
+							},
+						},
+					},
+					NodeSelector:      nodeSelector,
+					Tolerations:       spec.Spec.Tolerations,
+					Affinity:          affinity,
+					PriorityClassName: spec.Spec.PriorityClassName,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: ptr.To(true),
+						SeccompProfile: &corev1.SeccompProfile{
+							Type: corev1.SeccompProfileTypeRuntimeDefault,
+						},
+					},
+				},
+			},
+			ProgressDeadlineSeconds: ptr.To(int32(300)),
+		},
+	}
+}
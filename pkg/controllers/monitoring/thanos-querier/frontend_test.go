@@ -0,0 +1,137 @@
+package thanos_querier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func TestNewResponseCacheConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cache *msoapi.ThanosQueryFrontendCache
+		exp   string
+		err   string
+	}{
+		{
+			name: "default",
+			exp:  "type: IN-MEMORY\nconfig: {}\n",
+		},
+		{
+			name:  "in-memory",
+			cache: &msoapi.ThanosQueryFrontendCache{Type: msoapi.InMemoryCache, MaxSize: "256MB", Validity: ptr.To(monv1.Duration("1h"))},
+			exp:   "type: IN-MEMORY\nconfig:\n    max_size: 256MB\n    validity: 1h\n",
+		},
+		{
+			name:  "memcached",
+			cache: &msoapi.ThanosQueryFrontendCache{Type: msoapi.MemcachedCache, Addresses: []string{"memcached-0:11211", "memcached-1:11211"}},
+			exp:   "type: MEMCACHED\nconfig:\n    addresses:\n        - memcached-0:11211\n        - memcached-1:11211\n",
+		},
+		{
+			name:  "redis",
+			cache: &msoapi.ThanosQueryFrontendCache{Type: msoapi.RedisCache, Addresses: []string{"redis:6379"}, Validity: ptr.To(monv1.Duration("24h"))},
+			exp:   "type: REDIS\nconfig:\n    addr: redis:6379\n    expiration: 24h\n",
+		},
+		{
+			name:  "memcached without addresses",
+			cache: &msoapi.ThanosQueryFrontendCache{Type: msoapi.MemcachedCache},
+			err:   "addresses are required for the Memcached cache",
+		},
+		{
+			name:  "redis with several addresses",
+			cache: &msoapi.ThanosQueryFrontendCache{Type: msoapi.RedisCache, Addresses: []string{"redis-0:6379", "redis-1:6379"}},
+			err:   "the Redis cache requires exactly one address",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := newResponseCacheConfig(tc.cache)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, cfg, tc.exp)
+		})
+	}
+}
+
+func TestNewQueryFrontendDeployment(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.QueryFrontend = &msoapi.ThanosQueryFrontendConfig{
+		Enabled:       true,
+		Replicas:      ptr.To(int32(2)),
+		SplitInterval: ptr.To(monv1.Duration("12h")),
+	}
+
+	d := newQueryFrontendDeployment("thanos-query-frontend-tq", "thanos-querier-tq-downstream", tq, "type: IN-MEMORY", ThanosConfiguration{Image: "thanos"})
+	assert.Equal(t, *d.Spec.Replicas, int32(2))
+	assert.Assert(t, d.Spec.Template.Spec.Affinity != nil)
+	assert.DeepEqual(t, d.Spec.Template.Spec.Containers[0].Args, []string{
+		"query-frontend",
+		"--log.format=logfmt",
+		"--http-address=0.0.0.0:10902",
+		"--query-frontend.downstream-url=http://thanos-querier-tq-downstream.ns.svc:10902",
+		"--query-range.split-interval=12h",
+		"--query-range.max-retries-per-request=5",
+		"--query-range.response-cache-config=type: IN-MEMORY",
+	})
+}
+
+func TestReconcileQueryFrontend(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.QueryFrontend = &msoapi.ThanosQueryFrontendConfig{Enabled: true}
+	rm := newTestManager(&fakeStoreLister{}, tq)
+
+	reconcileQuerier(t, rm)
+
+	// The Service targets the query frontend which reaches the querier
+	// through the downstream Service.
+	svc := &corev1.Service{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, svc))
+	assert.DeepEqual(t, svc.Spec.Selector, map[string]string{"app.kubernetes.io/instance": "thanos-query-frontend-tq"})
+
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-downstream"}, svc))
+	assert.DeepEqual(t, svc.Spec.Selector, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq"})
+
+	// Both the query frontend and the querier are scraped.
+	sm := &monv1.ServiceMonitor{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, sm))
+	assert.DeepEqual(t, sm.Spec.Selector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq"})
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-downstream"}, sm))
+	assert.DeepEqual(t, sm.Spec.Selector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq-downstream"})
+
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-query-frontend-tq"}, &appsv1.Deployment{}))
+
+	// An invalid cache is reported in the status.
+	got := &msoapi.ThanosQuerier{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(tq), got))
+	got.Spec.QueryFrontend.Cache = &msoapi.ThanosQueryFrontendCache{Type: msoapi.RedisCache}
+	assert.NilError(t, rm.Update(context.Background(), got))
+
+	got = reconcileQuerier(t, rm)
+	reconciled := getCondition(t, got, msoapi.ReconciledCondition)
+	assert.Equal(t, reconciled.Status, msoapi.ConditionFalse)
+	assert.Assert(t, strings.HasPrefix(reconciled.Message, "invalid query frontend cache"), reconciled.Message)
+
+	// Disabling the query frontend deletes its resources.
+	got.Spec.QueryFrontend = nil
+	assert.NilError(t, rm.Update(context.Background(), got))
+	reconcileQuerier(t, rm)
+
+	err := rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-query-frontend-tq"}, &appsv1.Deployment{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	err = rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-downstream"}, &monv1.ServiceMonitor{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, svc))
+	assert.DeepEqual(t, svc.Spec.Selector, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq"})
+}
@@ -0,0 +1,124 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+// errGRPCClientNotAllowed is returned when the operator is requested to issue
+// a client certificate in a namespace which isn't allowed to get one.
+var errGRPCClientNotAllowed = fmt.Errorf("the operator only issues gRPC client certificates in the namespaces labelled with %s=true, set grpcTLS.secretName instead", certificates.ClientNamespaceLabel)
+
+func managedGRPCTLSSecretName(querier *msoapi.ThanosQuerier) string {
+	return querier.Name + "-grpc-tls"
+}
+
+// grpcTLSSecretName returns the name of the secret holding the client
+// certificate of the Thanos querier.
+func grpcTLSSecretName(querier *msoapi.ThanosQuerier) string {
+	if tls := querier.Spec.GRPCTLS; tls != nil && tls.SecretName != "" {
+		return tls.SecretName
+	}
+	return managedGRPCTLSSecretName(querier)
+}
+
+// grpcTLSSecret returns the secret holding the client certificate of the
+// Thanos querier issued by the operator. It returns nil when mutual TLS is
+// disabled or when the secret is provided by the user.
+//
+// The client certificates are trusted by the Thanos sidecars of all the
+// MonitoringStacks: they are only issued in the namespaces labelled by a
+// cluster administrator with certificates.ClientNamespaceLabel. Otherwise the
+// secret issued before is deleted.
+func (rm resourceManager) grpcTLSSecret(ctx context.Context, querier *msoapi.ThanosQuerier) (*corev1.Secret, error) {
+	tls := querier.Spec.GRPCTLS
+	if tls == nil || tls.SecretName != "" {
+		return nil, nil
+	}
+
+	name := managedGRPCTLSSecretName(querier)
+	ns := &corev1.Namespace{}
+	if err := rm.Get(ctx, client.ObjectKey{Name: querier.Namespace}, ns); err != nil {
+		return nil, err
+	}
+	if ns.Labels[certificates.ClientNamespaceLabel] != "true" {
+		if err := rm.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: querier.Namespace}}); client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		return nil, errGRPCClientNotAllowed
+	}
+
+	ca, err := certificates.GetCA(ctx, rm, rm.apiReader, rm.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the certificate authority: %w", err)
+	}
+	clientCA, err := certificates.GetClientCA(ctx, rm, rm.apiReader, rm.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the client certificate authority: %w", err)
+	}
+
+	secret, err := clientCA.NewSecret(ctx, rm, name, querier.Namespace, certificates.Request{
+		CommonName: fmt.Sprintf("thanos-querier-%s.%s", querier.Name, querier.Namespace),
+		Usage:      x509.ExtKeyUsageClientAuth,
+		PeerCA:     ca,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue the client certificate of the Thanos querier: %w", err)
+	}
+
+	secret.Labels = componentLabels("thanos-querier-" + querier.Name)
+	return secret, nil
+}
+
+// grpcTLSReconcilers apply the secret issued by the operator or delete it
+// when it isn't needed anymore. A secret provided by the user is left
+// untouched, even if it has the name of the generated secret.
+func grpcTLSReconcilers(querier *msoapi.ThanosQuerier, secret *corev1.Secret) []reconciler.Reconciler {
+	if secret != nil {
+		return []reconciler.Reconciler{reconciler.NewUpdater(secret, querier)}
+	}
+
+	if tls := querier.Spec.GRPCTLS; tls != nil && tls.SecretName == managedGRPCTLSSecretName(querier) {
+		return nil
+	}
+
+	return []reconciler.Reconciler{
+		reconciler.NewDeleter(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      managedGRPCTLSSecretName(querier),
+				Namespace: querier.Namespace,
+			},
+		}),
+	}
+}
+
+// grpcTLSConfig returns the TLS configuration of the gRPC connections using
+// the client certificate of the querier, verifying the given server name.
+func grpcTLSConfig(querier *msoapi.ThanosQuerier, serverName string) *msoapi.ThanosEndpointTLSConfig {
+	secret := grpcTLSSecretName(querier)
+	return &msoapi.ThanosEndpointTLSConfig{
+		CA:         &msoapi.SecretKeySelector{Name: secret, Key: certificates.CAKey},
+		Cert:       &msoapi.SecretKeySelector{Name: secret, Key: certificates.CertificateKey},
+		Key:        &msoapi.SecretKeySelector{Name: secret, Key: certificates.PrivateKeyKey},
+		ServerName: serverName,
+	}
+}
+
+// sidecarServerName returns the name verified against the certificate of the
+// Thanos sidecar of a MonitoringStack: the name of its Service unless the
+// querier overrides it.
+func sidecarServerName(querier *msoapi.ThanosQuerier, ms *msoapi.MonitoringStack) string {
+	if querier.Spec.GRPCTLS.ServerName != "" {
+		return querier.Spec.GRPCTLS.ServerName
+	}
+	return fmt.Sprintf("%s-thanos-sidecar.%s.svc", ms.Name, ms.Namespace)
+}
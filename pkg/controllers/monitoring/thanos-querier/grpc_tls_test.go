@@ -0,0 +1,141 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+)
+
+func TestGRPCTLSSecret(t *testing.T) {
+	rm := newTestManager(&fakeStoreLister{}, newNamespace("ns", map[string]string{certificates.ClientNamespaceLabel: "true"}))
+	rm.namespace = "operator"
+
+	tq := newQuerier()
+	secret, err := rm.grpcTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+
+	tq.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{}
+	secret, err = rm.grpcTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Equal(t, secret.Name, "tq-grpc-tls")
+	assert.Equal(t, secret.Namespace, "ns")
+
+	block, _ := pem.Decode(secret.Data[certificates.CertificateKey])
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NilError(t, err)
+	assert.Equal(t, cert.Subject.CommonName, "thanos-querier-tq.ns")
+	assert.DeepEqual(t, cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+
+	// The client certificate is issued by the client certificate authority
+	// and the querier only trusts the serving one.
+	clientCA := &corev1.Secret{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "operator", Name: "observability-operator-grpc-client-ca"}, clientCA))
+	assert.Equal(t, cert.Issuer.CommonName, "observability-operator-grpc-client-ca")
+	ca := &corev1.Secret{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "operator", Name: "observability-operator-grpc-ca"}, ca))
+	assert.DeepEqual(t, secret.Data[certificates.CAKey], ca.Data[certificates.CertificateKey])
+
+	tq.Spec.GRPCTLS.SecretName = "custom"
+	secret, err = rm.grpcTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+}
+
+func TestGRPCTLSSecretNotAllowed(t *testing.T) {
+	issued := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tq-grpc-tls", Namespace: "ns"}}
+	rm := newTestManager(&fakeStoreLister{}, newNamespace("ns", nil), issued)
+	rm.namespace = "operator"
+
+	// The client certificates are only issued in the namespaces allowed by
+	// a cluster administrator and the certificate issued before is revoked.
+	tq := newQuerier()
+	tq.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{}
+	_, err := rm.grpcTLSSecret(context.Background(), tq)
+	assert.ErrorIs(t, err, errGRPCClientNotAllowed)
+	err = rm.Get(context.Background(), client.ObjectKeyFromObject(issued), &corev1.Secret{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+
+	// The secrets provided by the user are allowed.
+	tq.Spec.GRPCTLS.SecretName = "custom"
+	secret, err := rm.grpcTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+}
+
+func TestNewThanosQuerierDeploymentGRPCTLS(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{}
+
+	// Each sidecar is verified with the name of its Service and the other
+	// endpoints use the default client configuration.
+	stacks := &discoveredStacks{endpoints: []string{"store:10901"}}
+	ms := newMonitoringStack("ms", "a")
+	stacks.tlsEndpoints = []endpointGroup{{
+		addresses: []string{getEndpointUrl("ms-thanos-sidecar", "a")},
+		tls:       grpcTLSConfig(tq, sidecarServerName(tq, ms)),
+	}}
+	cfg, err := newEndpointConfig(tq, stacks.tlsEndpoints)
+	assert.NilError(t, err)
+	assert.Equal(t, cfg, `default_client_config:
+    tls_config:
+        enabled: true
+        ca_file: /etc/thanos/endpoints/tq-grpc-tls/ca.crt
+        cert_file: /etc/thanos/endpoints/tq-grpc-tls/tls.crt
+        key_file: /etc/thanos/endpoints/tq-grpc-tls/tls.key
+endpoints:
+    - address: dnssrv+_grpc._tcp.ms-thanos-sidecar.a.svc.cluster.local
+      group: true
+      client_config:
+        tls_config:
+            enabled: true
+            ca_file: /etc/thanos/endpoints/tq-grpc-tls/ca.crt
+            cert_file: /etc/thanos/endpoints/tq-grpc-tls/tls.crt
+            key_file: /etc/thanos/endpoints/tq-grpc-tls/tls.key
+        server_name: ms-thanos-sidecar.a.svc
+`)
+
+	d := newThanosQuerierDeployment("thanos-querier-tq", tq, stacks.endpoints, stacks.tlsEndpoints, cfg, ThanosConfiguration{})
+	pod := d.Spec.Template.Spec
+	assert.DeepEqual(t, pod.Containers[0].Args[4:], []string{
+		"--endpoint=store:10901",
+		"--endpoint.sd-config=" + cfg,
+	})
+	assert.Equal(t, len(pod.Volumes), 1)
+	assert.Equal(t, pod.Volumes[0].Secret.SecretName, "tq-grpc-tls")
+	assert.Equal(t, pod.Containers[0].VolumeMounts[0].MountPath, "/etc/thanos/endpoints/tq-grpc-tls")
+
+	// The server name can be overridden for the sidecars serving
+	// certificates provided by the users.
+	tq.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{
+		GRPCTLSConfig: msoapi.GRPCTLSConfig{SecretName: "custom"},
+		ServerName:    "sidecar.example.com",
+	}
+	assert.Equal(t, sidecarServerName(tq, ms), "sidecar.example.com")
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", ThanosConfiguration{})
+	assert.Equal(t, d.Spec.Template.Spec.Volumes[0].Secret.SecretName, "custom")
+}
+
+func TestFindSidecarServicesGRPCTLS(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{}
+	rm := newTestManager(&fakeStoreLister{}, newNamespace("ns", nil), newMonitoringStack("ms", "ns"))
+
+	stacks, err := rm.findSidecarServices(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Equal(t, len(stacks.endpoints), 0)
+	assert.Equal(t, len(stacks.tlsEndpoints), 1)
+	assert.DeepEqual(t, stacks.tlsEndpoints[0].addresses, []string{"dnssrv+_grpc._tcp.ms-thanos-sidecar.ns.svc.cluster.local"})
+	assert.Equal(t, stacks.tlsEndpoints[0].tls.ServerName, "ms-thanos-sidecar.ns.svc")
+	assert.Equal(t, stacks.tlsEndpoints[0].tls.Cert.Name, "tq-grpc-tls")
+}
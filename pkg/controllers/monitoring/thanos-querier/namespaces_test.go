@@ -0,0 +1,144 @@
+package thanos_querier
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newNamespace(name string, lbls map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls}}
+}
+
+func TestMatchesNamespace(t *testing.T) {
+	monitored := map[string]string{"monitoring": "true"}
+	for _, tc := range []struct {
+		name     string
+		selector msoapi.NamespaceSelector
+		ns       *corev1.Namespace
+		matches  bool
+	}{
+		{name: "default selects the querier namespace", ns: newNamespace("ns", nil), matches: true},
+		{name: "default excludes other namespaces", ns: newNamespace("other", nil)},
+		{name: "any", selector: msoapi.NamespaceSelector{Any: true}, ns: newNamespace("other", nil), matches: true},
+		{name: "names", selector: msoapi.NamespaceSelector{MatchNames: []string{"a", "b"}}, ns: newNamespace("b", nil), matches: true},
+		{name: "names exclude the querier namespace", selector: msoapi.NamespaceSelector{MatchNames: []string{"a"}}, ns: newNamespace("ns", nil)},
+		{
+			name:     "labels",
+			selector: msoapi.NamespaceSelector{LabelSelector: metav1.LabelSelector{MatchLabels: monitored}},
+			ns:       newNamespace("other", monitored),
+			matches:  true,
+		},
+		{
+			name:     "labels exclude the querier namespace",
+			selector: msoapi.NamespaceSelector{LabelSelector: metav1.LabelSelector{MatchLabels: monitored}},
+			ns:       newNamespace("ns", nil),
+		},
+		{
+			name: "names and labels",
+			selector: msoapi.NamespaceSelector{
+				MatchNames:    []string{"a"},
+				LabelSelector: metav1.LabelSelector{MatchLabels: monitored},
+			},
+			ns: newNamespace("b", monitored),
+		},
+		{
+			name: "expressions",
+			selector: msoapi.NamespaceSelector{LabelSelector: metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: metav1.LabelSelectorOpExists}},
+			}},
+			ns:      newNamespace("other", map[string]string{"team": "a"}),
+			matches: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tq := newQuerier()
+			tq.Spec.NamespaceSelector = tc.selector
+			assert.Equal(t, tq.MatchesNamespaceObject(tc.ns), tc.matches)
+		})
+	}
+}
+
+func TestMatchesNamespaceName(t *testing.T) {
+	tq := newQuerier()
+	assert.Assert(t, tq.MatchesNamespace("ns"))
+	assert.Assert(t, !tq.MatchesNamespace("other"))
+
+	tq.Spec.NamespaceSelector = msoapi.NamespaceSelector{MatchNames: []string{"a"}}
+	assert.Assert(t, tq.MatchesNamespace("a"))
+
+	// The labels of the namespace are unknown.
+	tq.Spec.NamespaceSelector = msoapi.NamespaceSelector{
+		LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+	}
+	assert.Assert(t, !tq.MatchesNamespace("a"))
+}
+
+func TestFindSidecarServicesNamespaceLabels(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.NamespaceSelector = msoapi.NamespaceSelector{
+		LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+	}
+
+	rm := newTestManager(&fakeStoreLister{},
+		newNamespace("a", map[string]string{"monitoring": "true"}),
+		newNamespace("b", nil),
+		newMonitoringStack("ms", "a"),
+		newMonitoringStack("ms", "b"),
+	)
+
+	stacks, err := rm.findSidecarServices(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stacks.selected, []string{"a/ms"})
+	assert.DeepEqual(t, stacks.excluded, []string{"b/ms"})
+}
+
+func TestFindQueriersForNamespace(t *testing.T) {
+	byName := newQuerier()
+	byName.Name = "by-name"
+	byName.Spec.NamespaceSelector = msoapi.NamespaceSelector{MatchNames: []string{"a"}}
+	byLabels := newQuerier()
+	byLabels.Name = "by-labels"
+	byLabels.Spec.NamespaceSelector = msoapi.NamespaceSelector{
+		LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+	}
+
+	byNameAndLabels := newQuerier()
+	byNameAndLabels.Name = "by-name-and-labels"
+	byNameAndLabels.Spec.NamespaceSelector = msoapi.NamespaceSelector{
+		MatchNames:    []string{"a"},
+		LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"monitoring": "true"}},
+	}
+
+	rm := newTestManager(&fakeStoreLister{}, newQuerier(), byName, byLabels, byNameAndLabels)
+	assert.DeepEqual(t, rm.findQueriersForNamespace(context.Background(), newNamespace("a", nil)), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "by-labels"}},
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "by-name-and-labels"}},
+	})
+	assert.DeepEqual(t, rm.findQueriersForNamespace(context.Background(), newNamespace("b", nil)), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "by-labels"}},
+	})
+}
+
+func TestFindQueriersForNamespaceGRPCClient(t *testing.T) {
+	managed := newQuerier()
+	managed.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{}
+	custom := newQuerier()
+	custom.Name = "custom"
+	custom.Spec.GRPCTLS = &msoapi.ThanosQuerierGRPCTLSConfig{GRPCTLSConfig: msoapi.GRPCTLSConfig{SecretName: "custom"}}
+
+	// Only the queriers requesting a client certificate from the operator
+	// depend on the labels of their namespace.
+	rm := newTestManager(&fakeStoreLister{}, managed, custom)
+	assert.DeepEqual(t, rm.findQueriersForNamespace(context.Background(), newNamespace("ns", nil)), []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "ns", Name: "tq"}},
+	})
+	assert.Equal(t, len(rm.findQueriersForNamespace(context.Background(), newNamespace("other", nil))), 0)
+}
@@ -0,0 +1,123 @@
+package thanos_querier
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// defaultReplicaLabel is the replica label of the Prometheus instances
+// deployed by the MonitoringStacks.
+const defaultReplicaLabel = "prometheus_replica"
+
+func deduplicationEnabled(querier *msoapi.ThanosQuerier) bool {
+	return querier.Spec.Deduplication == nil || *querier.Spec.Deduplication
+}
+
+// replicaLabels returns the labels by which the series are deduplicated.
+func replicaLabels(querier *msoapi.ThanosQuerier) []string {
+	if !deduplicationEnabled(querier) {
+		return nil
+	}
+
+	label := querier.Spec.DefaultReplicaLabel
+	if label == "" {
+		label = defaultReplicaLabel
+	}
+	return append([]string{label}, querier.Spec.ReplicaLabels...)
+}
+
+// validateQueryConfig checks the settings of the query engine which can't be
+// validated by the CRD schema, so that an invalid configuration is reported
+// before the deployment is rolled out.
+func validateQueryConfig(querier *msoapi.ThanosQuerier) error {
+	if !deduplicationEnabled(querier) && (len(querier.Spec.ReplicaLabels) > 0 || querier.Spec.DefaultReplicaLabel != "") {
+		return fmt.Errorf("replica labels can't be configured when the deduplication is disabled")
+	}
+
+	query := querier.Spec.Query
+	if query == nil {
+		return nil
+	}
+
+	switch query.PartialResponseStrategy {
+	case "", msoapi.PartialResponseWarn, msoapi.PartialResponseAbort:
+	default:
+		return fmt.Errorf("unsupported partial response strategy %q", query.PartialResponseStrategy)
+	}
+
+	if query.MaxConcurrent != nil && *query.MaxConcurrent < 1 {
+		return fmt.Errorf("the maximum number of concurrent queries must be positive")
+	}
+
+	for _, field := range []struct {
+		name     string
+		duration *monv1.Duration
+	}{
+		{name: "query timeout", duration: query.Timeout},
+		{name: "lookback delta", duration: query.LookbackDelta},
+		{name: "default evaluation interval", duration: query.DefaultEvaluationInterval},
+	} {
+		if field.duration == nil {
+			continue
+		}
+		d, err := model.ParseDuration(string(*field.duration))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", field.name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("the %s must be positive", field.name)
+		}
+	}
+
+	if query.StoreResponseTimeout != nil {
+		storeTimeout, err := model.ParseDuration(string(*query.StoreResponseTimeout))
+		if err != nil {
+			return fmt.Errorf("invalid store response timeout: %w", err)
+		}
+		if query.Timeout != nil {
+			// The query timeout has been validated above.
+			timeout, _ := model.ParseDuration(string(*query.Timeout))
+			if storeTimeout >= timeout {
+				return fmt.Errorf("the store response timeout (%s) must be lower than the query timeout (%s)", *query.StoreResponseTimeout, *query.Timeout)
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryArgs returns the flags of the query engine set in the spec.
+func queryArgs(querier *msoapi.ThanosQuerier) []string {
+	query := querier.Spec.Query
+	if query == nil {
+		return nil
+	}
+
+	var args []string
+	switch query.PartialResponseStrategy {
+	case msoapi.PartialResponseWarn:
+		args = append(args, "--query.partial-response")
+	case msoapi.PartialResponseAbort:
+		args = append(args, "--no-query.partial-response")
+	}
+	if query.Timeout != nil {
+		args = append(args, fmt.Sprintf("--query.timeout=%s", *query.Timeout))
+	}
+	if query.MaxConcurrent != nil {
+		args = append(args, fmt.Sprintf("--query.max-concurrent=%d", *query.MaxConcurrent))
+	}
+	if query.LookbackDelta != nil {
+		args = append(args, fmt.Sprintf("--query.lookback-delta=%s", *query.LookbackDelta))
+	}
+	if query.DefaultEvaluationInterval != nil {
+		args = append(args, fmt.Sprintf("--query.default-evaluation-interval=%s", *query.DefaultEvaluationInterval))
+	}
+	if query.StoreResponseTimeout != nil {
+		args = append(args, fmt.Sprintf("--store.response-timeout=%s", *query.StoreResponseTimeout))
+	}
+	return args
+}
@@ -0,0 +1,159 @@
+package thanos_querier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func durationPtr(d string) *monv1.Duration {
+	return ptr.To(monv1.Duration(d))
+}
+
+func TestNewThanosQuerierDeploymentQueryConfig(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.ReplicaLabels = []string{"replica"}
+	tq.Spec.DefaultReplicaLabel = "cluster_replica"
+	tq.Spec.Query = &msoapi.ThanosQuerierQueryConfig{
+		PartialResponseStrategy:   msoapi.PartialResponseAbort,
+		Timeout:                   durationPtr("5m"),
+		MaxConcurrent:             ptr.To(int32(10)),
+		LookbackDelta:             durationPtr("10m"),
+		DefaultEvaluationInterval: durationPtr("30s"),
+		StoreResponseTimeout:      durationPtr("1m"),
+	}
+
+	d := newThanosQuerierDeployment("thanos-querier-tq", tq, []string{"sidecar:10901"}, nil, "", ThanosConfiguration{})
+	assert.DeepEqual(t, d.Spec.Template.Spec.Containers[0].Args, []string{
+		"query",
+		"--log.format=logfmt",
+		"--query.replica-label=cluster_replica",
+		"--query.replica-label=replica",
+		"--query.auto-downsampling",
+		"--no-query.partial-response",
+		"--query.timeout=5m",
+		"--query.max-concurrent=10",
+		"--query.lookback-delta=10m",
+		"--query.default-evaluation-interval=30s",
+		"--store.response-timeout=1m",
+		"--endpoint=sidecar:10901",
+	})
+
+	// Without deduplication, no replica label is configured.
+	tq = newQuerier()
+	tq.Spec.Deduplication = ptr.To(false)
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", ThanosConfiguration{})
+	assert.DeepEqual(t, d.Spec.Template.Spec.Containers[0].Args, []string{
+		"query",
+		"--log.format=logfmt",
+		"--query.auto-downsampling",
+	})
+}
+
+func TestValidateQueryConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate func(*msoapi.ThanosQuerierSpec)
+		err    string
+	}{
+		{
+			name:   "defaults",
+			mutate: func(*msoapi.ThanosQuerierSpec) {},
+		},
+		{
+			name: "valid",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Deduplication = ptr.To(true)
+				spec.ReplicaLabels = []string{"replica"}
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{
+					PartialResponseStrategy: msoapi.PartialResponseWarn,
+					Timeout:                 durationPtr("2m"),
+					StoreResponseTimeout:    durationPtr("1m"),
+				}
+			},
+		},
+		{
+			name: "replica labels without deduplication",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Deduplication = ptr.To(false)
+				spec.ReplicaLabels = []string{"replica"}
+			},
+			err: "replica labels can't be configured when the deduplication is disabled",
+		},
+		{
+			name: "unsupported partial response strategy",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{PartialResponseStrategy: "Ignore"}
+			},
+			err: `unsupported partial response strategy "Ignore"`,
+		},
+		{
+			name: "no concurrent queries",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{MaxConcurrent: ptr.To(int32(0))}
+			},
+			err: "the maximum number of concurrent queries must be positive",
+		},
+		{
+			name: "invalid duration",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{LookbackDelta: durationPtr("5 minutes")}
+			},
+			err: "invalid lookback delta",
+		},
+		{
+			name: "zero duration",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{Timeout: durationPtr("0s")}
+			},
+			err: "the query timeout must be positive",
+		},
+		{
+			name: "store response timeout exceeding the query timeout",
+			mutate: func(spec *msoapi.ThanosQuerierSpec) {
+				spec.Query = &msoapi.ThanosQuerierQueryConfig{
+					Timeout:              durationPtr("1m"),
+					StoreResponseTimeout: durationPtr("2m"),
+				}
+			},
+			err: "the store response timeout (2m) must be lower than the query timeout (1m)",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tq := newQuerier()
+			tc.mutate(&tq.Spec)
+
+			err := validateQueryConfig(tq)
+			if tc.err == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestReconcileInvalidQueryConfig(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.Query = &msoapi.ThanosQuerierQueryConfig{Timeout: durationPtr("0s")}
+	rm := newTestManager(&fakeStoreLister{}, tq)
+
+	// The invalid configuration is reported without rolling out the
+	// deployment.
+	got := reconcileQuerier(t, rm)
+	reconciled := getCondition(t, got, msoapi.ReconciledCondition)
+	assert.Equal(t, reconciled.Status, msoapi.ConditionFalse)
+	assert.Assert(t, strings.HasPrefix(reconciled.Message, "invalid query configuration"), reconciled.Message)
+
+	err := rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, &appsv1.Deployment{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,229 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+const (
+	AvailableReason               = "ThanosQuerierAvailable"
+	ReconciledReason              = "ThanosQuerierReconciled"
+	FailedToReconcileReason       = "FailedToReconcile"
+	DeploymentNotAvailableReason  = "DeploymentNotAvailable"
+	AvailableMessage              = "Thanos Querier is available"
+	SuccessfullyReconciledMessage = "Thanos Querier is successfully reconciled"
+
+	// statusRefreshInterval is the interval between two checks of the
+	// health of the stores.
+	statusRefreshInterval = time.Minute
+)
+
+// storeLister lists the stores known by a Thanos querier.
+type storeLister interface {
+	Stores(ctx context.Context) (map[string][]prometheus.StoreStatus, error)
+}
+
+// newStoreLister returns a store lister for the querier reachable at baseURL.
+// The bearer token is only sent over TLS (tenancy mode).
+func newStoreLister(baseURL string, tlsConfig *tls.Config, token string) (storeLister, error) {
+	c, err := prometheus.NewClient(baseURL, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && token != "" {
+		c.WithBearerToken(token)
+	}
+	return c, nil
+}
+
+// updateStatus reports the discovered stacks, the conditions and the health
+// of the stores in the status of the querier. The status is refreshed
+// periodically since the health of the stores changes without any event.
+func (rm resourceManager) updateStatus(ctx context.Context, querier *msoapi.ThanosQuerier, stacks *discoveredStacks, recErr error) ctrl.Result {
+	logger := rm.logger.WithValues("querier", client.ObjectKeyFromObject(querier))
+	name := "thanos-querier-" + querier.Name
+
+	querier.Status.MonitoringStacks = stacks.selected
+	querier.Status.ExcludedMonitoringStacks = stacks.excluded
+	querier.Status.Endpoints = stacks.endpoints
+	for _, g := range stacks.tlsEndpoints {
+		querier.Status.Endpoints = append(querier.Status.Endpoints, g.addresses...)
+	}
+
+	d := &appsv1.Deployment{}
+	if err := rm.Get(ctx, client.ObjectKey{Namespace: querier.Namespace, Name: name}, d); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Info("Failed to get deployment", "err", err)
+			return ctrl.Result{RequeueAfter: 2 * time.Second}
+		}
+		d = nil
+	}
+
+	available := availableCondition(querier, d)
+	querier.Status.Conditions = setCondition(querier.Status.Conditions, reconciledCondition(querier, recErr))
+	querier.Status.Conditions = setCondition(querier.Status.Conditions, available)
+
+	querier.Status.Stores = nil
+	if available.Status == msoapi.ConditionTrue {
+		stores, err := rm.querierStores(ctx, querier, name)
+		if err != nil {
+			logger.Info("Failed to get the stores", "err", err)
+		}
+		querier.Status.Stores = stores
+	}
+
+	if err := rm.Status().Update(ctx, querier); err != nil {
+		logger.Info("Failed to update status", "err", err)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}
+	}
+
+	return ctrl.Result{RequeueAfter: statusRefreshInterval}
+}
+
+// querierStores returns the health of the stores of the querier. In tenancy
+// mode, the stores API is only exposed by kube-rbac-proxy on the metrics port
+// of the tenancy Service.
+func (rm resourceManager) querierStores(ctx context.Context, querier *msoapi.ThanosQuerier, name string) ([]msoapi.ThanosStoreStatus, error) {
+	if tenancyEnabled(querier) {
+		tlsConfig, token, err := rm.tenancyClient(ctx, querier, name)
+		if err != nil {
+			return nil, err
+		}
+		return rm.stores(ctx, fmt.Sprintf("https://%s.%s.svc:%d", tenancyServiceName(name), querier.Namespace, tenancyMetricsPort), tlsConfig, token)
+	}
+
+	// The stores API isn't served by the query frontend.
+	service := name
+	if queryFrontendEnabled(querier) {
+		service = name + "-downstream"
+	}
+	return rm.stores(ctx, fmt.Sprintf("http://%s.%s.svc:%d", service, querier.Namespace, httpPort), nil, "")
+}
+
+// stores returns the health of the stores known by the Thanos querier
+// reachable at baseURL, sorted by type and name.
+func (rm resourceManager) stores(ctx context.Context, baseURL string, tlsConfig *tls.Config, token string) ([]msoapi.ThanosStoreStatus, error) {
+	sl, err := rm.newStoreLister(baseURL, tlsConfig, token)
+	if err != nil {
+		return nil, err
+	}
+
+	stores, err := sl.Stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []msoapi.ThanosStoreStatus
+	for typ, list := range stores {
+		for _, s := range list {
+			status := msoapi.ThanosStoreStatus{
+				Name:    s.Name,
+				Type:    typ,
+				Healthy: s.LastError == nil || *s.LastError == "",
+			}
+			if !s.LastCheck.IsZero() {
+				status.LastCheck = &metav1.Time{Time: s.LastCheck}
+			}
+			if s.LastError != nil {
+				status.LastError = *s.LastError
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Type != statuses[j].Type {
+			return statuses[i].Type < statuses[j].Type
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+
+	return statuses, nil
+}
+
+func reconciledCondition(querier *msoapi.ThanosQuerier, err error) msoapi.Condition {
+	c := msoapi.Condition{
+		Type:               msoapi.ReconciledCondition,
+		Status:             msoapi.ConditionTrue,
+		Reason:             ReconciledReason,
+		Message:            SuccessfullyReconciledMessage,
+		ObservedGeneration: querier.Generation,
+	}
+
+	if err != nil {
+		c.Status = msoapi.ConditionFalse
+		c.Reason = FailedToReconcileReason
+		c.Message = err.Error()
+	}
+
+	return c
+}
+
+// availableCondition returns the Available condition based on the state of
+// the querier's deployment (nil if it doesn't exist).
+func availableCondition(querier *msoapi.ThanosQuerier, d *appsv1.Deployment) msoapi.Condition {
+	c := msoapi.Condition{
+		Type:               msoapi.AvailableCondition,
+		Status:             msoapi.ConditionTrue,
+		Reason:             AvailableReason,
+		Message:            AvailableMessage,
+		ObservedGeneration: querier.Generation,
+	}
+
+	if d == nil {
+		c.Status = msoapi.ConditionUnknown
+		c.Reason = DeploymentNotAvailableReason
+		c.Message = "Deployment not found"
+		return c
+	}
+
+	if d.Status.AvailableReplicas == 0 {
+		c.Status = msoapi.ConditionFalse
+		c.Reason = DeploymentNotAvailableReason
+		c.Message = "No replica of the Thanos querier is available"
+		return c
+	}
+
+	for _, dc := range d.Status.Conditions {
+		if dc.Type == appsv1.DeploymentAvailable && dc.Status != corev1.ConditionTrue {
+			c.Status = msoapi.ConditionFalse
+			c.Reason = DeploymentNotAvailableReason
+			c.Message = dc.Message
+		}
+	}
+
+	return c
+}
+
+// setCondition replaces (or adds) the condition in the list. The transition
+// time is only updated when the status changes.
+func setCondition(conditions []msoapi.Condition, c msoapi.Condition) []msoapi.Condition {
+	conditions = append([]msoapi.Condition(nil), conditions...)
+	for i, existing := range conditions {
+		if existing.Type != c.Type {
+			continue
+		}
+		c.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != c.Status {
+			c.LastTransitionTime = metav1.Now()
+		}
+		conditions[i] = c
+		return conditions
+	}
+
+	c.LastTransitionTime = metav1.Now()
+	return append(conditions, c)
+}
@@ -0,0 +1,172 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/controllers/monitoring/internal/testutil"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+)
+
+type fakeStoreLister struct {
+	baseURL   string
+	tlsConfig *tls.Config
+	token     string
+	stores    map[string][]prometheus.StoreStatus
+	err       error
+}
+
+func (f *fakeStoreLister) Stores(_ context.Context) (map[string][]prometheus.StoreStatus, error) {
+	return f.stores, f.err
+}
+
+// newTestManager returns a resourceManager backed by a fake client and the
+// given store lister.
+func newTestManager(sl *fakeStoreLister, objs ...client.Object) *resourceManager {
+	c := testutil.NewClientBuilder(objs...).
+		WithIndex(&msoapi.ThanosQuerier{}, stacksIndexKey, indexQuerierStacks).
+		WithIndex(&msoapi.ThanosQuerier{}, namespacesIndexKey, indexQuerierNamespaces).
+		WithInterceptorFuncs(testutil.WithApplyPatch()).
+		Build()
+
+	return &resourceManager{
+		Client:    c,
+		apiReader: c,
+		scheme:    c.Scheme(),
+		logger:    logr.Discard(),
+		newStoreLister: func(baseURL string, tlsConfig *tls.Config, token string) (storeLister, error) {
+			sl.baseURL = baseURL
+			sl.tlsConfig = tlsConfig
+			sl.token = token
+			return sl, nil
+		},
+	}
+}
+
+func newQuerier() *msoapi.ThanosQuerier {
+	return &msoapi.ThanosQuerier{
+		ObjectMeta: metav1.ObjectMeta{Name: "tq", Namespace: "ns", Generation: 1},
+		Spec: msoapi.ThanosQuerierSpec{
+			Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+		},
+	}
+}
+
+func newMonitoringStack(name string, namespace string) *msoapi.MonitoringStack {
+	return &msoapi.MonitoringStack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": "demo"}},
+	}
+}
+
+func reconcileQuerier(t *testing.T, rm *resourceManager) *msoapi.ThanosQuerier {
+	t.Helper()
+
+	key := client.ObjectKeyFromObject(newQuerier())
+	res, err := rm.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	assert.NilError(t, err)
+	assert.Equal(t, res.RequeueAfter, statusRefreshInterval)
+
+	got := &msoapi.ThanosQuerier{}
+	assert.NilError(t, rm.Get(context.Background(), key, got))
+	return got
+}
+
+func getCondition(t *testing.T, tq *msoapi.ThanosQuerier, ct msoapi.ConditionType) msoapi.Condition {
+	t.Helper()
+
+	for _, c := range tq.Status.Conditions {
+		if c.Type == ct {
+			return c
+		}
+	}
+	t.Fatalf("condition %q not found", ct)
+	return msoapi.Condition{}
+}
+
+func TestReconcileStatus(t *testing.T) {
+	lastError := "connection refused"
+	sl := &fakeStoreLister{
+		stores: map[string][]prometheus.StoreStatus{
+			"sidecar": {
+				{Name: "10.0.0.2:10901", LastError: &lastError},
+				{Name: "10.0.0.1:10901", LastCheck: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+	rm := newTestManager(sl,
+		newQuerier(),
+		newMonitoringStack("ms", "ns"),
+		newMonitoringStack("other", "other"),
+	)
+
+	got := reconcileQuerier(t, rm)
+	assert.DeepEqual(t, got.Status.MonitoringStacks, []string{"ns/ms"})
+	assert.DeepEqual(t, got.Status.ExcludedMonitoringStacks, []string{"other/other"})
+	assert.DeepEqual(t, got.Status.Endpoints, []string{"dnssrv+_grpc._tcp.ms-thanos-sidecar.ns.svc.cluster.local"})
+	assert.Equal(t, getCondition(t, got, msoapi.ReconciledCondition).Status, msoapi.ConditionTrue)
+
+	// The fake client doesn't run the deployment controller.
+	available := getCondition(t, got, msoapi.AvailableCondition)
+	assert.Equal(t, available.Status, msoapi.ConditionFalse)
+	assert.Equal(t, available.Reason, DeploymentNotAvailableReason)
+	assert.Assert(t, got.Status.Stores == nil)
+
+	d := &appsv1.Deployment{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, d))
+	d.Status.AvailableReplicas = 1
+	d.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}}
+	assert.NilError(t, rm.Status().Update(context.Background(), d))
+
+	got = reconcileQuerier(t, rm)
+	available = getCondition(t, got, msoapi.AvailableCondition)
+	assert.Equal(t, available.Status, msoapi.ConditionTrue)
+	assert.Equal(t, available.Reason, AvailableReason)
+	assert.Equal(t, sl.baseURL, "http://thanos-querier-tq.ns.svc:10902")
+	assert.Assert(t, sl.tlsConfig == nil)
+	assert.Equal(t, sl.token, "")
+	assert.Equal(t, len(got.Status.Stores), 2)
+	assert.Equal(t, got.Status.Stores[0].Name, "10.0.0.1:10901")
+	assert.Equal(t, got.Status.Stores[0].Type, "sidecar")
+	assert.Assert(t, got.Status.Stores[0].Healthy)
+	assert.Assert(t, got.Status.Stores[0].LastCheck != nil)
+	assert.Equal(t, got.Status.Stores[1].Name, "10.0.0.2:10901")
+	assert.Assert(t, !got.Status.Stores[1].Healthy)
+	assert.Equal(t, got.Status.Stores[1].LastError, "connection refused")
+
+	// Failures to get the stores don't fail the reconciliation.
+	sl.err = errors.New("timeout")
+	got = reconcileQuerier(t, rm)
+	assert.Equal(t, getCondition(t, got, msoapi.AvailableCondition).Status, msoapi.ConditionTrue)
+	assert.Assert(t, got.Status.Stores == nil)
+}
+
+func TestAvailableCondition(t *testing.T) {
+	tq := newQuerier()
+
+	c := availableCondition(tq, nil)
+	assert.Equal(t, c.Status, msoapi.ConditionUnknown)
+
+	c = availableCondition(tq, &appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			AvailableReplicas: 1,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Message: "Deployment does not have minimum availability."},
+			},
+		},
+	})
+	assert.Equal(t, c.Status, msoapi.ConditionFalse)
+	assert.Equal(t, c.Message, "Deployment does not have minimum availability.")
+	assert.Equal(t, c.ObservedGeneration, int64(1))
+}
@@ -0,0 +1,445 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+const (
+	tenancyPort                  = 9092
+	tenancyMetricsPort           = 9093
+	grpcPort                     = 10901
+	promLabelProxyPort           = 9095
+	defaultTenancyLabel          = "namespace"
+	tenancyConfigMountPath       = "/etc/kube-rbac-proxy"
+	tenancyTLSMountPath          = "/etc/tls/private"
+	tenancyConfigFileName        = "config.yaml"
+	tenancyMetricsConfigFileName = "metrics-config.yaml"
+	tenancyAllowedPaths          = "/api/v1/query,/api/v1/query_range,/api/v1/labels,/api/v1/label/*/values,/api/v1/series"
+	tenancyMetricsPath           = "/metrics"
+	tenancyStoresPath            = "/api/v1/stores"
+	authDelegatorRole            = "system:auth-delegator"
+	tenancyTLSVolumeName         = "tenancy-tls"
+	tenancyConfigVolumeName      = "tenancy-config"
+)
+
+func tenancyEnabled(querier *msoapi.ThanosQuerier) bool {
+	return querier.Spec.Tenancy != nil && querier.Spec.Tenancy.Enabled
+}
+
+// tenancyLabel returns the label enforced in the queries.
+func tenancyLabel(querier *msoapi.ThanosQuerier) string {
+	if querier.Spec.Tenancy == nil || querier.Spec.Tenancy.Label == "" {
+		return defaultTenancyLabel
+	}
+	return querier.Spec.Tenancy.Label
+}
+
+func managedTenancyTLSSecretName(querier *msoapi.ThanosQuerier) string {
+	return querier.Name + "-tenancy-tls"
+}
+
+// tenancyTLSSecretName returns the name of the secret holding the serving
+// certificate of the tenancy endpoint.
+func tenancyTLSSecretName(querier *msoapi.ThanosQuerier) string {
+	if querier.Spec.Tenancy != nil && querier.Spec.Tenancy.TLSSecretName != "" {
+		return querier.Spec.Tenancy.TLSSecretName
+	}
+	return managedTenancyTLSSecretName(querier)
+}
+
+// tenancyTLSSecret returns the secret holding the serving certificate of the
+// tenancy endpoint issued by the operator. It returns nil when the tenancy
+// mode is disabled or when the secret is provided by the user.
+func (rm resourceManager) tenancyTLSSecret(ctx context.Context, querier *msoapi.ThanosQuerier) (*corev1.Secret, error) {
+	if !tenancyEnabled(querier) || querier.Spec.Tenancy.TLSSecretName != "" {
+		return nil, nil
+	}
+
+	ca, err := certificates.GetCA(ctx, rm, rm.apiReader, rm.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the certificate authority: %w", err)
+	}
+
+	service := tenancyServiceName("thanos-querier-" + querier.Name)
+	secret, err := ca.NewSecret(ctx, rm, managedTenancyTLSSecretName(querier), querier.Namespace, certificates.Request{
+		CommonName: service,
+		DNSNames: []string{
+			service,
+			fmt.Sprintf("%s.%s.svc", service, querier.Namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", service, querier.Namespace),
+		},
+		Usage: x509.ExtKeyUsageServerAuth,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue the serving certificate of the tenancy endpoint: %w", err)
+	}
+
+	secret.Labels = componentLabels("thanos-querier-" + querier.Name)
+	return secret, nil
+}
+
+// tenancyArgs binds the APIs of the querier to the loopback interface in
+// tenancy mode so that they can only be reached through the proxies: the
+// query APIs through the tenancy port and the metrics and the stores through
+// the metrics port.
+func tenancyArgs(querier *msoapi.ThanosQuerier) []string {
+	if !tenancyEnabled(querier) {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("--http-address=127.0.0.1:%d", httpPort),
+		fmt.Sprintf("--grpc-address=127.0.0.1:%d", grpcPort),
+	}
+}
+
+func tenancyServiceName(name string) string {
+	return name + "-tenancy"
+}
+
+// metricsReaderName returns the name of the service account allowed to get
+// the metrics and the stores of the querier in tenancy mode. Its token is
+// used by the ServiceMonitor and by the operator.
+func metricsReaderName(name string) string {
+	return name + "-metrics-reader"
+}
+
+// tenancyClient returns the TLS configuration and the bearer token to get
+// the stores of the querier through the metrics port in tenancy mode. The
+// serving certificate is verified with the certificate authority of the
+// tenancy secret.
+func (rm resourceManager) tenancyClient(ctx context.Context, querier *msoapi.ThanosQuerier, name string) (*tls.Config, string, error) {
+	tlsSecret := &corev1.Secret{}
+	if err := rm.apiReader.Get(ctx, client.ObjectKey{Namespace: querier.Namespace, Name: tenancyTLSSecretName(querier)}, tlsSecret); err != nil {
+		return nil, "", fmt.Errorf("failed to get the serving certificate of the tenancy endpoint: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(tlsSecret.Data[certificates.CAKey]) {
+		return nil, "", fmt.Errorf("no certificate authority (%s) in secret %s/%s", certificates.CAKey, tlsSecret.Namespace, tlsSecret.Name)
+	}
+
+	tokenSecret := &corev1.Secret{}
+	if err := rm.Get(ctx, client.ObjectKey{Namespace: querier.Namespace, Name: metricsReaderName(name)}, tokenSecret); err != nil {
+		return nil, "", fmt.Errorf("failed to get the token of the metrics reader: %w", err)
+	}
+	token := string(tokenSecret.Data[corev1.ServiceAccountTokenKey])
+	if token == "" {
+		return nil, "", fmt.Errorf("the token of the metrics reader isn't issued yet")
+	}
+
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, token, nil
+}
+
+// tenancyReconcilers return the reconcilers of the resources of the tenancy
+// mode. Like the gRPC certificates, a serving certificate provided by the user
+// is left untouched.
+func tenancyReconcilers(querier *msoapi.ThanosQuerier, name string, tlsSecret *corev1.Secret) []reconciler.Reconciler {
+	enabled := tenancyEnabled(querier)
+	userProvided := querier.Spec.Tenancy != nil && querier.Spec.Tenancy.TLSSecretName == managedTenancyTLSSecretName(querier)
+
+	var reconcilers []reconciler.Reconciler
+	switch {
+	case tlsSecret != nil:
+		reconcilers = append(reconcilers, reconciler.NewUpdater(tlsSecret, querier))
+	case !userProvided:
+		reconcilers = append(reconcilers, reconciler.NewDeleter(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      managedTenancyTLSSecretName(querier),
+				Namespace: querier.Namespace,
+			},
+		}))
+	}
+
+	return append(reconcilers,
+		reconciler.NewOptionalUpdater(newTenancyConfigMap(querier, name), querier, enabled),
+		reconciler.NewOptionalUpdater(newTenancyService(tenancyServiceName(name), querier.Namespace, name), querier, enabled),
+		reconciler.NewOptionalUpdater(newTenancyClusterRoleBinding(querier, name), querier, enabled),
+		reconciler.NewOptionalUpdater(newServiceAccount(metricsReaderName(name), querier.Namespace), querier, enabled),
+		reconciler.NewOptionalUpdater(newMetricsReaderTokenSecret(metricsReaderName(name), querier.Namespace), querier, enabled),
+		reconciler.NewOptionalUpdater(newTenancyServiceMonitor(querier, name), querier, enabled),
+	)
+}
+
+// newTenancyConfigMap returns the configurations of kube-rbac-proxy. On the
+// tenancy port, the requests are authorized if the caller can get the metrics
+// of the pods in the namespace passed in the query parameter. On the metrics
+// port, the metrics reader is allowed to get the metrics and the stores, the
+// other callers need to be allowed to get the paths.
+func newTenancyConfigMap(querier *msoapi.ThanosQuerier, name string) *corev1.ConfigMap {
+	cmName := tenancyServiceName(name)
+	reader := fmt.Sprintf("system:serviceaccount:%s:%s", querier.Namespace, metricsReaderName(name))
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cmName,
+			Namespace: querier.Namespace,
+			Labels:    componentLabels(name),
+		},
+		Data: map[string]string{
+			tenancyConfigFileName: fmt.Sprintf(`authorization:
+  rewrites:
+    byQueryParameter:
+      name: %s
+  resourceAttributes:
+    apiGroup: metrics.k8s.io
+    resource: pods
+    namespace: "{{ .Value }}"
+`, tenancyLabel(querier)),
+			tenancyMetricsConfigFileName: fmt.Sprintf(`authorization:
+  static:
+  - user:
+      name: %[1]s
+    verb: get
+    path: %[2]s
+    resourceRequest: false
+  - user:
+      name: %[1]s
+    verb: get
+    path: %[3]s
+    resourceRequest: false
+`, reader, tenancyMetricsPath, tenancyStoresPath),
+		},
+	}
+}
+
+// newMetricsReaderTokenSecret returns the secret populated by Kubernetes with
+// a token of the metrics reader service account.
+func newMetricsReaderTokenSecret(name string, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+			Annotations: map[string]string{
+				corev1.ServiceAccountNameKey: name,
+			},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+}
+
+// newTenancyServiceMonitor scrapes the querier through the metrics port of
+// the tenancy Service with the token of the metrics reader.
+func newTenancyServiceMonitor(querier *msoapi.ThanosQuerier, name string) *monv1.ServiceMonitor {
+	service := tenancyServiceName(name)
+	sm := newServiceMonitor(service, querier.Namespace)
+	sm.Spec.Endpoints = []monv1.Endpoint{{
+		Port:   "metrics",
+		Scheme: "https",
+		Authorization: &monv1.SafeAuthorization{
+			Credentials: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: metricsReaderName(name)},
+				Key:                  corev1.ServiceAccountTokenKey,
+			},
+		},
+		TLSConfig: &monv1.TLSConfig{
+			SafeTLSConfig: monv1.SafeTLSConfig{
+				CA: monv1.SecretOrConfigMap{
+					Secret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: tenancyTLSSecretName(querier)},
+						Key:                  certificates.CAKey,
+					},
+				},
+				ServerName: ptr.To(fmt.Sprintf("%s.%s.svc", service, querier.Namespace)),
+			},
+		},
+	}}
+	return sm
+}
+
+func newTenancyService(name string, namespace string, target string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    componentLabels(name),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Port: tenancyPort,
+					Name: "tenancy",
+				},
+				{
+					Port: tenancyMetricsPort,
+					Name: "metrics",
+				},
+			},
+			Selector: map[string]string{
+				"app.kubernetes.io/instance": target,
+			},
+			Type: "ClusterIP",
+		},
+	}
+}
+
+// newTenancyClusterRoleBinding allows kube-rbac-proxy to authenticate and
+// authorize the callers.
+func newTenancyClusterRoleBinding(querier *msoapi.ThanosQuerier, name string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       "ClusterRoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("%s-%s-tenancy", querier.Namespace, name),
+			Labels: componentLabels(name),
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      "ServiceAccount",
+			Name:      name,
+			Namespace: querier.Namespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.SchemeGroupVersion.Group,
+			Kind:     "ClusterRole",
+			Name:     authDelegatorRole,
+		},
+	}
+}
+
+// newTenancyContainers returns the containers of the proxies serving the
+// tenancy endpoint: kube-rbac-proxy authorizes the requests and forwards them
+// to prom-label-proxy which enforces the label matcher before querying the
+// Thanos querier. A second kube-rbac-proxy serves the metrics and the stores
+// of the querier.
+func newTenancyContainers(querier *msoapi.ThanosQuerier, thanosCfg ThanosConfiguration) []corev1.Container {
+	securityContext := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{
+				"ALL",
+			},
+		},
+		RunAsNonRoot: ptr.To(true),
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+
+	return []corev1.Container{
+		{
+			Name:  "kube-rbac-proxy",
+			Image: thanosCfg.KubeRBACProxyImage,
+			Args: []string{
+				fmt.Sprintf("--secure-listen-address=0.0.0.0:%d", tenancyPort),
+				fmt.Sprintf("--upstream=http://127.0.0.1:%d", promLabelProxyPort),
+				fmt.Sprintf("--config-file=%s", filepath.Join(tenancyConfigMountPath, tenancyConfigFileName)),
+				fmt.Sprintf("--tls-cert-file=%s", filepath.Join(tenancyTLSMountPath, certificates.CertificateKey)),
+				fmt.Sprintf("--tls-private-key-file=%s", filepath.Join(tenancyTLSMountPath, certificates.PrivateKeyKey)),
+				fmt.Sprintf("--allow-paths=%s", tenancyAllowedPaths),
+			},
+			Ports: []corev1.ContainerPort{
+				{
+					ContainerPort: tenancyPort,
+					Name:          "tenancy",
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      tenancyConfigVolumeName,
+					MountPath: tenancyConfigMountPath,
+					ReadOnly:  true,
+				},
+				{
+					Name:      tenancyTLSVolumeName,
+					MountPath: tenancyTLSMountPath,
+					ReadOnly:  true,
+				},
+			},
+			TerminationMessagePolicy: "FallbackToLogsOnError",
+			SecurityContext:          securityContext,
+		},
+		{
+			Name:  "kube-rbac-proxy-metrics",
+			Image: thanosCfg.KubeRBACProxyImage,
+			Args: []string{
+				fmt.Sprintf("--secure-listen-address=0.0.0.0:%d", tenancyMetricsPort),
+				fmt.Sprintf("--upstream=http://127.0.0.1:%d", httpPort),
+				fmt.Sprintf("--config-file=%s", filepath.Join(tenancyConfigMountPath, tenancyMetricsConfigFileName)),
+				fmt.Sprintf("--tls-cert-file=%s", filepath.Join(tenancyTLSMountPath, certificates.CertificateKey)),
+				fmt.Sprintf("--tls-private-key-file=%s", filepath.Join(tenancyTLSMountPath, certificates.PrivateKeyKey)),
+				fmt.Sprintf("--allow-paths=%s,%s", tenancyMetricsPath, tenancyStoresPath),
+			},
+			Ports: []corev1.ContainerPort{
+				{
+					ContainerPort: tenancyMetricsPort,
+					Name:          "metrics",
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      tenancyConfigVolumeName,
+					MountPath: tenancyConfigMountPath,
+					ReadOnly:  true,
+				},
+				{
+					Name:      tenancyTLSVolumeName,
+					MountPath: tenancyTLSMountPath,
+					ReadOnly:  true,
+				},
+			},
+			TerminationMessagePolicy: "FallbackToLogsOnError",
+			SecurityContext:          securityContext,
+		},
+		{
+			Name:  "prom-label-proxy",
+			Image: thanosCfg.PromLabelProxyImage,
+			Args: []string{
+				fmt.Sprintf("--insecure-listen-address=127.0.0.1:%d", promLabelProxyPort),
+				fmt.Sprintf("--upstream=http://127.0.0.1:%d", httpPort),
+				fmt.Sprintf("--label=%s", tenancyLabel(querier)),
+				"--enable-label-apis",
+				"--error-on-replace",
+			},
+			TerminationMessagePolicy: "FallbackToLogsOnError",
+			SecurityContext:          securityContext,
+		},
+	}
+}
+
+func newTenancyVolumes(querier *msoapi.ThanosQuerier, name string) []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: tenancyConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: tenancyServiceName(name)},
+				},
+			},
+		},
+		{
+			Name: tenancyTLSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tenancyTLSSecretName(querier),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package thanos_querier
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"gotest.tools/v3/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msoapi "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/pkg/certificates"
+	"github.com/rhobs/observability-operator/pkg/prometheus"
+	"github.com/rhobs/observability-operator/pkg/reconciler"
+)
+
+func TestNewThanosQuerierDeploymentTenancy(t *testing.T) {
+	tq := newQuerier()
+	cfg := ThanosConfiguration{Image: "thanos", PromLabelProxyImage: "prom-label-proxy", KubeRBACProxyImage: "kube-rbac-proxy"}
+
+	d := newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", cfg)
+	assert.Equal(t, len(d.Spec.Template.Spec.Containers), 1)
+
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true, Label: "tenant"}
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", cfg)
+	pod := d.Spec.Template.Spec
+	assert.Equal(t, len(pod.Containers), 4)
+
+	// The querier APIs are only reachable through the proxies.
+	args := pod.Containers[0].Args
+	assert.DeepEqual(t, args[len(args)-2:], []string{
+		"--http-address=127.0.0.1:10902",
+		"--grpc-address=127.0.0.1:10901",
+	})
+	assert.Equal(t, len(pod.Containers[0].Ports), 0)
+
+	rbacProxy := pod.Containers[1]
+	assert.Equal(t, rbacProxy.Image, "kube-rbac-proxy")
+	assert.Equal(t, rbacProxy.Args[0], "--secure-listen-address=0.0.0.0:9092")
+	assert.Equal(t, rbacProxy.Args[1], "--upstream=http://127.0.0.1:9095")
+
+	// The metrics and the stores are served by another kube-rbac-proxy.
+	metricsProxy := pod.Containers[2]
+	assert.Equal(t, metricsProxy.Image, "kube-rbac-proxy")
+	assert.DeepEqual(t, metricsProxy.Args, []string{
+		"--secure-listen-address=0.0.0.0:9093",
+		"--upstream=http://127.0.0.1:10902",
+		"--config-file=/etc/kube-rbac-proxy/metrics-config.yaml",
+		"--tls-cert-file=/etc/tls/private/tls.crt",
+		"--tls-private-key-file=/etc/tls/private/tls.key",
+		"--allow-paths=/metrics,/api/v1/stores",
+	})
+	assert.Equal(t, metricsProxy.Ports[0].Name, "metrics")
+
+	labelProxy := pod.Containers[3]
+	assert.Equal(t, labelProxy.Image, "prom-label-proxy")
+	assert.DeepEqual(t, labelProxy.Args, []string{
+		"--insecure-listen-address=127.0.0.1:9095",
+		"--upstream=http://127.0.0.1:10902",
+		"--label=tenant",
+		"--enable-label-apis",
+		"--error-on-replace",
+	})
+
+	assert.Equal(t, pod.Volumes[0].ConfigMap.Name, "thanos-querier-tq-tenancy")
+	assert.Equal(t, pod.Volumes[1].Secret.SecretName, "tq-tenancy-tls")
+
+	tq.Spec.Tenancy.TLSSecretName = "custom"
+	d = newThanosQuerierDeployment("thanos-querier-tq", tq, nil, nil, "", cfg)
+	assert.Equal(t, d.Spec.Template.Spec.Volumes[1].Secret.SecretName, "custom")
+}
+
+func TestNewTenancyConfigMap(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+
+	cm := newTenancyConfigMap(tq, "thanos-querier-tq")
+	assert.Equal(t, cm.Data["config.yaml"], `authorization:
+  rewrites:
+    byQueryParameter:
+      name: namespace
+  resourceAttributes:
+    apiGroup: metrics.k8s.io
+    resource: pods
+    namespace: "{{ .Value }}"
+`)
+	assert.Equal(t, cm.Data["metrics-config.yaml"], `authorization:
+  static:
+  - user:
+      name: system:serviceaccount:ns:thanos-querier-tq-metrics-reader
+    verb: get
+    path: /metrics
+    resourceRequest: false
+  - user:
+      name: system:serviceaccount:ns:thanos-querier-tq-metrics-reader
+    verb: get
+    path: /api/v1/stores
+    resourceRequest: false
+`)
+}
+
+func TestTenancyTLSSecret(t *testing.T) {
+	rm := newTestManager(&fakeStoreLister{})
+	rm.namespace = "operator"
+
+	tq := newQuerier()
+	secret, err := rm.tenancyTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+	secret, err = rm.tenancyTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Equal(t, secret.Name, "tq-tenancy-tls")
+
+	block, _ := pem.Decode(secret.Data[certificates.CertificateKey])
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cert.DNSNames, []string{
+		"thanos-querier-tq-tenancy",
+		"thanos-querier-tq-tenancy.ns.svc",
+		"thanos-querier-tq-tenancy.ns.svc.cluster.local",
+	})
+
+	// The secret provided by the user is never deleted.
+	tq.Spec.Tenancy.TLSSecretName = "tq-tenancy-tls"
+	secret, err = rm.tenancyTLSSecret(context.Background(), tq)
+	assert.NilError(t, err)
+	assert.Assert(t, secret == nil)
+	for _, r := range tenancyReconcilers(tq, "thanos-querier-tq", nil) {
+		_, isDeleter := r.(reconciler.Deleter)
+		assert.Assert(t, !isDeleter)
+	}
+
+	tq.Spec.Tenancy = nil
+	_, isDeleter := tenancyReconcilers(tq, "thanos-querier-tq", nil)[0].(reconciler.Deleter)
+	assert.Assert(t, isDeleter)
+}
+
+func TestReconcileTenancy(t *testing.T) {
+	tq := newQuerier()
+	rm := newTestManager(&fakeStoreLister{}, tq)
+	rm.namespace = "operator"
+
+	reconcileQuerier(t, rm)
+	key := client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}
+	assert.NilError(t, rm.Get(context.Background(), key, &corev1.Service{}))
+	assert.NilError(t, rm.Get(context.Background(), key, &monv1.ServiceMonitor{}))
+
+	// The unrestricted Service and the ServiceMonitor are removed in
+	// tenancy mode.
+	got := &msoapi.ThanosQuerier{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(tq), got))
+	got.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+	assert.NilError(t, rm.Update(context.Background(), got))
+
+	got = reconcileQuerier(t, rm)
+	err := rm.Get(context.Background(), key, &corev1.Service{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	err = rm.Get(context.Background(), key, &monv1.ServiceMonitor{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	tenancyKey := client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-tenancy"}
+	svc := &corev1.Service{}
+	assert.NilError(t, rm.Get(context.Background(), tenancyKey, svc))
+	assert.Equal(t, svc.Spec.Ports[1].Name, "metrics")
+	assert.Equal(t, svc.Spec.Ports[1].Port, int32(9093))
+
+	// The querier is scraped through kube-rbac-proxy with the token of the
+	// metrics reader.
+	sm := &monv1.ServiceMonitor{}
+	assert.NilError(t, rm.Get(context.Background(), tenancyKey, sm))
+	assert.DeepEqual(t, sm.Spec.Selector.MatchLabels, map[string]string{"app.kubernetes.io/instance": "thanos-querier-tq-tenancy"})
+	assert.Equal(t, sm.Spec.Endpoints[0].Port, "metrics")
+	assert.Equal(t, sm.Spec.Endpoints[0].Scheme, "https")
+	assert.Equal(t, sm.Spec.Endpoints[0].Authorization.Credentials.Name, "thanos-querier-tq-metrics-reader")
+	assert.Equal(t, sm.Spec.Endpoints[0].TLSConfig.CA.Secret.Name, "tq-tenancy-tls")
+	readerKey := client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-metrics-reader"}
+	assert.NilError(t, rm.Get(context.Background(), readerKey, &corev1.ServiceAccount{}))
+	token := &corev1.Secret{}
+	assert.NilError(t, rm.Get(context.Background(), readerKey, token))
+	assert.Equal(t, token.Type, corev1.SecretTypeServiceAccountToken)
+	assert.Equal(t, token.Annotations[corev1.ServiceAccountNameKey], "thanos-querier-tq-metrics-reader")
+
+	// The query frontend would bypass the proxies.
+	got.Spec.QueryFrontend = &msoapi.ThanosQueryFrontendConfig{Enabled: true}
+	assert.NilError(t, rm.Update(context.Background(), got))
+
+	got = reconcileQuerier(t, rm)
+	reconciled := getCondition(t, got, msoapi.ReconciledCondition)
+	assert.Equal(t, reconciled.Status, msoapi.ConditionFalse)
+	assert.Assert(t, strings.Contains(reconciled.Message, "the query frontend can't be deployed in tenancy mode"), reconciled.Message)
+
+	// Disabling the tenancy mode removes the metrics reader.
+	got.Spec.QueryFrontend = nil
+	got.Spec.Tenancy = nil
+	assert.NilError(t, rm.Update(context.Background(), got))
+	reconcileQuerier(t, rm)
+	err = rm.Get(context.Background(), readerKey, &corev1.Secret{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+	err = rm.Get(context.Background(), tenancyKey, &monv1.ServiceMonitor{})
+	assert.Assert(t, apierrors.IsNotFound(err))
+}
+
+func TestReconcileStatusTenancy(t *testing.T) {
+	tq := newQuerier()
+	tq.Spec.Tenancy = &msoapi.ThanosQuerierTenancyConfig{Enabled: true}
+	sl := &fakeStoreLister{
+		stores: map[string][]prometheus.StoreStatus{
+			"sidecar": {{Name: "10.0.0.1:10901"}},
+		},
+	}
+	rm := newTestManager(sl, tq)
+	rm.namespace = "operator"
+	got := reconcileQuerier(t, rm)
+
+	// The deployment is available and the token of the metrics reader is
+	// issued.
+	d := &appsv1.Deployment{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq"}, d))
+	d.Status.AvailableReplicas = 1
+	d.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}}
+	assert.NilError(t, rm.Status().Update(context.Background(), d))
+	token := &corev1.Secret{}
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "thanos-querier-tq-metrics-reader"}, token))
+	token.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("secret-token")}
+	assert.NilError(t, rm.Update(context.Background(), token))
+
+	// The stores are read through kube-rbac-proxy. The fake client would
+	// reset the token when reconciling the secret: only the status is
+	// updated.
+	rm.updateStatus(context.Background(), got, &discoveredStacks{}, nil)
+	assert.NilError(t, rm.Get(context.Background(), client.ObjectKeyFromObject(tq), got))
+	assert.Equal(t, sl.baseURL, "https://thanos-querier-tq-tenancy.ns.svc:9093")
+	assert.Assert(t, sl.tlsConfig != nil && sl.tlsConfig.RootCAs != nil)
+	assert.Equal(t, sl.token, "secret-token")
+	assert.Equal(t, len(got.Status.Stores), 1)
+	assert.Equal(t, got.Status.Stores[0].Name, "10.0.0.1:10901")
+}
@@ -8,17 +8,29 @@ import (
 	"path/filepath"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	stackctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack"
+	insightsctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-insights"
+	policyctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-policy"
+	recommenderctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-recommender"
+	reloadctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-reload"
+	snapshotctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-snapshot"
+	validationctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/monitoring-stack-validation"
+	ruletestctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/prometheus-rule-test"
+	sloctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/slo"
 	tqctrl "github.com/rhobs/observability-operator/pkg/controllers/monitoring/thanos-querier"
 	opctrl "github.com/rhobs/observability-operator/pkg/controllers/operator"
 	uictrl "github.com/rhobs/observability-operator/pkg/controllers/uiplugin"
@@ -53,15 +65,16 @@ type FeatureGates struct {
 }
 
 type OperatorConfiguration struct {
-	Namespace       string
-	MetricsAddr     string
-	HealthProbeAddr string
-	Prometheus      stackctrl.PrometheusConfiguration
-	Alertmanager    stackctrl.AlertmanagerConfiguration
-	ThanosSidecar   stackctrl.ThanosConfiguration
-	ThanosQuerier   tqctrl.ThanosConfiguration
-	UIPlugins       uictrl.UIPluginsConfiguration
-	FeatureGates    FeatureGates
+	Namespace        string
+	MetricsAddr      string
+	HealthProbeAddr  string
+	Prometheus       stackctrl.PrometheusConfiguration
+	Alertmanager     stackctrl.AlertmanagerConfiguration
+	ThanosSidecar    stackctrl.ThanosConfiguration
+	BlackboxExporter stackctrl.BlackboxExporterConfiguration
+	ThanosQuerier    tqctrl.ThanosConfiguration
+	UIPlugins        uictrl.UIPluginsConfiguration
+	FeatureGates     FeatureGates
 }
 
 func WithNamespace(ns string) func(*OperatorConfiguration) {
@@ -89,12 +102,30 @@ func WithThanosSidecarImage(image string) func(*OperatorConfiguration) {
 	}
 }
 
+func WithBlackboxExporterImage(image string) func(*OperatorConfiguration) {
+	return func(oc *OperatorConfiguration) {
+		oc.BlackboxExporter.Image = image
+	}
+}
+
 func WithThanosQuerierImage(image string) func(*OperatorConfiguration) {
 	return func(oc *OperatorConfiguration) {
 		oc.ThanosQuerier.Image = image
 	}
 }
 
+func WithPromLabelProxyImage(image string) func(*OperatorConfiguration) {
+	return func(oc *OperatorConfiguration) {
+		oc.ThanosQuerier.PromLabelProxyImage = image
+	}
+}
+
+func WithKubeRBACProxyImage(image string) func(*OperatorConfiguration) {
+	return func(oc *OperatorConfiguration) {
+		oc.ThanosQuerier.KubeRBACProxyImage = image
+	}
+}
+
 func WithMetricsAddr(addr string) func(*OperatorConfiguration) {
 	return func(oc *OperatorConfiguration) {
 		oc.MetricsAddr = addr
@@ -212,12 +243,29 @@ func New(ctx context.Context, cfg *OperatorConfiguration) (*Operator, error) {
 		}
 	}
 
+	managedSelector, err := labels.Parse(instanceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance selector: %w", err)
+	}
+
 	mgr, err := ctrl.NewManager(
 		restConfig,
 		ctrl.Options{
 			Scheme:                 scheme,
 			Metrics:                metricsOpts,
 			HealthProbeBindAddress: cfg.HealthProbeAddr,
+			Cache: cache.Options{
+				ByObject: map[client.Object]cache.ByObject{
+					// Only the ClusterRoleBindings of the ThanosQueriers
+					// are watched: don't cache the others.
+					&rbacv1.ClusterRoleBinding{}: {Label: tqctrl.ClusterRoleBindingSelector()},
+					// Only the Secrets and ConfigMaps managed by the
+					// operator are watched. The Secrets provided by the
+					// users are read with the API reader.
+					&corev1.Secret{}:    {Label: managedSelector},
+					&corev1.ConfigMap{}: {Label: managedSelector},
+				},
+			},
 		})
 	if err != nil {
 		return nil, fmt.Errorf("unable to create manager: %w", err)
@@ -228,11 +276,47 @@ func New(ctx context.Context, cfg *OperatorConfiguration) (*Operator, error) {
 		Prometheus:       cfg.Prometheus,
 		Alertmanager:     cfg.Alertmanager,
 		Thanos:           cfg.ThanosSidecar,
+		BlackboxExporter: cfg.BlackboxExporter,
+		Namespace:        cfg.Namespace,
 	}); err != nil {
 		return nil, fmt.Errorf("unable to register monitoring stack controller: %w", err)
 	}
 
-	if err := tqctrl.RegisterWithManager(mgr, tqctrl.Options{Thanos: cfg.ThanosQuerier}); err != nil {
+	if err := policyctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack policy controller: %w", err)
+	}
+
+	if err := insightsctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack insights controller: %w", err)
+	}
+
+	if err := recommenderctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack recommender controller: %w", err)
+	}
+
+	if err := reloadctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack reload controller: %w", err)
+	}
+
+	if err := snapshotctrl.RegisterWithManager(mgr, snapshotctrl.Options{
+		Thanos: snapshotctrl.ThanosConfiguration(cfg.ThanosSidecar),
+	}); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack snapshot controller: %w", err)
+	}
+
+	if err := validationctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register monitoring stack validation controller: %w", err)
+	}
+
+	if err := ruletestctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register prometheus rule test controller: %w", err)
+	}
+
+	if err := sloctrl.RegisterWithManager(mgr); err != nil {
+		return nil, fmt.Errorf("unable to register SLO controller: %w", err)
+	}
+
+	if err := tqctrl.RegisterWithManager(mgr, tqctrl.Options{Thanos: cfg.ThanosQuerier, Namespace: cfg.Namespace}); err != nil {
 		return nil, fmt.Errorf("unable to register the thanos querier controller with the manager: %w", err)
 	}
 
@@ -0,0 +1,348 @@
+// Package prometheus contains a minimal client for the HTTP API of the
+// Prometheus instances managed by the operator.
+package prometheus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+const (
+	// WebPort is the port on which Prometheus serves its web and API endpoints.
+	WebPort = 9090
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Client is an HTTP client for the Prometheus API.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// APIError is returned when the Prometheus API answers with an error.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("prometheus API error (status %d, type %q): %s", e.StatusCode, e.Type, e.Message)
+}
+
+// apiResponse is the envelope of every Prometheus API response.
+type apiResponse struct {
+	Status    string          `json:"status"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewClient returns a client for the Prometheus API served at baseURL. If
+// tlsConfig is nil, the default transport is used.
+func NewClient(baseURL string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus URL %q: %w", baseURL, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &Client{
+		baseURL: u,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+		},
+	}, nil
+}
+
+// WithBearerToken authenticates the requests of the client with the given
+// bearer token, e.g. for an API served behind kube-rbac-proxy.
+func (c *Client) WithBearerToken(token string) {
+	c.httpClient.Transport = transport.NewBearerAuthRoundTripper(token, c.httpClient.Transport)
+}
+
+// NewClientForStack returns a client for the Prometheus API of the given
+// MonitoringStack reachable at host (a pod IP or a service name). When the
+// stack enables TLS on the Prometheus web server, the client verifies the
+// server certificate with the configured certificate authority.
+func NewClientForStack(ctx context.Context, c client.Reader, ms *stack.MonitoringStack, host string) (*Client, error) {
+	scheme := "http"
+	var tlsConfig *tls.Config
+
+	if ms.Spec.PrometheusConfig != nil && ms.Spec.PrometheusConfig.WebTLSConfig != nil {
+		caRef := ms.Spec.PrometheusConfig.WebTLSConfig.CertificateAuthority
+		var secret corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: ms.Namespace, Name: caRef.Name}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get CA secret %s/%s: %w", ms.Namespace, caRef.Name, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(secret.Data[caRef.Key]) {
+			return nil, fmt.Errorf("failed to parse CA certificate from secret %s/%s (key %q)", ms.Namespace, caRef.Name, caRef.Key)
+		}
+
+		scheme = "https"
+		tlsConfig = &tls.Config{
+			RootCAs:    pool,
+			ServerName: ms.Name + "-prometheus",
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return NewClient(fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(WebPort))), tlsConfig)
+}
+
+// do sends a request to the given API path and decodes the "data" field of
+// the response into v (if not nil).
+func (c *Client) do(ctx context.Context, method string, path string, params url.Values, v any) error {
+	u := c.baseURL.JoinPath(path)
+
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(params.Encode())
+	} else {
+		u.RawQuery = params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		if resp.StatusCode/100 != 2 {
+			return &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+
+	if r.Status != "success" {
+		return &APIError{StatusCode: resp.StatusCode, Type: r.ErrorType, Message: r.Error}
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.Unmarshal(r.Data, v)
+}
+
+// Snapshot creates a snapshot of the TSDB and returns the name of the
+// directory (relative to the snapshots directory of the data path) holding
+// it. It requires the admin API to be enabled.
+func (c *Client) Snapshot(ctx context.Context, skipHead bool) (string, error) {
+	var data struct {
+		Name string `json:"name"`
+	}
+
+	params := url.Values{}
+	params.Set("skip_head", strconv.FormatBool(skipHead))
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/tsdb/snapshot", params, &data); err != nil {
+		return "", err
+	}
+
+	return data.Name, nil
+}
+
+// TSDBHeadStats holds statistics about the TSDB head block.
+type TSDBHeadStats struct {
+	NumSeries     uint64 `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int64  `json:"chunkCount"`
+	MinTime       int64  `json:"minTime"`
+	MaxTime       int64  `json:"maxTime"`
+}
+
+// Stat is a name/value pair returned by the TSDB status endpoint.
+type Stat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// TSDBStatus holds the cardinality statistics of the TSDB head block.
+type TSDBStatus struct {
+	HeadStats                   TSDBHeadStats `json:"headStats"`
+	SeriesCountByMetricName     []Stat        `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []Stat        `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []Stat        `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []Stat        `json:"seriesCountByLabelValuePair"`
+}
+
+// TSDBStatus returns the cardinality statistics of the TSDB head block,
+// limited to the top limit entries for each list.
+func (c *Client) TSDBStatus(ctx context.Context, limit int) (*TSDBStatus, error) {
+	params := url.Values{}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var status TSDBStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/status/tsdb", params, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Target health values.
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown"
+)
+
+// ActiveTarget is a target currently scraped by Prometheus.
+type ActiveTarget struct {
+	Labels     map[string]string `json:"labels"`
+	ScrapePool string            `json:"scrapePool"`
+	ScrapeURL  string            `json:"scrapeUrl"`
+	LastError  string            `json:"lastError"`
+	Health     string            `json:"health"`
+}
+
+// ActiveTargets returns the targets currently scraped by Prometheus.
+func (c *Client) ActiveTargets(ctx context.Context) ([]ActiveTarget, error) {
+	var data struct {
+		ActiveTargets []ActiveTarget `json:"activeTargets"`
+	}
+
+	params := url.Values{}
+	params.Set("state", "active")
+	if err := c.do(ctx, http.MethodGet, "/api/v1/targets", params, &data); err != nil {
+		return nil, err
+	}
+
+	return data.ActiveTargets, nil
+}
+
+// StoreStatus is the state of a store API endpoint known by a Thanos querier.
+type StoreStatus struct {
+	Name      string    `json:"name"`
+	LastCheck time.Time `json:"lastCheck"`
+	LastError *string   `json:"lastError"`
+	MinTime   int64     `json:"minTime"`
+	MaxTime   int64     `json:"maxTime"`
+}
+
+// Stores returns the store API endpoints known by a Thanos querier (which
+// serves the Prometheus API), grouped by component type.
+func (c *Client) Stores(ctx context.Context) (map[string][]StoreStatus, error) {
+	var data map[string][]StoreStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stores", nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Sample is an element of an instant vector.
+type Sample struct {
+	Metric map[string]string
+	Value  float64
+}
+
+// Query evaluates an instant query at the current time. Only queries
+// returning an instant vector are supported.
+func (c *Client) Query(ctx context.Context, query string) ([]Sample, error) {
+	var data struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	if err := c.do(ctx, http.MethodPost, "/api/v1/query", params, &data); err != nil {
+		return nil, err
+	}
+
+	if data.ResultType != "vector" {
+		return nil, fmt.Errorf("unexpected result type %q for query %q", data.ResultType, query)
+	}
+
+	var vector []struct {
+		Metric map[string]string `json:"metric"`
+		Value  [2]any            `json:"value"`
+	}
+	if err := json.Unmarshal(data.Result, &vector); err != nil {
+		return nil, fmt.Errorf("failed to decode result of query %q: %w", query, err)
+	}
+
+	samples := make([]Sample, 0, len(vector))
+	for _, r := range vector {
+		s, ok := r.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid sample value %v for query %q", r.Value[1], query)
+		}
+
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample value %q for query %q: %w", s, query, err)
+		}
+
+		samples = append(samples, Sample{Metric: r.Metric, Value: v})
+	}
+
+	return samples, nil
+}
+
+// Metrics scrapes the /metrics endpoint and returns the metric families
+// exposed in the text format.
+func (c *Client) Metrics(ctx context.Context) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL.JoinPath("/metrics").String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	return families, nil
+}
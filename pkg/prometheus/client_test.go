@@ -0,0 +1,207 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func newFakePrometheus(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(srv.URL, nil)
+	assert.NilError(t, err)
+
+	return c
+}
+
+func TestSnapshot(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		skipHead bool
+		status   int
+		body     string
+		expected string
+		err      *APIError
+	}{
+		{
+			name:     "success",
+			status:   http.StatusOK,
+			body:     `{"status":"success","data":{"name":"20240101T000000Z-1234"}}`,
+			expected: "20240101T000000Z-1234",
+		},
+		{
+			name:     "success without head",
+			skipHead: true,
+			status:   http.StatusOK,
+			body:     `{"status":"success","data":{"name":"20240101T000000Z-5678"}}`,
+			expected: "20240101T000000Z-5678",
+		},
+		{
+			name:   "admin API disabled",
+			status: http.StatusServiceUnavailable,
+			body:   `{"status":"error","errorType":"unavailable","error":"admin APIs disabled"}`,
+			err: &APIError{
+				StatusCode: http.StatusServiceUnavailable,
+				Type:       "unavailable",
+				Message:    "admin APIs disabled",
+			},
+		},
+		{
+			name:   "invalid body",
+			status: http.StatusBadGateway,
+			body:   `upstream error`,
+			err: &APIError{
+				StatusCode: http.StatusBadGateway,
+				Message:    "Bad Gateway",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, r.Method, http.MethodPost)
+				assert.Equal(t, r.URL.Path, "/api/v1/admin/tsdb/snapshot")
+				assert.NilError(t, r.ParseForm())
+				if tc.skipHead {
+					assert.Equal(t, r.Form.Get("skip_head"), "true")
+				} else {
+					assert.Equal(t, r.Form.Get("skip_head"), "false")
+				}
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			})
+
+			name, err := c.Snapshot(context.Background(), tc.skipHead)
+			if tc.err != nil {
+				var apiErr *APIError
+				assert.Assert(t, errors.As(err, &apiErr))
+				assert.DeepEqual(t, apiErr, tc.err)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, name, tc.expected)
+		})
+	}
+}
+
+func TestTSDBStatus(t *testing.T) {
+	c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/api/v1/status/tsdb")
+		assert.Equal(t, r.URL.Query().Get("limit"), "2")
+
+		_, _ = w.Write([]byte(`{"status":"success","data":{
+			"headStats":{"numSeries":1234,"numLabelPairs":56,"chunkCount":7890,"minTime":1,"maxTime":2},
+			"seriesCountByMetricName":[{"name":"http_requests_total","value":800},{"name":"up","value":10}],
+			"labelValueCountByLabelName":[{"name":"pod","value":42}],
+			"memoryInBytesByLabelName":[{"name":"pod","value":4200}],
+			"seriesCountByLabelValuePair":[{"name":"job=api","value":900}]
+		}}`))
+	})
+
+	status, err := c.TSDBStatus(context.Background(), 2)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, status, &TSDBStatus{
+		HeadStats:                   TSDBHeadStats{NumSeries: 1234, NumLabelPairs: 56, ChunkCount: 7890, MinTime: 1, MaxTime: 2},
+		SeriesCountByMetricName:     []Stat{{Name: "http_requests_total", Value: 800}, {Name: "up", Value: 10}},
+		LabelValueCountByLabelName:  []Stat{{Name: "pod", Value: 42}},
+		MemoryInBytesByLabelName:    []Stat{{Name: "pod", Value: 4200}},
+		SeriesCountByLabelValuePair: []Stat{{Name: "job=api", Value: 900}},
+	})
+}
+
+func TestActiveTargets(t *testing.T) {
+	c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/api/v1/targets")
+		assert.Equal(t, r.URL.Query().Get("state"), "active")
+
+		_, _ = w.Write([]byte(`{"status":"success","data":{"activeTargets":[
+			{"labels":{"job":"api","instance":"10.0.0.1:8080"},"scrapePool":"serviceMonitor/ns/api/0","scrapeUrl":"http://10.0.0.1:8080/metrics","lastError":"","health":"up"},
+			{"labels":{"job":"api","instance":"10.0.0.2:8080"},"scrapePool":"serviceMonitor/ns/api/0","scrapeUrl":"http://10.0.0.2:8080/metrics","lastError":"connection refused","health":"down"}
+		],"droppedTargets":[]}}`))
+	})
+
+	targets, err := c.ActiveTargets(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, len(targets), 2)
+	assert.Equal(t, targets[1].Health, HealthDown)
+	assert.Equal(t, targets[1].LastError, "connection refused")
+	assert.Equal(t, targets[0].Labels["job"], "api")
+	assert.Equal(t, targets[0].ScrapePool, "serviceMonitor/ns/api/0")
+}
+
+func TestStores(t *testing.T) {
+	c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/api/v1/stores")
+
+		_, _ = w.Write([]byte(`{"status":"success","data":{"sidecar":[
+			{"name":"10.0.0.1:10901","lastCheck":"2024-01-01T00:00:00Z","lastError":null,"minTime":0,"maxTime":1000},
+			{"name":"10.0.0.2:10901","lastCheck":"2024-01-01T00:00:00Z","lastError":"connection refused","minTime":0,"maxTime":0}
+		]}}`))
+	})
+
+	stores, err := c.Stores(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, len(stores), 1)
+	assert.Equal(t, len(stores["sidecar"]), 2)
+	assert.Equal(t, stores["sidecar"][0].Name, "10.0.0.1:10901")
+	assert.Assert(t, stores["sidecar"][0].LastError == nil)
+	assert.Equal(t, *stores["sidecar"][1].LastError, "connection refused")
+	assert.Equal(t, stores["sidecar"][0].MaxTime, int64(1000))
+}
+
+func TestQuery(t *testing.T) {
+	c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodPost)
+		assert.Equal(t, r.URL.Path, "/api/v1/query")
+		assert.NilError(t, r.ParseForm())
+
+		switch r.PostForm.Get("query") {
+		case "up":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"job":"api"},"value":[1717243200,"1"]},
+				{"metric":{"job":"db"},"value":[1717243200,"0.5"]}
+			]}}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1717243200,"1"]}}`))
+		}
+	})
+
+	samples, err := c.Query(context.Background(), "up")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, samples, []Sample{
+		{Metric: map[string]string{"job": "api"}, Value: 1},
+		{Metric: map[string]string{"job": "db"}, Value: 0.5},
+	})
+
+	_, err = c.Query(context.Background(), "scalar(up)")
+	assert.ErrorContains(t, err, `unexpected result type "scalar"`)
+}
+
+func TestMetrics(t *testing.T) {
+	c := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/metrics")
+
+		_, _ = w.Write([]byte(`# HELP prometheus_config_last_reload_successful Whether the last configuration reload attempt was successful.
+# TYPE prometheus_config_last_reload_successful gauge
+prometheus_config_last_reload_successful 0
+`))
+	})
+
+	families, err := c.Metrics(context.Background())
+	assert.NilError(t, err)
+	mf, found := families["prometheus_config_last_reload_successful"]
+	assert.Assert(t, found)
+	assert.Equal(t, mf.GetMetric()[0].GetGauge().GetValue(), float64(0))
+}
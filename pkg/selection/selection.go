@@ -0,0 +1,84 @@
+// Package selection resolves the namespaces and the resources selected by
+// the MonitoringStacks: a stack selects the resources matching its resource
+// selector in the namespaces matching its namespace selector, or in its own
+// namespace when the namespace selector isn't set.
+package selection
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+// Namespaces returns the names of the namespaces matching the selector or
+// the given namespace when the selector is nil.
+func Namespaces(ctx context.Context, c client.Reader, namespace string, nsSelector *metav1.LabelSelector) ([]string, error) {
+	if nsSelector == nil {
+		return []string{namespace}, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(nsSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	list := &corev1.NamespaceList{}
+	if err := c.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces, nil
+}
+
+// MatchesNamespace returns true if the namespace matches the selector or,
+// when the selector is nil, is the given namespace. An invalid selector
+// matches no namespace.
+func MatchesNamespace(namespace string, nsSelector *metav1.LabelSelector, ns *corev1.Namespace) bool {
+	if nsSelector == nil {
+		return ns.Name == namespace
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(nsSelector)
+	return err == nil && selector.Matches(labels.Set(ns.Labels))
+}
+
+// StackNamespaces returns the namespaces in which the stack discovers
+// resources.
+func StackNamespaces(ctx context.Context, c client.Reader, ms *stack.MonitoringStack) ([]string, error) {
+	return Namespaces(ctx, c, ms.Namespace, ms.Spec.NamespaceSelector)
+}
+
+// StackSelects returns true if the stack selects the object living in the
+// given namespace. A stack without resource selector selects nothing.
+func StackSelects(ms *stack.MonitoringStack, obj client.Object, ns *corev1.Namespace) bool {
+	if ms.Spec.ResourceSelector == nil || obj.GetNamespace() != ns.Name {
+		return false
+	}
+	if !MatchesNamespace(ms.Namespace, ms.Spec.NamespaceSelector, ns) {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(ms.Spec.ResourceSelector)
+	return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// GetNamespace returns the namespace with the given name. A namespace without
+// labels is returned when it can't be read so that it's still selected by the
+// stacks without namespace selector.
+func GetNamespace(ctx context.Context, c client.Reader, name string) *corev1.Namespace {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	return ns
+}
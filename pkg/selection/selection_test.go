@@ -0,0 +1,145 @@
+package selection
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+)
+
+func newNamespace(name string, lbls map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls}}
+}
+
+func TestNamespaces(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		newNamespace("team-a", map[string]string{"team": "a"}),
+		newNamespace("team-b", map[string]string{"team": "b"}),
+		newNamespace("other", nil),
+	).Build()
+
+	for _, tc := range []struct {
+		name       string
+		nsSelector *metav1.LabelSelector
+		expected   []string
+		err        string
+	}{
+		{
+			name:     "own namespace without selector",
+			expected: []string{"monitoring"},
+		},
+		{
+			name:       "matching namespaces",
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: metav1.LabelSelectorOpExists}}},
+			expected:   []string{"team-a", "team-b"},
+		},
+		{
+			name:       "empty selector",
+			nsSelector: &metav1.LabelSelector{},
+			expected:   []string{"other", "team-a", "team-b"},
+		},
+		{
+			name:       "invalid selector",
+			nsSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Invalid"}}},
+			err:        "invalid namespace selector: \"Invalid\" is not a valid label selector operator",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaces, err := Namespaces(context.Background(), c, "monitoring", tc.nsSelector)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, namespaces, tc.expected)
+		})
+	}
+}
+
+func TestStackSelects(t *testing.T) {
+	teamA := newNamespace("team-a", map[string]string{"team": "a"})
+	monitoring := newNamespace("monitoring", nil)
+
+	newStack := func(nsSelector *metav1.LabelSelector) *stack.MonitoringStack {
+		return &stack.MonitoringStack{
+			ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "monitoring"},
+			Spec: stack.MonitoringStackSpec{
+				ResourceSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+				NamespaceSelector: nsSelector,
+			},
+		}
+	}
+	newObject := func(namespace string, lbls map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "obj", Namespace: namespace, Labels: lbls}}
+	}
+	demo := map[string]string{"app": "demo"}
+
+	for _, tc := range []struct {
+		name     string
+		ms       *stack.MonitoringStack
+		obj      *corev1.ConfigMap
+		ns       *corev1.Namespace
+		expected bool
+	}{
+		{
+			name:     "object in the namespace of the stack",
+			ms:       newStack(nil),
+			obj:      newObject("monitoring", demo),
+			ns:       monitoring,
+			expected: true,
+		},
+		{
+			name: "object not matching the resource selector",
+			ms:   newStack(nil),
+			obj:  newObject("monitoring", map[string]string{"app": "other"}),
+			ns:   monitoring,
+		},
+		{
+			name: "object in another namespace without namespace selector",
+			ms:   newStack(nil),
+			obj:  newObject("team-a", demo),
+			ns:   teamA,
+		},
+		{
+			name:     "object in a selected namespace",
+			ms:       newStack(&metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}),
+			obj:      newObject("team-a", demo),
+			ns:       teamA,
+			expected: true,
+		},
+		{
+			name: "object in a namespace not selected",
+			ms:   newStack(&metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}),
+			obj:  newObject("monitoring", demo),
+			ns:   monitoring,
+		},
+		{
+			name: "stack without resource selector",
+			ms: &stack.MonitoringStack{
+				ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "monitoring"},
+			},
+			obj: newObject("monitoring", demo),
+			ns:  monitoring,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, StackSelects(tc.ms, tc.obj, tc.ns), tc.expected)
+		})
+	}
+}
+
+func TestGetNamespace(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(newNamespace("team-a", map[string]string{"team": "a"})).Build()
+
+	assert.DeepEqual(t, GetNamespace(context.Background(), c, "team-a").Labels, map[string]string{"team": "a"})
+
+	// A namespace which can't be read is still selected by name.
+	ns := GetNamespace(context.Background(), c, "unknown")
+	assert.Equal(t, ns.Name, "unknown")
+	assert.Assert(t, MatchesNamespace("unknown", nil, ns))
+}
@@ -32,6 +32,12 @@ func SetAlertmanagerDisabled(disabled bool) MonitoringStackConfig {
 	}
 }
 
+func SetSuspend(suspend bool) MonitoringStackConfig {
+	return func(ms *stack.MonitoringStack) {
+		ms.Spec.Suspend = suspend
+	}
+}
+
 // UpdateWithRetry updates monitoringstack with retry
 func (f *Framework) UpdateWithRetry(t *testing.T, ms *stack.MonitoringStack, fns ...MonitoringStackConfig) error {
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -118,6 +118,9 @@ func TestMonitoringStackController(t *testing.T) {
 	}, {
 		name:     "Verify ability to scale down Prometheus",
 		scenario: prometheusScaleDown,
+	}, {
+		name:     "Suspended stack is scaled down and resumed",
+		scenario: suspendAndResumeStack,
 	}, {
 		name:     "managed fields in Prometheus object",
 		scenario: assertPrometheusManagedFields,
@@ -694,6 +697,49 @@ func prometheusScaleDown(t *testing.T) {
 	f.AssertPrometheusReplicaStatus(ms.Name, ms.Namespace, 0)
 }
 
+func suspendAndResumeStack(t *testing.T) {
+	ms := newMonitoringStack(t, "suspend-resume-test")
+	ms.Spec.PrometheusConfig = &stack.PrometheusConfig{
+		Replicas: intPtr(1),
+	}
+
+	err := f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+	f.AssertStatefulsetReady("prometheus-"+ms.Name, ms.Namespace, framework.WithTimeout(5*time.Minute))(t)
+
+	err = f.UpdateWithRetry(t, ms, framework.SetSuspend(true))
+	assert.NilError(t, err, "failed to suspend the monitoring stack")
+	f.AssertPrometheusReplicaStatus(ms.Name, ms.Namespace, 0)(t)
+	assertSuspendedCondition(t, ms, stack.ConditionTrue)
+
+	am := monv1.Alertmanager{}
+	f.GetResourceWithRetry(t, ms.Name, ms.Namespace, &am)
+	assert.Equal(t, *am.Spec.Replicas, int32(0), "alertmanager isn't scaled down")
+
+	err = f.UpdateWithRetry(t, ms, framework.SetSuspend(false))
+	assert.NilError(t, err, "failed to resume the monitoring stack")
+	f.AssertPrometheusReplicaStatus(ms.Name, ms.Namespace, 1)(t)
+	f.AssertStatefulsetReady("prometheus-"+ms.Name, ms.Namespace, framework.WithTimeout(5*time.Minute))(t)
+	assertSuspendedCondition(t, ms, stack.ConditionFalse)
+}
+
+// assertSuspendedCondition waits for the Suspended condition of the stack to
+// have the expected status.
+func assertSuspendedCondition(t *testing.T, ms *stack.MonitoringStack, status stack.ConditionStatus) {
+	t.Helper()
+	key := types.NamespacedName{Name: ms.Name, Namespace: ms.Namespace}
+	var got stack.MonitoringStack
+	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, framework.DefaultTestTimeout, true, func(ctx context.Context) (bool, error) {
+		if err := f.K8sClient.Get(ctx, key, &got); err != nil {
+			return false, nil
+		}
+		c := getConditionByType(got.Status.Conditions, stack.SuspendedCondition)
+		return c != nil && c.Status == status && c.ObservedGeneration == got.Generation, nil
+	}); wait.Interrupted(err) {
+		t.Fatalf("the Suspended condition of the stack %s never became %s", key, status)
+	}
+}
+
 func assertPrometheusManagedFields(t *testing.T) {
 	numOfRep := int32(1)
 
@@ -0,0 +1,166 @@
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+
+	stack "github.com/rhobs/observability-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/rhobs/observability-operator/test/e2e/framework"
+)
+
+func TestMonitoringStackSnapshot(t *testing.T) {
+	assertCRDExists(t, "monitoringstacksnapshots.monitoring.rhobs")
+
+	ts := []testCase{
+		{
+			name:     "Snapshot is copied to a PersistentVolumeClaim",
+			scenario: snapshotCopiedToPVC,
+		},
+		{
+			name:     "Snapshot is uploaded to object storage",
+			scenario: snapshotUploadedToObjectStorage,
+		},
+	}
+
+	for _, tc := range ts {
+		t.Run(tc.name, tc.scenario)
+	}
+}
+
+func snapshotCopiedToPVC(t *testing.T) {
+	ms := newSnapshotStack(t, "snapshot-pvc")
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "snapshot-pvc-destination",
+			Namespace: e2eTestNamespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	err := f.K8sClient.Create(context.Background(), pvc)
+	assert.NilError(t, err, "failed to create the destination PVC")
+	f.CleanUp(t, func() {
+		f.K8sClient.Delete(context.Background(), pvc)
+	})
+
+	snap := newSnapshot(t, "snapshot-pvc", ms.Name, stack.SnapshotDestination{
+		PersistentVolumeClaim: &stack.SnapshotPersistentVolumeClaim{ClaimName: pvc.Name},
+	})
+	err = f.K8sClient.Create(context.Background(), snap)
+	assert.NilError(t, err, "failed to create the snapshot")
+
+	snap = waitForSnapshotCompletion(t, snap)
+	assert.Equal(t, snap.Status.Location, "pvc://snapshot-pvc-destination/snapshot-pvc")
+	assert.Assert(t, snap.Status.Size != nil, "the size of the snapshot isn't reported")
+}
+
+func snapshotUploadedToObjectStorage(t *testing.T) {
+	ms := newSnapshotStack(t, "snapshot-objstore")
+
+	// The bucket lives in the filesystem of the copy job: the test asserts
+	// that the blocks are uploaded by the Thanos image of the operator.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "snapshot-objstore",
+			Namespace: e2eTestNamespace,
+		},
+		StringData: map[string]string{
+			"objstore.yaml": "type: FILESYSTEM\nconfig:\n  directory: /tmp/bucket\n",
+		},
+	}
+	err := f.K8sClient.Create(context.Background(), secret)
+	assert.NilError(t, err, "failed to create the object storage secret")
+	f.CleanUp(t, func() {
+		f.K8sClient.Delete(context.Background(), secret)
+	})
+
+	snap := newSnapshot(t, "snapshot-objstore", ms.Name, stack.SnapshotDestination{
+		ObjectStorage: &stack.SnapshotObjectStorage{
+			Config: stack.SecretKeySelector{Name: secret.Name, Key: "objstore.yaml"},
+		},
+	})
+	err = f.K8sClient.Create(context.Background(), snap)
+	assert.NilError(t, err, "failed to create the snapshot")
+
+	snap = waitForSnapshotCompletion(t, snap)
+	assert.Equal(t, snap.Status.Location, "objstore://snapshot-objstore/objstore.yaml")
+}
+
+// newSnapshotStack creates a MonitoringStack with a single Prometheus replica
+// and persistent storage, and waits for Prometheus to be ready.
+func newSnapshotStack(t *testing.T, name string) *stack.MonitoringStack {
+	ms := newMonitoringStack(t, name, func(ms *stack.MonitoringStack) {
+		ms.Spec.AlertmanagerConfig.Disabled = true
+		ms.Spec.PrometheusConfig = &stack.PrometheusConfig{
+			Replicas: ptr.To(int32(1)),
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+	})
+	err := f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	f.AssertStatefulsetReady("prometheus-"+name, e2eTestNamespace, framework.WithTimeout(5*time.Minute))(t)
+	return ms
+}
+
+func newSnapshot(t *testing.T, name string, stackName string, dest stack.SnapshotDestination) *stack.MonitoringStackSnapshot {
+	snap := &stack.MonitoringStackSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: e2eTestNamespace,
+		},
+		Spec: stack.MonitoringStackSnapshotSpec{
+			MonitoringStack: stackName,
+			Destination:     dest,
+		},
+	}
+	f.CleanUp(t, func() {
+		f.K8sClient.Delete(context.Background(), snap)
+	})
+
+	return snap
+}
+
+// waitForSnapshotCompletion waits for the snapshot to reach a terminal phase
+// and fails the test unless it's completed.
+func waitForSnapshotCompletion(t *testing.T, snap *stack.MonitoringStackSnapshot) *stack.MonitoringStackSnapshot {
+	t.Helper()
+	key := types.NamespacedName{Name: snap.Name, Namespace: snap.Namespace}
+	got := &stack.MonitoringStackSnapshot{}
+	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, 10*time.Minute, true, func(ctx context.Context) (bool, error) {
+		if err := f.K8sClient.Get(ctx, key, got); err != nil {
+			return false, nil
+		}
+		switch got.Status.Phase {
+		case stack.SnapshotFailed:
+			return false, fmt.Errorf("snapshot failed: %s", got.Status.Message)
+		case stack.SnapshotCompleted:
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		t.Fatal(fmt.Errorf("snapshot %s never completed (phase %q): %w", key, got.Status.Phase, err))
+	}
+
+	return got
+}
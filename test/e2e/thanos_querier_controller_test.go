@@ -2,7 +2,10 @@ package e2e
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -31,6 +34,18 @@ func TestThanosQuerierController(t *testing.T) {
 			name:     "Delete resources if matched monitoring stack is deleted",
 			scenario: stackWithSidecarGetsDeleted,
 		},
+		{
+			name:     "Query a single monitoring stack with an additional TLS endpoint",
+			scenario: singleStackWithTLSEndpoint,
+		},
+		{
+			name:     "Serve the queries through the tenancy proxies",
+			scenario: singleStackWithTenancy,
+		},
+		{
+			name:     "Query a single monitoring stack over mutual TLS",
+			scenario: singleStackWithGRPCTLS,
+		},
 	}
 
 	for _, tc := range ts {
@@ -71,8 +86,160 @@ func singleStackWithSidecar(t *testing.T) {
 
 	f.AssertDeploymentReady(name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
 	// Assert prometheus instance can be queried
+	assertThanosQuerierResults(t, name, map[string]int{
+		"prometheus_build_info": 2, // must return from both prometheus pods
+	})
+}
+
+func singleStackWithTLSEndpoint(t *testing.T) {
+	tq, ms := newThanosStackCombo(t, "tq-tls-endpoint")
+	// The endpoint is unreachable: the test asserts that the querier
+	// accepts the endpoint configuration and keeps querying the stack.
+	tq.Spec.AdditionalEndpoints = []msov1.ThanosEndpoint{
+		{
+			Address: "store.tq-tls-endpoint.invalid:10901",
+			TLS:     &msov1.ThanosEndpointTLSConfig{ServerName: "store.example.com"},
+		},
+	}
+	err := f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+	err = f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	name := "thanos-querier-" + tq.Name
+	f.AssertDeploymentReady(name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
+	assertThanosQuerierResults(t, name, map[string]int{
+		"prometheus_build_info": 2,
+	})
+
+	var deployment appsv1.Deployment
+	f.GetResourceWithRetry(t, name, tq.Namespace, &deployment)
+	pods := &corev1.PodList{}
+	err = f.K8sClient.List(context.Background(), pods, client.InNamespace(tq.Namespace), client.MatchingLabels(deployment.Spec.Selector.MatchLabels))
+	assert.NilError(t, err)
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			assert.Equal(t, status.RestartCount, int32(0), "container %s of pod %s restarted", status.Name, pod.Name)
+		}
+	}
+}
+
+func singleStackWithTenancy(t *testing.T) {
+	tq, ms := newThanosStackCombo(t, "tq-tenancy")
+	tq.Spec.Tenancy = &msov1.ThanosQuerierTenancyConfig{Enabled: true}
+	err := f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+	err = f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	name := "thanos-querier-" + tq.Name
+	f.AssertDeploymentReady(name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
+
+	var svc corev1.Service
+	f.GetResourceWithRetry(t, name+"-tenancy", tq.Namespace, &svc)
+	ports := make(map[string]int32, len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		ports[port.Name] = port.Port
+	}
+	assert.Equal(t, ports["tenancy"], int32(9092), "unexpected ports %v", ports)
+	f.AssertResourceNeverExists(name, tq.Namespace, &corev1.Service{})(t)
+
+	var secret corev1.Secret
+	f.GetResourceWithRetry(t, tq.Name+"-tenancy-tls", tq.Namespace, &secret)
+
+	// The proxy must reject the queries without bearer token.
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		err = f.StartServicePortForward(svc.Name, e2eTestNamespace, "9092", stopChan)
+		return err == nil, nil
+	}); wait.Interrupted(err) {
+		t.Fatal("timeout waiting for port-forward")
+	}
+
+	ca := x509.NewCertPool()
+	assert.Assert(t, ca.AppendCertsFromPEM(secret.Data["ca.crt"]), "failed to parse the certificate authority")
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    ca,
+				ServerName: fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace),
+			},
+		},
+	}
+	var lastErr error
+	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		resp, err := httpClient.Get("https://localhost:9092/api/v1/query?query=up&namespace=" + e2eTestNamespace)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			lastErr = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+			return false, nil
+		}
+		return true, nil
+	}); wait.Interrupted(err) {
+		t.Fatal(fmt.Errorf("the tenancy proxy never rejected the anonymous query: %w", lastErr))
+	}
+}
+
+func singleStackWithGRPCTLS(t *testing.T) {
+	// The operator only issues the client certificates of the queriers in
+	// the namespaces allowed to receive them.
+	setNamespaceLabel(t, e2eTestNamespace, "monitoring.rhobs/grpc-client-certificates", "true")
+
+	tq, ms := newThanosStackCombo(t, "tq-grpc-tls")
+	tq.Spec.GRPCTLS = &msov1.ThanosQuerierGRPCTLSConfig{}
+	ms.Spec.PrometheusConfig = &msov1.PrometheusConfig{
+		ThanosSidecarGRPCTLS: &msov1.GRPCTLSConfig{},
+	}
+	err := f.K8sClient.Create(context.Background(), tq)
+	assert.NilError(t, err, "failed to create a thanos querier")
+	err = f.K8sClient.Create(context.Background(), ms)
+	assert.NilError(t, err, "failed to create a monitoring stack")
+
+	name := "thanos-querier-" + tq.Name
+	f.AssertDeploymentReady(name, tq.Namespace, framework.WithTimeout(5*time.Minute))(t)
+	assertThanosQuerierResults(t, name, map[string]int{
+		"prometheus_build_info": 2,
+	})
+}
+
+// setNamespaceLabel sets the label on the namespace and restores the previous
+// labels at the end of the test.
+func setNamespaceLabel(t *testing.T, namespace, name, value string) {
+	var ns corev1.Namespace
+	err := f.K8sClient.Get(context.Background(), types.NamespacedName{Name: namespace}, &ns)
+	assert.NilError(t, err, "failed to get the namespace %s", namespace)
+
+	orig := ns.DeepCopy()
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	ns.Labels[name] = value
+	err = f.K8sClient.Patch(context.Background(), &ns, client.MergeFrom(orig))
+	assert.NilError(t, err, "failed to label the namespace %s", namespace)
+
+	f.CleanUp(t, func() {
+		labeled := orig.DeepCopy()
+		if err := f.K8sClient.Get(context.Background(), types.NamespacedName{Name: namespace}, labeled); err != nil {
+			return
+		}
+		restored := labeled.DeepCopy()
+		restored.Labels = orig.Labels
+		f.K8sClient.Patch(context.Background(), restored, client.MergeFrom(labeled))
+	})
+}
+
+// assertThanosQuerierResults asserts that the queries return the expected
+// number of series through the Service of the Thanos querier.
+func assertThanosQuerierResults(t *testing.T, name string, expectedResults map[string]int) {
 	stopChan := make(chan struct{})
 	defer close(stopChan)
+	var err error
 	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
 		err = f.StartServicePortForward(name, e2eTestNamespace, "10902", stopChan)
 		return err == nil, nil
@@ -81,9 +248,6 @@ func singleStackWithSidecar(t *testing.T) {
 	}
 
 	promClient := framework.NewPrometheusClient("http://localhost:10902")
-	expectedResults := map[string]int{
-		"prometheus_build_info": 2, // must return from both prometheus pods
-	}
 	var lastErr error
 	if err := wait.PollUntilContextTimeout(context.Background(), 5*time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
 		correct := 0